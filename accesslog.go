@@ -0,0 +1,154 @@
+package httpx
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Access log formats for AccessLog.
+const (
+	// CommonLogFormat is the Apache Common Log Format.
+	CommonLogFormat = `{{.Host}} - {{.User}} [{{.Time.Format "02/Jan/2006:15:04:05 -0700"}}] "{{.Method}} {{.URI}} {{.Proto}}" {{.Status}} {{.Size}}`
+
+	// CombinedLogFormat is the Apache Combined Log Format.
+	CombinedLogFormat = CommonLogFormat + ` "{{.Referer}}" "{{.UserAgent}}"`
+)
+
+// AccessLogEntry holds the fields available to an access log template.
+type AccessLogEntry struct {
+	Host      string
+	User      string
+	Time      time.Time
+	Method    string
+	URI       string
+	Proto     string
+	Status    int
+	Bytes     int64
+	Duration  time.Duration
+	Referer   string
+	UserAgent string
+	Request   *http.Request
+}
+
+// Size returns the number of body bytes written, or "-" when no body
+// was written, as in the Common Log Format.
+func (e AccessLogEntry) Size() string {
+	if e.Bytes == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(e.Bytes, 10)
+}
+
+// AccessLog is a middleware that writes a line for each request to out,
+// formatted by the text/template format, such as CommonLogFormat or
+// CombinedLogFormat, executed with an AccessLogEntry. AccessLog panics
+// if format is not a valid template.
+//
+// Lines are written with a single Write call each, so out may be shared
+// with other goroutines when its Write method is safe for concurrent
+// use, as it is for an *os.File or a *LogFile.
+func AccessLog(out io.Writer, format string) Middleware {
+	tmpl := template.Must(template.New("accesslog").Parse(format))
+	pool := sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			start := time.Now()
+			rw := wrapWriter(w)
+			err := next.ServeHTTP(rw, r)
+
+			entry := AccessLogEntry{
+				Host:      "-",
+				User:      "-",
+				Time:      start,
+				Method:    r.Method,
+				URI:       r.RequestURI,
+				Proto:     r.Proto,
+				Status:    statusOf(r, rw, err),
+				Bytes:     rw.written,
+				Duration:  time.Since(start),
+				Referer:   dash(r.Referer()),
+				UserAgent: dash(r.UserAgent()),
+				Request:   r,
+			}
+			if ip := ClientIP(r); ip.IsValid() {
+				entry.Host = ip.String()
+			}
+			if user, _, ok := r.BasicAuth(); ok && user != "" {
+				entry.User = user
+			}
+
+			buf := pool.Get().(*bytes.Buffer)
+			buf.Reset()
+			if tmpl.Execute(buf, entry) == nil {
+				buf.WriteByte('\n')
+				out.Write(buf.Bytes())
+			}
+			pool.Put(buf)
+			return err
+		})
+	}
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// LogFile is an append-only log file that can be reopened, so that it
+// cooperates with external log rotation. After the rotation tool has
+// moved the file away, call Reopen, usually from a SIGHUP handler, to
+// continue writing to a fresh file at the original path.
+type LogFile struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// OpenLogFile opens the log file at path for appending, creating it if
+// necessary.
+func OpenLogFile(path string) (*LogFile, error) {
+	lf := &LogFile{path: path}
+	if err := lf.Reopen(); err != nil {
+		return nil, err
+	}
+	return lf, nil
+}
+
+// Write appends b to the log file. It is safe for concurrent use.
+func (lf *LogFile) Write(b []byte) (int, error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	return lf.f.Write(b)
+}
+
+// Reopen closes the log file and opens the file at its path again.
+func (lf *LogFile) Reopen() error {
+	f, err := os.OpenFile(lf.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	lf.mu.Lock()
+	old := lf.f
+	lf.f = f
+	lf.mu.Unlock()
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (lf *LogFile) Close() error {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	return lf.f.Close()
+}
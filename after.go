@@ -0,0 +1,75 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+// afterHooks holds the callbacks registered by AfterResponse for a
+// routed request.
+type afterHooks struct {
+	mu    sync.Mutex
+	hooks []afterHook
+}
+
+type afterHook struct {
+	ctx context.Context
+	fn  func(ctx context.Context)
+}
+
+// AfterResponse registers fn to run once the response to the request
+// has been written, for follow-up work such as cache invalidation or
+// notifications that must not delay the response. AfterResponse
+// reports false outside of a request routed by a Mux, where fn is not
+// registered.
+//
+// Once the route handler has returned and the Mux has written the
+// response, including the error response of a handler error, the
+// callbacks run in a new goroutine, in the order they were registered,
+// each with the context of the request that registered it detached
+// from its cancellation. A panicking callback is recovered and logged
+// with the logger of the request, and doesn't prevent the next
+// callbacks from running.
+func AfterResponse(r *http.Request, fn func(ctx context.Context)) bool {
+	rc := callOf(r)
+	if rc == nil {
+		return false
+	}
+	rc.after.mu.Lock()
+	rc.after.hooks = append(rc.after.hooks, afterHook{Detach(r.Context()), fn})
+	rc.after.mu.Unlock()
+	return true
+}
+
+// run starts the registered callbacks, once the response is written.
+func (a *afterHooks) run() {
+	a.mu.Lock()
+	hooks := a.hooks
+	a.hooks = nil
+	a.mu.Unlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	go func() {
+		for _, h := range hooks {
+			func() {
+				defer func() {
+					if v := recover(); v != nil {
+						logger, ok := h.ctx.Value(loggerKey{}).(*slog.Logger)
+						if !ok {
+							logger = slog.Default()
+						}
+						logger.ErrorContext(h.ctx, "after response hook panicked",
+							"panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+					}
+				}()
+				h.fn(h.ctx)
+			}()
+		}
+	}()
+}
@@ -0,0 +1,42 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAfterResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	statuses := make(chan int, 2)
+
+	m := NewMux()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		AfterResponse(r, func(ctx context.Context) { statuses <- rec.Code })
+		Go(r, func(ctx context.Context) error {
+			statuses <- rec.Code
+			return ctx.Err()
+		})
+		return Error(http.StatusBadRequest, "bad request")
+	})
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case status := <-statuses:
+			if status != http.StatusBadRequest {
+				t.Errorf("hook ran before the error response was written, status %d", status)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("hook didn't run")
+		}
+	}
+}
+
+func TestAfterResponseUnrouted(t *testing.T) {
+	if AfterResponse(httptest.NewRequest(http.MethodGet, "/", nil), func(context.Context) {}) {
+		t.Error("AfterResponse reported true outside of a routed request")
+	}
+}
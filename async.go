@@ -0,0 +1,171 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobState is the state of a Job.
+type JobState string
+
+// Job states.
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// Job is a long-running operation started by an Async handler and
+// carried out by a background worker.
+type Job struct {
+	ID      string    `json:"id"`
+	State   JobState  `json:"state"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+
+	// Result is the outcome of a succeeded job, as JSON.
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// ResultURL, when set on a succeeded job, is the location of the
+	// resource the job created, to which JobStatus redirects.
+	ResultURL string `json:"result_url,omitempty"`
+
+	// Error is the reason a failed job failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Done reports whether the job has succeeded or failed.
+func (j Job) Done() bool {
+	return j.State == JobSucceeded || j.State == JobFailed
+}
+
+// JobStore stores the state of Jobs. Workers report progress by
+// updating the job in the store. Implementations backed by a shared
+// database let the status be polled from any instance of a service.
+type JobStore interface {
+	// Get returns the job with id, and false when there is none.
+	Get(ctx context.Context, id string) (Job, bool, error)
+
+	// Put creates or replaces a job.
+	Put(ctx context.Context, job Job) error
+}
+
+// NewMemoryJobStore returns a JobStore that keeps jobs in memory, for
+// single instance services and tests. Finished jobs are forgotten ttl
+// after their last update.
+func NewMemoryJobStore(ttl time.Duration) JobStore {
+	return &memoryJobStore{ttl: ttl, jobs: map[string]Job{}}
+}
+
+type memoryJobStore struct {
+	ttl  time.Duration
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+func (s *memoryJobStore) Get(ctx context.Context, id string) (Job, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if ok && job.Done() && time.Since(job.Updated) > s.ttl {
+		delete(s.jobs, id)
+		return Job{}, false, nil
+	}
+	return job, ok, nil
+}
+
+func (s *memoryJobStore) Put(ctx context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// AsyncOptions configures an Async handler.
+type AsyncOptions struct {
+	// Store keeps the state of the jobs.
+	Store JobStore
+
+	// StatusRoute is the name of the route serving JobStatus, with an
+	// {id} URL param. When empty, the status URL is the request path
+	// followed by the job ID.
+	StatusRoute string
+}
+
+// Async returns a handler for a long-running operation. The prepare
+// function validates the request and returns the payload of the job,
+// or an error to fail the request. A pending Job is then created in
+// opts.Store and passed with the payload to enqueue, which hands it to
+// a queue or worker of the service's choice. The handler responds
+// immediately with 202 Accepted, a Location header of the status URL
+// and the job as JSON:
+//
+//	mux.Post("/exports", httpx.Async(parseExport, queue.Enqueue, opts))
+//	mux.Get("/exports/jobs/{id}", httpx.JobStatus(opts.Store), httpx.Name("export-job"))
+//
+// Workers update the job in the store as it runs and finishes.
+func Async(prepare func(r *http.Request) (interface{}, error), enqueue func(ctx context.Context, job Job, payload interface{}) error, opts AsyncOptions) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		payload, err := prepare(r)
+		if err != nil {
+			return err
+		}
+
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return err
+		}
+		now := time.Now()
+		job := Job{ID: hex.EncodeToString(id[:]), State: JobPending, Created: now, Updated: now}
+		if err := opts.Store.Put(r.Context(), job); err != nil {
+			return err
+		}
+		if err := enqueue(r.Context(), job, payload); err != nil {
+			job.State, job.Error, job.Updated = JobFailed, "job could not be enqueued", time.Now()
+			opts.Store.Put(context.WithoutCancel(r.Context()), job)
+			return err
+		}
+
+		var location string
+		if opts.StatusRoute != "" {
+			if location, err = URLFor(r, opts.StatusRoute, "id", job.ID); err != nil {
+				return err
+			}
+		} else {
+			location = strings.TrimSuffix(r.URL.Path, "/") + "/" + job.ID
+		}
+		w.Header().Set("Location", location)
+		return JSON(w, http.StatusAccepted, job)
+	})
+}
+
+// JobStatus returns a handler that reports the Job with the ID of the
+// {id} URL param as JSON. While the job isn't done, the response has a
+// Retry-After header suggesting when to poll again. A succeeded job
+// with a ResultURL is answered with a 303 See Other redirect to it. An
+// unknown job is a 404 Not Found StatusError.
+func JobStatus(store JobStore) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		job, ok, err := store.Get(r.Context(), URLParam(r, "id"))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return Error(http.StatusNotFound, "job not found")
+		}
+		switch {
+		case !job.Done():
+			w.Header().Set("Retry-After", "1")
+		case job.State == JobSucceeded && job.ResultURL != "":
+			return SeeOther(w, r, job.ResultURL)
+		}
+		return JSON(w, http.StatusOK, job)
+	})
+}
@@ -0,0 +1,145 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// auditActionKey is the route metadata key declared by AuditAction.
+const auditActionKey = "audit.action"
+
+// Audit event outcomes.
+const (
+	AuditSuccess = "success"
+	AuditDenied  = "denied"
+	AuditFailure = "failure"
+)
+
+// AuditEvent records an audited request.
+type AuditEvent struct {
+	Time     time.Time         `json:"time"`
+	Actor    string            `json:"actor,omitempty"`
+	Action   string            `json:"action"`
+	Resource map[string]string `json:"resource,omitempty"`
+	Method   string            `json:"method"`
+	Path     string            `json:"path"`
+	Route    string            `json:"route"`
+	Status   int               `json:"status"`
+	Outcome  string            `json:"outcome"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// An AuditSink receives audit events. Sinks must be safe for concurrent
+// use.
+type AuditSink interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// The AuditSinkFunc type is an adapter to allow the use of ordinary
+// functions as audit sinks.
+type AuditSinkFunc func(ctx context.Context, event AuditEvent)
+
+// Audit calls fn(ctx, event).
+func (fn AuditSinkFunc) Audit(ctx context.Context, event AuditEvent) {
+	fn(ctx, event)
+}
+
+// JSONAuditSink returns an AuditSink that writes each event to w as a
+// line of JSON.
+func JSONAuditSink(w io.Writer) AuditSink {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return AuditSinkFunc(func(ctx context.Context, event AuditEvent) {
+		mu.Lock()
+		enc.Encode(event)
+		mu.Unlock()
+	})
+}
+
+// AuditOptions configures the Audit middleware.
+type AuditOptions struct {
+	// Sink receives the audit events.
+	Sink AuditSink
+
+	// Patterns lists the route patterns that are audited, in path.Match
+	// syntax. Routes declaring an action with AuditAction are always
+	// audited.
+	Patterns []string
+
+	// Actor returns the identity of the user making a request, usually
+	// from a value set on the request context by an auth middleware.
+	Actor func(r *http.Request) string
+}
+
+// AuditAction returns a RouteOption that declares the action recorded
+// in audit events for a route. Without an action, events are recorded
+// with the method and route pattern as the action.
+func AuditAction(action string) RouteOption {
+	return Meta(auditActionKey, action)
+}
+
+// Audit is a middleware that emits an AuditEvent for each request to
+// an audited route, once the route handler has returned. The event
+// resource holds the URL params of the request, and its outcome is
+// derived from the response status.
+func Audit(opts AuditOptions) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			ri, ok := CurrentRoute(r)
+			if !ok {
+				return next.ServeHTTP(w, r)
+			}
+			action, declared := ri.Metadata[auditActionKey].(string)
+			if !declared && !matchAny(opts.Patterns, ri.Pattern) {
+				return next.ServeHTTP(w, r)
+			}
+			if action == "" {
+				action = r.Method + " " + ri.Pattern
+			}
+
+			start := time.Now()
+			rw := wrapWriter(w)
+			err := next.ServeHTTP(rw, r)
+
+			event := AuditEvent{
+				Time:     start,
+				Action:   action,
+				Resource: URLParams(r),
+				Method:   r.Method,
+				Path:     r.URL.Path,
+				Route:    ri.Pattern,
+				Status:   statusOf(r, rw, err),
+			}
+			if opts.Actor != nil {
+				event.Actor = opts.Actor(r)
+			}
+			switch {
+			case event.Status == http.StatusUnauthorized || event.Status == http.StatusForbidden:
+				event.Outcome = AuditDenied
+			case event.Status >= 400:
+				event.Outcome = AuditFailure
+			default:
+				event.Outcome = AuditSuccess
+			}
+			if err != nil {
+				event.Error = err.Error()
+			}
+			opts.Sink.Audit(r.Context(), event)
+			return err
+		})
+	}
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package httpx
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Principal is the identity of an authenticated client. Auth
+// middlewares set it on the request context, where authorization
+// middlewares and handlers get it with PrincipalFrom.
+type Principal struct {
+	// ID identifies the client, such as a user or service account id.
+	ID string
+
+	// Scopes lists the scopes granted to the client.
+	Scopes []string
+
+	// Roles lists the roles of the client.
+	Roles []string
+
+	// RateClass is the rate limit class of the client, such as "free"
+	// or "partner", for rate limiting middlewares to key on.
+	RateClass string
+
+	// Claims holds further attributes of the client.
+	Claims map[string]interface{}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a shallow copy of r with p set as its
+// Principal. It is used by auth middlewares.
+func WithPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+}
+
+// PrincipalFrom returns the Principal of an authenticated request. The
+// ok result is false for anonymous requests.
+func PrincipalFrom(r *http.Request) (p *Principal, ok bool) {
+	p, ok = r.Context().Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// ErrInvalidAPIKey is returned by the lookup function of APIKeyAuth for
+// an unknown key.
+var ErrInvalidAPIKey = errors.New("httpx: invalid API key")
+
+// A KeySource extracts the API key of a request, returning "" when the
+// request has none.
+type KeySource func(r *http.Request) string
+
+// HeaderKey returns a KeySource reading the key from the header name,
+// such as X-API-Key. A key in the Authorization header may carry a
+// Bearer prefix.
+func HeaderKey(name string) KeySource {
+	return func(r *http.Request) string {
+		v := r.Header.Get(name)
+		if scheme, token, ok := strings.Cut(v, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return v
+	}
+}
+
+// QueryKey returns a KeySource reading the key from the query param
+// name. Keys in URLs end up in logs and browser histories, so query keys
+// are best limited to keys of low privilege.
+func QueryKey(name string) KeySource {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+// CookieKey returns a KeySource reading the key from the cookie name.
+func CookieKey(name string) KeySource {
+	return func(r *http.Request) string {
+		if c, err := r.Cookie(name); err == nil {
+			return c.Value
+		}
+		return ""
+	}
+}
+
+// APIKeyAuth is a middleware that authenticates requests with the API
+// key found by the first of sources to find one, and sets the Principal
+// returned by lookup for it on the request. The default source is the
+// X-API-Key header.
+//
+// Requests without a key, or with a key for which lookup returns
+// ErrInvalidAPIKey, are rejected with a 401 Unauthorized StatusError.
+// Lookup may return a 403 Forbidden StatusError for a known key that is
+// revoked or suspended; its other errors are returned as is.
+//
+// Lookup should compare keys in constant time, as the one returned by
+// APIKeys does, so that keys can't be guessed from response times.
+func APIKeyAuth(lookup func(key string) (Principal, error), sources ...KeySource) Middleware {
+	if len(sources) == 0 {
+		sources = []KeySource{HeaderKey("X-API-Key")}
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			var key string
+			for _, source := range sources {
+				if key = source(r); key != "" {
+					break
+				}
+			}
+			if key == "" {
+				return securityError(r, SecurityAuthFailure, http.StatusUnauthorized, "API key required")
+			}
+			p, err := lookup(key)
+			if errors.Is(err, ErrInvalidAPIKey) {
+				return securityError(r, SecurityAuthFailure, http.StatusUnauthorized, "invalid API key")
+			}
+			if err != nil {
+				return err
+			}
+			return next.ServeHTTP(w, WithPrincipal(r, &p))
+		})
+	}
+}
+
+// APIKeys returns a lookup function for APIKeyAuth serving a fixed set
+// of keys. Every key is compared, in constant time, so that the time
+// taken doesn't reveal how much of a key is right.
+func APIKeys(keys map[string]Principal) func(key string) (Principal, error) {
+	type entry struct {
+		sum [sha256.Size]byte
+		p   Principal
+	}
+	entries := make([]entry, 0, len(keys))
+	for key, p := range keys {
+		entries = append(entries, entry{sha256.Sum256([]byte(key)), p})
+	}
+	return func(key string) (Principal, error) {
+		sum := sha256.Sum256([]byte(key))
+		found := -1
+		for i := range entries {
+			if subtle.ConstantTimeCompare(sum[:], entries[i].sum[:]) == 1 {
+				found = i
+			}
+		}
+		if found < 0 {
+			return Principal{}, ErrInvalidAPIKey
+		}
+		return entries[found].p, nil
+	}
+}
+
+// User returns the claims of the user making an authenticated request,
+// such as those of the ID token of an OpenID Connect login. The ok
+// result is false for anonymous requests.
+func User(r *http.Request) (claims map[string]interface{}, ok bool) {
+	p, ok := PrincipalFrom(r)
+	if !ok {
+		return nil, false
+	}
+	return p.Claims, true
+}
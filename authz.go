@@ -0,0 +1,137 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Route metadata keys declared by Scopes and Roles.
+const (
+	scopesKey = "auth.scopes"
+	rolesKey  = "auth.roles"
+)
+
+// Scopes returns a RouteOption declaring the scopes a Principal needs
+// to be granted, all of them, to use a route. The scopes are checked by
+// RequireScopes and Authorize.
+func Scopes(scopes ...string) RouteOption {
+	return Meta(scopesKey, scopes)
+}
+
+// Roles returns a RouteOption declaring the roles allowed to use a
+// route; a Principal needs one of them. The roles are checked by
+// RequireRole and Authorize.
+func Roles(roles ...string) RouteOption {
+	return Meta(rolesKey, roles)
+}
+
+// Requirement is what a request needs to be authorized.
+type Requirement struct {
+	// Scopes lists the scopes that all need to be granted.
+	Scopes []string
+
+	// Roles lists the roles of which one is needed.
+	Roles []string
+}
+
+// A Policy decides whether the Principal of a request meets a
+// Requirement, returning nil to allow the request. Policies may consult
+// an external engine, such as OPA or casbin. An error that is not a
+// StatusError denies the request with a 403 Forbidden Problem.
+type Policy interface {
+	Authorize(r *http.Request, p *Principal, req Requirement) error
+}
+
+// The PolicyFunc type is an adapter to allow the use of ordinary
+// functions as policies.
+type PolicyFunc func(r *http.Request, p *Principal, req Requirement) error
+
+// Authorize calls fn(r, p, req).
+func (fn PolicyFunc) Authorize(r *http.Request, p *Principal, req Requirement) error {
+	return fn(r, p, req)
+}
+
+// DefaultPolicy allows a Principal granted all of the required scopes
+// and having one of the required roles, if any.
+var DefaultPolicy Policy = PolicyFunc(func(r *http.Request, p *Principal, req Requirement) error {
+	for _, scope := range req.Scopes {
+		if !contains(p.Scopes, scope) {
+			return NewProblem(http.StatusForbidden, "missing scope "+scope)
+		}
+	}
+	if len(req.Roles) == 0 {
+		return nil
+	}
+	for _, role := range req.Roles {
+		if contains(p.Roles, role) {
+			return nil
+		}
+	}
+	return NewProblem(http.StatusForbidden, "requires role "+strings.Join(req.Roles, " or "))
+})
+
+// RequireScopes is a middleware that allows the requests whose
+// Principal is granted scopes, along with the scopes declared for the
+// route with Scopes. Anonymous requests are rejected with a 401
+// Unauthorized StatusError, and requests missing a scope with a 403
+// Forbidden Problem.
+//
+//	mux.With(httpx.RequireScopes("users:write")).Post("/users", createUser)
+func RequireScopes(scopes ...string) Middleware {
+	return authorize(DefaultPolicy, Requirement{Scopes: scopes}, true, false, false)
+}
+
+// RequireRole is a middleware that allows the requests whose Principal
+// has one of roles, or of the roles declared for the route with Roles.
+// Anonymous requests are rejected with a 401 Unauthorized StatusError,
+// and other requests with a 403 Forbidden Problem.
+func RequireRole(roles ...string) Middleware {
+	return authorize(DefaultPolicy, Requirement{Roles: roles}, false, true, false)
+}
+
+// Authorize is a middleware that checks the requirements declared for
+// the routes with Scopes and Roles with policy, or DefaultPolicy when
+// policy is nil. Requests to routes without requirements are passed
+// through, anonymous or not.
+func Authorize(policy Policy) Middleware {
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+	return authorize(policy, Requirement{}, true, true, true)
+}
+
+// authorize returns a middleware checking base, extended with the
+// scopes and roles declared for the route when asked to, with policy.
+// When optional is set, requests without any requirement are passed
+// through; otherwise they still need a Principal.
+func authorize(policy Policy, base Requirement, scopes, roles, optional bool) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			req := base
+			if ri, ok := CurrentRoute(r); ok {
+				if declared, ok := ri.Metadata[scopesKey].([]string); ok && scopes {
+					req.Scopes = append(req.Scopes[:len(req.Scopes):len(req.Scopes)], declared...)
+				}
+				if declared, ok := ri.Metadata[rolesKey].([]string); ok && roles {
+					req.Roles = append(req.Roles[:len(req.Roles):len(req.Roles)], declared...)
+				}
+			}
+			if optional && len(req.Scopes) == 0 && len(req.Roles) == 0 {
+				return next.ServeHTTP(w, r)
+			}
+
+			p, ok := PrincipalFrom(r)
+			if !ok {
+				return securityError(r, SecurityAuthFailure, http.StatusUnauthorized, "authentication required")
+			}
+			if err := policy.Authorize(r, p, req); err != nil {
+				ReportSecurityEvent(r, SecurityAccessDenied, err.Error())
+				if _, ok := err.(StatusError); ok {
+					return err
+				}
+				return NewProblem(http.StatusForbidden, err.Error())
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BatchLimits bounds the work of a batch request. The zero value of each
+// field selects its default.
+type BatchLimits struct {
+	// MaxItems is the number of sub-requests a batch may hold. Larger
+	// batches are rejected with a 413 Request Entity Too Large. The
+	// default is 20.
+	MaxItems int
+
+	// Concurrency is the number of sub-requests of a batch run at once.
+	// The default is 4.
+	Concurrency int
+
+	// MaxBodyBytes is the size of the largest batch request body. The
+	// default is 1 MiB.
+	MaxBodyBytes int64
+}
+
+// BatchRequest is a sub-request of a batch.
+type BatchRequest struct {
+	// Method is the method of the sub-request. The default is GET.
+	Method string `json:"method,omitempty"`
+
+	// Path is the path of the sub-request, with its query, such as
+	// "/users/42?fields=name".
+	Path string `json:"path"`
+
+	// Headers are set on the sub-request, over the headers of the batch
+	// request.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Body is the JSON body of the sub-request.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse is the response to a sub-request of a batch.
+type BatchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Body is the body of the response: the JSON value of JSON
+	// responses, and a JSON string otherwise.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// batchKey marks the context of sub-requests, so that batches can't
+// nest.
+type batchKey struct{}
+
+// Batch returns a handler running a batch of sub-requests, posted as a
+// JSON array of BatchRequests, through mux with Mux.DoRequest, and
+// answering with the JSON array of their BatchResponses, in the order
+// of the batch. It lets clients, such as mobile apps, save round trips:
+//
+//	mux.Post("/batch", httpx.Batch(mux, httpx.BatchLimits{}).ServeHTTP)
+//
+//	[{"path": "/users/42"}, {"method": "POST", "path": "/events", "body": {"type": "open"}}]
+//
+// Sub-requests carry the headers, remote address and context of the
+// batch request, so that they are authenticated and canceled with it,
+// and each goes through the middlewares and the routing of mux. The
+// Idempotency-Key of the batch is suffixed with the index of each
+// sub-request, such as "k-0", so that the sub-requests aren't taken for
+// replays of each other. A sub-request whose handler panics gets a 500
+// response, and the panic is logged. The batch succeeds with a 200 OK
+// whatever the statuses of the sub-requests; batches that are
+// malformed, too large, or nested in another batch fail with a
+// StatusError.
+func Batch(mux *Mux, limits BatchLimits) Handler {
+	if limits.MaxItems <= 0 {
+		limits.MaxItems = 20
+	}
+	if limits.Concurrency <= 0 {
+		limits.Concurrency = 4
+	}
+	if limits.MaxBodyBytes <= 0 {
+		limits.MaxBodyBytes = 1 << 20
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Context().Value(batchKey{}) != nil {
+			return Error(http.StatusBadRequest, "batches can't be nested")
+		}
+
+		var reqs []BatchRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limits.MaxBodyBytes))
+		if err := dec.Decode(&reqs); err != nil {
+			var mErr *http.MaxBytesError
+			if errors.As(err, &mErr) {
+				return Error(http.StatusRequestEntityTooLarge, "batch body too large")
+			}
+			return Error(http.StatusBadRequest, "request body is not a JSON array of requests")
+		}
+		if len(reqs) > limits.MaxItems {
+			return Errorf(http.StatusRequestEntityTooLarge, "batch holds %d requests, more than %d", len(reqs), limits.MaxItems)
+		}
+		var errs FieldErrors
+		for i, req := range reqs {
+			if !strings.HasPrefix(req.Path, "/") {
+				errs.Add("["+strconv.Itoa(i)+"].path", "must be an absolute path")
+			}
+		}
+		if err := errs.Err(); err != nil {
+			return err
+		}
+
+		ctx := context.WithValue(r.Context(), batchKey{}, true)
+		logger := LoggerFrom(r)
+		resps := make([]BatchResponse, len(reqs))
+		sem := make(chan struct{}, limits.Concurrency)
+		var wg sync.WaitGroup
+		for i, req := range reqs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, req BatchRequest) {
+				defer func() {
+					// Panics of sub-requests happen off the connection
+					// goroutine, where net/http can't recover them.
+					if v := recover(); v != nil {
+						logger.ErrorContext(ctx, "batch sub-request panicked", "path", req.Path,
+							"panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+						resps[i] = batchError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+					}
+					<-sem
+					wg.Done()
+				}()
+				resps[i] = batchDo(ctx, mux, r, i, req)
+			}(i, req)
+		}
+		wg.Wait()
+
+		return JSON(w, http.StatusOK, resps)
+	})
+}
+
+// batchDo runs the sub-request req, at index i of the batch request r,
+// through mux.
+func batchDo(ctx context.Context, mux *Mux, r *http.Request, i int, req BatchRequest) BatchResponse {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if len(req.Body) > 0 {
+		body = bytes.NewReader(req.Body)
+	}
+	sub, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), req.Path, body)
+	if err != nil {
+		return batchError(http.StatusBadRequest, "invalid request: "+err.Error())
+	}
+	sub.RequestURI = sub.URL.RequestURI()
+	sub.RemoteAddr = r.RemoteAddr
+	sub.Host = r.Host
+	sub.TLS = r.TLS
+	sub.Header = r.Header.Clone()
+	sub.Header.Del("Content-Length")
+	sub.Header.Del("Content-Type")
+	if key := sub.Header.Get("Idempotency-Key"); key != "" {
+		// Sub-requests aren't replays of each other.
+		sub.Header.Set("Idempotency-Key", key+"-"+strconv.Itoa(i))
+	}
+	if body != nil {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range req.Headers {
+		sub.Header.Set(k, v)
+	}
+
+	resp := mux.DoRequest(sub)
+	b, _ := io.ReadAll(resp.Body)
+	out := BatchResponse{Status: resp.StatusCode}
+	if len(resp.Header) > 0 {
+		out.Headers = make(map[string]string, len(resp.Header))
+		for k := range resp.Header {
+			out.Headers[k] = resp.Header.Get(k)
+		}
+	}
+	switch {
+	case len(b) == 0:
+	case isJSONMediaType(resp.Header.Get("Content-Type")) && json.Valid(b):
+		out.Body = b
+	default:
+		out.Body, _ = json.Marshal(string(b))
+	}
+	return out
+}
+
+// batchError returns the BatchResponse of a sub-request that couldn't
+// be run.
+func batchError(status int, msg string) BatchResponse {
+	body, _ := json.Marshal(map[string]string{"message": msg})
+	return BatchResponse{Status: status, Body: body}
+}
+
+// isJSONMediaType reports whether contentType is JSON, such as
+// application/json or application/problem+json.
+func isJSONMediaType(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestBatchIdempotencyKey checks that each sub-request gets its own
+// idempotency key.
+func TestBatchIdempotencyKey(t *testing.T) {
+	m := NewMux()
+	m.Post("/echo", func(w http.ResponseWriter, r *http.Request) error {
+		return JSON(w, http.StatusOK, r.Header.Get("Idempotency-Key"))
+	})
+	m.Post("/batch", Batch(m, BatchLimits{}).ServeHTTP)
+
+	r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{"method": "POST", "path": "/echo"}, {"method": "POST", "path": "/echo"}]`))
+	r.Header.Set("Idempotency-Key", "k")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, r)
+
+	var resps []BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resps); err != nil || len(resps) != 2 {
+		t.Fatalf("batch response %d %q", rec.Code, rec.Body.String())
+	}
+	for i, want := range []string{`"k-0"`, `"k-1"`} {
+		if got := strings.TrimSpace(string(resps[i].Body)); got != want {
+			t.Errorf("sub-request %d had key %s, want %s", i, got, want)
+		}
+	}
+}
+
+// TestBatchPanic checks that a panicking sub-request gets a 500
+// response rather than crashing the process.
+func TestBatchPanic(t *testing.T) {
+	m := NewMux()
+	m.Get("/boom", func(w http.ResponseWriter, r *http.Request) error {
+		panic("boom")
+	})
+	m.Get("/ok", func(w http.ResponseWriter, r *http.Request) error {
+		return JSON(w, http.StatusOK, "ok")
+	})
+	m.Post("/batch", Batch(m, BatchLimits{}).ServeHTTP)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{"path": "/boom"}, {"path": "/ok"}]`)))
+
+	var resps []BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resps); err != nil || len(resps) != 2 {
+		t.Fatalf("batch response %d %q", rec.Code, rec.Body.String())
+	}
+	if resps[0].Status != http.StatusInternalServerError || resps[1].Status != http.StatusOK {
+		t.Errorf("sub-request statuses %d and %d, want 500 and 200", resps[0].Status, resps[1].Status)
+	}
+}
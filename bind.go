@@ -0,0 +1,116 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+)
+
+// maxMultipartMemory is the number of bytes of a multipart form kept in
+// memory by Bind; larger file parts are stored in temporary files.
+const maxMultipartMemory = 32 << 20
+
+// Bind decodes the request body into v with the codec registered for
+// the Content-Type of the request. A request without a Content-Type is
+// decoded as JSON. URL encoded and multipart forms are decoded with
+// DecodeForm, and multipart file parts are decoded into
+// *multipart.FileHeader and []*multipart.FileHeader fields.
+//
+// Bind returns a 415 Unsupported Media Type StatusError when no codec
+// is registered for the Content-Type, and a 400 Bad Request StatusError
+// when the body is empty or can't be decoded. Form and JSON values of
+// the wrong type are reported as FieldErrors. A body cut short by
+// http.MaxBytesReader is reported as a 413 Request Entity Too Large.
+//
+// When v implements Validator, Bind returns the error of its Validate
+// method, sent as a 422 Unprocessable Entity when it is not a
+// StatusError.
+func Bind(r *http.Request, v interface{}) error {
+	if err := bind(r, v); err != nil {
+		return err
+	}
+	if val, ok := v.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			if _, ok := err.(StatusError); ok {
+				return err
+			}
+			return Error(http.StatusUnprocessableEntity, err.Error())
+		}
+	}
+	return nil
+}
+
+func bind(r *http.Request, v interface{}) error {
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return Errorf(http.StatusBadRequest, "invalid content type: %v", err)
+		}
+		mediaType = mt
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return formError(err)
+		}
+		return DecodeForm(r.PostForm, v)
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+			return formError(err)
+		}
+		return decodeForm(r.MultipartForm.Value, r.MultipartForm.File, v)
+	}
+
+	c, ok := lookupCodec(mediaType)
+	if !ok {
+		return Errorf(http.StatusUnsupportedMediaType, "unsupported content type %q", mediaType)
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return Error(http.StatusBadRequest, "missing request body")
+	}
+	if err := c.Decode(r.Body, v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Error(http.StatusBadRequest, "missing request body")
+		}
+		var mbErr *http.MaxBytesError
+		if errors.As(err, &mbErr) {
+			return Errorf(http.StatusRequestEntityTooLarge, "request body exceeds %d bytes", mbErr.Limit)
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return FieldErrors{{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)}}
+		}
+		return Errorf(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func formError(err error) error {
+	var mbErr *http.MaxBytesError
+	if errors.As(err, &mbErr) {
+		return Errorf(http.StatusRequestEntityTooLarge, "request body exceeds %d bytes", mbErr.Limit)
+	}
+	return Errorf(http.StatusBadRequest, "invalid form: %v", err)
+}
+
+// jsonKind names the JSON type decoded into t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
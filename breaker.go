@@ -0,0 +1,239 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned by a client request short-circuited by an
+// open Breaker.
+var ErrBreakerOpen = errors.New("httpx: circuit breaker is open")
+
+// BreakerState is the state of a Breaker.
+type BreakerState int
+
+// Breaker states.
+const (
+	// BreakerClosed lets all requests through while counting failures.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen rejects all requests until the open timeout expires.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a few trial requests through to probe whether
+	// the downstream dependency has recovered.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "BreakerState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// BreakerOptions configures a Breaker.
+type BreakerOptions struct {
+	// Window is the period over which the failure rate is measured. The
+	// default is 10 seconds.
+	Window time.Duration
+
+	// MinRequests is the number of requests in a window below which the
+	// breaker doesn't trip, whatever the failure rate. The default is 20.
+	MinRequests int
+
+	// FailureRate is the fraction of failed requests in a window that
+	// trips the breaker open. The default is 0.5.
+	FailureRate float64
+
+	// OpenTimeout is how long the breaker stays open before it lets
+	// trial requests through. The default is 30 seconds.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of trial requests that must all
+	// succeed for the breaker to close again. The default is 1.
+	HalfOpenRequests int
+
+	// OnStateChange, when set, is called on each state transition, for
+	// example to export the state as a metric. It must not block.
+	OnStateChange func(from, to BreakerState)
+}
+
+// Breaker is a circuit breaker. It counts the failures of the requests
+// it guards and, once the failure rate is too high, rejects requests
+// for a while instead of waiting on a failing downstream dependency. A
+// Breaker must be created with NewBreaker.
+//
+// A Breaker guards a group of routes with Middleware, or the requests
+// of a Client with ClientMiddleware:
+//
+//	billing := httpx.NewBreaker(httpx.BreakerOptions{})
+//	client := httpx.NewClient(billingURL, nil).With(billing.ClientMiddleware)
+type Breaker struct {
+	opts BreakerOptions
+
+	mu       sync.Mutex
+	state    BreakerState
+	gen      uint64
+	start    time.Time // start of the window, or of the open state
+	requests int
+	failures int
+	trials   int
+}
+
+// NewBreaker returns a newly initialized Breaker in the closed state.
+func NewBreaker(opts BreakerOptions) *Breaker {
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = 20
+	}
+	if opts.FailureRate <= 0 {
+		opts.FailureRate = 0.5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 30 * time.Second
+	}
+	if opts.HalfOpenRequests <= 0 {
+		opts.HalfOpenRequests = 1
+	}
+	return &Breaker{opts: opts, start: time.Now()}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(time.Now())
+	return b.state
+}
+
+// Middleware is a middleware that guards the next handler. Requests
+// that fail with a 5xx status, or panic, count as failures. While the breaker is
+// open, requests are rejected with a 503 Service Unavailable
+// StatusError and a Retry-After header of the remaining open time.
+func (b *Breaker) Middleware(next Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		gen, wait, ok := b.allow()
+		if !ok {
+			return Unavailable(wait, "circuit breaker is open")
+		}
+		rw := wrapWriter(w)
+		success := false
+		defer func() {
+			// A panic is recorded as a failure, and goes on up.
+			b.record(gen, success)
+		}()
+		err := next.ServeHTTP(rw, r)
+		success = statusOf(r, rw, err) < 500
+		return err
+	})
+}
+
+// ClientMiddleware is a ClientMiddleware that guards outbound requests.
+// Transport errors and 5xx responses count as failures. While the
+// breaker is open, requests fail with ErrBreakerOpen without being
+// sent.
+func (b *Breaker) ClientMiddleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		gen, _, ok := b.allow()
+		if !ok {
+			return nil, ErrBreakerOpen
+		}
+		success := false
+		defer func() { b.record(gen, success) }()
+		resp, err := next.RoundTrip(r)
+		success = err == nil && resp.StatusCode < 500
+		return resp, err
+	})
+}
+
+// allow reports whether a request may proceed, returning the
+// generation of the state it was admitted in, or how long the breaker
+// remains open.
+func (b *Breaker) allow() (gen uint64, wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advance(now)
+	switch b.state {
+	case BreakerOpen:
+		return 0, b.opts.OpenTimeout - now.Sub(b.start), false
+	case BreakerHalfOpen:
+		if b.trials >= b.opts.HalfOpenRequests {
+			return 0, time.Second, false
+		}
+		b.trials++
+	}
+	return b.gen, 0, true
+}
+
+// record counts the outcome of a request admitted in generation gen.
+// Outcomes of requests admitted before the last state change are
+// ignored.
+func (b *Breaker) record(gen uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advance(now)
+	if gen != b.gen {
+		return
+	}
+	switch b.state {
+	case BreakerClosed:
+		b.requests++
+		if !success {
+			b.failures++
+		}
+		if b.requests >= b.opts.MinRequests &&
+			float64(b.failures) >= b.opts.FailureRate*float64(b.requests) {
+			b.transition(BreakerOpen, now)
+		}
+	case BreakerHalfOpen:
+		if !success {
+			b.transition(BreakerOpen, now)
+			return
+		}
+		b.requests++
+		if b.requests >= b.opts.HalfOpenRequests {
+			b.transition(BreakerClosed, now)
+		}
+	}
+}
+
+// advance applies the transitions due to the passing of time: the
+// start of a new window, and the end of the open state.
+func (b *Breaker) advance(now time.Time) {
+	switch b.state {
+	case BreakerClosed:
+		if now.Sub(b.start) >= b.opts.Window {
+			b.start = now
+			b.requests, b.failures = 0, 0
+		}
+	case BreakerOpen:
+		if now.Sub(b.start) >= b.opts.OpenTimeout {
+			b.transition(BreakerHalfOpen, now)
+		}
+	}
+}
+
+func (b *Breaker) transition(to BreakerState, now time.Time) {
+	from := b.state
+	b.state = to
+	b.gen++
+	b.start = now
+	b.requests, b.failures, b.trials = 0, 0, 0
+	if b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
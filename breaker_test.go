@@ -0,0 +1,93 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// breakerServe serves a request through the breaker, to a handler
+// failing with status, or panicking when status is 0.
+func breakerServe(b *Breaker, status int) int {
+	h := b.Middleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if status == 0 {
+			panic("boom")
+		}
+		w.WriteHeader(status)
+		return nil
+	}))
+	m := NewMux()
+	m.Handle("/", h)
+	rec := httptest.NewRecorder()
+	func() {
+		defer func() { recover() }()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	return rec.Code
+}
+
+// TestBreakerCycle takes a breaker from closed to open, half-open and
+// closed again.
+func TestBreakerCycle(t *testing.T) {
+	var changes []string
+	b := NewBreaker(BreakerOptions{
+		MinRequests: 4,
+		OpenTimeout: 20 * time.Millisecond,
+		OnStateChange: func(from, to BreakerState) {
+			changes = append(changes, from.String()+">"+to.String())
+		},
+	})
+	for _, status := range []int{200, 500, 200, 500} {
+		breakerServe(b, status)
+	}
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state %s after half of the requests failed, want open", s)
+	}
+	if code := breakerServe(b, 200); code != http.StatusServiceUnavailable {
+		t.Errorf("open breaker let a request through, status %d", code)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("state %s after the open timeout, want half-open", s)
+	}
+	if code := breakerServe(b, 200); code != http.StatusOK {
+		t.Errorf("half-open breaker rejected the trial, status %d", code)
+	}
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state %s after a successful trial, want closed", s)
+	}
+	want := []string{"closed>open", "open>half-open", "half-open>closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("state changes %v, want %v", changes, want)
+			break
+		}
+	}
+}
+
+// TestBreakerPanic checks that a trial that panics reopens the breaker,
+// rather than leaving it half-open for good.
+func TestBreakerPanic(t *testing.T) {
+	b := NewBreaker(BreakerOptions{MinRequests: 1, OpenTimeout: 20 * time.Millisecond})
+	breakerServe(b, 500)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state %s, want open", s)
+	}
+	time.Sleep(25 * time.Millisecond)
+	breakerServe(b, 0)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state %s after a panicking trial, want open", s)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if code := breakerServe(b, 200); code != http.StatusOK {
+		t.Errorf("trial after the open timeout got status %d", code)
+	}
+	if s := b.State(); s != BreakerClosed {
+		t.Errorf("state %s after a successful trial, want closed", s)
+	}
+}
@@ -0,0 +1,172 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// BufferedResponse is a response captured by the Buffer middleware,
+// before it is sent to the client.
+type BufferedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// A ResponseTransform modifies a buffered response, for example to
+// minify it or to inject values in it.
+type ResponseTransform func(r *http.Request, resp *BufferedResponse) error
+
+// BufferOptions configures the Buffer middleware.
+type BufferOptions struct {
+	// MaxSize is the size of the largest response body that is
+	// buffered. The default is 1 MiB.
+	MaxSize int
+
+	// Transforms are applied in order to each buffered response.
+	Transforms []ResponseTransform
+}
+
+// Buffer is a middleware that captures the response of the next handler
+// and applies opts.Transforms to it before it is sent, with a
+// Content-Length header of its final size:
+//
+//	mux.Use(httpx.Buffer(httpx.BufferOptions{
+//		Transforms: []httpx.ResponseTransform{httpx.ETagTransform},
+//	}))
+//
+// Responses that aren't suited to buffering are passed through
+// untransformed: responses whose body exceeds opts.MaxSize, event
+// streams, and responses that the handler flushes or whose connection
+// it hijacks, such as WebSockets.
+func Buffer(opts BufferOptions) Middleware {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 1 << 20
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			bw := &bufferWriter{ResponseWriter: w, max: opts.MaxSize}
+			err := next.ServeHTTP(bw, r)
+			if bw.passthrough || bw.status == 0 {
+				return err
+			}
+
+			resp := &BufferedResponse{Status: bw.status, Header: w.Header(), Body: bw.buf.Bytes()}
+			if err == nil {
+				for _, transform := range opts.Transforms {
+					if err := transform(r, resp); err != nil {
+						return err
+					}
+				}
+			}
+			if resp.Status != http.StatusNoContent && resp.Status != http.StatusNotModified {
+				resp.Header.Set("Content-Length", strconv.Itoa(len(resp.Body)))
+			} else {
+				resp.Header.Del("Content-Length")
+				resp.Body = nil
+			}
+			w.WriteHeader(resp.Status)
+			if r.Method != http.MethodHead {
+				if _, werr := w.Write(resp.Body); werr != nil && err == nil {
+					err = werr
+				}
+			}
+			return err
+		})
+	}
+}
+
+// ETagTransform is a ResponseTransform that sets a strong ETag computed
+// from the body of successful GET and HEAD responses that have none,
+// and turns the response into a 304 Not Modified when it matches the
+// If-None-Match header of the request.
+func ETagTransform(r *http.Request, resp *BufferedResponse) error {
+	if resp.Status != http.StatusOK || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return nil
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(resp.Body)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		resp.Header.Set("ETag", etag)
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" && matchETag(inm, etag, true, false) {
+		resp.Status = http.StatusNotModified
+		for _, h := range []string{"Content-Type", "Content-Encoding"} {
+			resp.Header.Del(h)
+		}
+	}
+	return nil
+}
+
+// bufferWriter buffers a response until it exceeds max bytes or turns
+// out to be a stream, then passes it through.
+type bufferWriter struct {
+	http.ResponseWriter
+	max         int
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (w *bufferWriter) WriteHeader(status int) {
+	switch {
+	case w.passthrough || status < 200:
+		w.ResponseWriter.WriteHeader(status)
+	case w.status == 0:
+		w.status = status
+	}
+}
+
+func (w *bufferWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	mt, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mt == "text/event-stream" || w.buf.Len()+len(b) > w.max {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// startPassthrough sends the status and the body buffered so far, and
+// passes the rest of the response through.
+func (w *bufferWriter) startPassthrough() error {
+	if w.passthrough {
+		return nil
+	}
+	w.passthrough = true
+	if w.status == 0 {
+		return nil
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *bufferWriter) Flush() {
+	w.startPassthrough()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *bufferWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.passthrough = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *bufferWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
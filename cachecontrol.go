@@ -0,0 +1,208 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheKey is the route metadata key declared by Cache.
+const cacheKey = "cache.control"
+
+// CachePolicy is a Cache-Control header value, built with CacheControl:
+//
+//	policy := httpx.CacheControl().Public().MaxAge(time.Hour).StaleWhileRevalidate(time.Minute)
+//
+// CachePolicy values are immutable; each method returns a new policy.
+type CachePolicy struct {
+	flags uint16
+	ages  [4]time.Duration
+	set   uint8
+}
+
+const (
+	cachePublic uint16 = 1 << iota
+	cachePrivate
+	cacheNoCache
+	cacheNoStore
+	cacheNoTransform
+	cacheMustRevalidate
+	cacheProxyRevalidate
+	cacheImmutable
+)
+
+var cacheFlagNames = []string{
+	"public", "private", "no-cache", "no-store",
+	"no-transform", "must-revalidate", "proxy-revalidate", "immutable",
+}
+
+const (
+	cacheMaxAge = iota
+	cacheSMaxAge
+	cacheStaleWhileRevalidate
+	cacheStaleIfError
+)
+
+var cacheAgeNames = []string{"max-age", "s-maxage", "stale-while-revalidate", "stale-if-error"}
+
+// CacheControl returns an empty CachePolicy.
+func CacheControl() CachePolicy {
+	return CachePolicy{}
+}
+
+// Public allows shared caches to store the response. It removes
+// Private.
+func (p CachePolicy) Public() CachePolicy {
+	p.flags = p.flags&^cachePrivate | cachePublic
+	return p
+}
+
+// Private restricts the storage of the response to the cache of the
+// client. It removes Public.
+func (p CachePolicy) Private() CachePolicy {
+	p.flags = p.flags&^cachePublic | cachePrivate
+	return p
+}
+
+// NoCache requires caches to revalidate the response before each use.
+func (p CachePolicy) NoCache() CachePolicy {
+	p.flags |= cacheNoCache
+	return p
+}
+
+// NoStore forbids caches from storing the response.
+func (p CachePolicy) NoStore() CachePolicy {
+	p.flags |= cacheNoStore
+	return p
+}
+
+// NoTransform forbids intermediaries from transforming the response.
+func (p CachePolicy) NoTransform() CachePolicy {
+	p.flags |= cacheNoTransform
+	return p
+}
+
+// MustRevalidate forbids caches from using the response once stale
+// without revalidating it.
+func (p CachePolicy) MustRevalidate() CachePolicy {
+	p.flags |= cacheMustRevalidate
+	return p
+}
+
+// ProxyRevalidate is like MustRevalidate, for shared caches only.
+func (p CachePolicy) ProxyRevalidate() CachePolicy {
+	p.flags |= cacheProxyRevalidate
+	return p
+}
+
+// Immutable tells caches that the response won't change while fresh.
+func (p CachePolicy) Immutable() CachePolicy {
+	p.flags |= cacheImmutable
+	return p
+}
+
+// MaxAge sets how long the response stays fresh.
+func (p CachePolicy) MaxAge(d time.Duration) CachePolicy {
+	return p.age(cacheMaxAge, d)
+}
+
+// SMaxAge sets how long the response stays fresh in shared caches,
+// overriding MaxAge.
+func (p CachePolicy) SMaxAge(d time.Duration) CachePolicy {
+	return p.age(cacheSMaxAge, d)
+}
+
+// StaleWhileRevalidate allows caches to serve the response for d after
+// it became stale, while they revalidate it in the background.
+func (p CachePolicy) StaleWhileRevalidate(d time.Duration) CachePolicy {
+	return p.age(cacheStaleWhileRevalidate, d)
+}
+
+// StaleIfError allows caches to serve the response for d after it
+// became stale, when revalidating it fails.
+func (p CachePolicy) StaleIfError(d time.Duration) CachePolicy {
+	return p.age(cacheStaleIfError, d)
+}
+
+func (p CachePolicy) age(i int, d time.Duration) CachePolicy {
+	if d < 0 {
+		d = 0
+	}
+	p.ages[i] = d
+	p.set |= 1 << i
+	return p
+}
+
+// String returns the Cache-Control header value of the policy. Ages
+// are rounded down to whole seconds.
+func (p CachePolicy) String() string {
+	var directives []string
+	for i, name := range cacheFlagNames {
+		if p.flags&(1<<i) != 0 {
+			directives = append(directives, name)
+		}
+	}
+	for i, name := range cacheAgeNames {
+		if p.set&(1<<i) != 0 {
+			directives = append(directives, name+"="+strconv.FormatInt(int64(p.ages[i]/time.Second), 10))
+		}
+	}
+	return strings.Join(directives, ", ")
+}
+
+// Middleware returns a middleware that sets the Cache-Control header of
+// successful responses to the policy, unless the next handler set one.
+// Error responses, 400 and above, are left without the header.
+func (p CachePolicy) Middleware() Middleware {
+	return cachePolicies(p, false)
+}
+
+// Cache returns a RouteOption that declares the cache policy of a
+// route, applied by the CachePolicies middleware.
+func Cache(p CachePolicy) RouteOption {
+	return Meta(cacheKey, p)
+}
+
+// CachePolicies is a middleware that sets the Cache-Control header of
+// successful responses to the policy declared for their route with
+// Cache, or to fallback for routes without one, unless the handler set
+// the header itself:
+//
+//	mux.Use(httpx.CachePolicies(httpx.CacheControl().NoStore()))
+//	mux.Get("/logo.png", logo, httpx.Cache(httpx.CacheControl().Public().MaxAge(24*time.Hour).Immutable()))
+//
+// Error responses, 400 and above, are left without the header.
+func CachePolicies(fallback CachePolicy) Middleware {
+	return cachePolicies(fallback, true)
+}
+
+// cachePolicies returns a middleware applying policy, or when perRoute
+// is set the policy declared for the route, if any.
+func cachePolicies(policy CachePolicy, perRoute bool) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			value := policy.String()
+			if ri, ok := CurrentRoute(r); ok && perRoute {
+				if declared, ok := ri.Metadata[cacheKey].(CachePolicy); ok {
+					value = declared.String()
+				}
+			}
+			if value == "" {
+				return next.ServeHTTP(w, r)
+			}
+
+			dw := &deferWriter{ResponseWriter: w}
+			dw.fn = func(h http.Header) {
+				if dw.status < 400 && h.Get("Cache-Control") == "" {
+					h.Set("Cache-Control", value)
+				}
+			}
+			err := next.ServeHTTP(dw, r)
+			if err == nil {
+				dw.apply()
+			}
+			return err
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CaptchaResult is the outcome of the server-side verification of a
+// captcha token.
+type CaptchaResult struct {
+	Success bool
+
+	// Score rates how likely the request is to come from a human, from
+	// 0 to 1, for providers that score requests, such as reCAPTCHA v3.
+	// It is 1 for providers that don't.
+	Score float64
+
+	// Action is the action the token was issued for, if any.
+	Action string
+
+	// Hostname is the site the captcha was solved on.
+	Hostname string
+}
+
+// A CaptchaProvider verifies captcha tokens with a captcha service.
+type CaptchaProvider interface {
+	// Field is the name of the form field carrying the token.
+	Field() string
+
+	// Verify checks token, solved by the client at remoteIP.
+	Verify(ctx context.Context, token, remoteIP string) (CaptchaResult, error)
+}
+
+// HCaptcha returns a CaptchaProvider for hCaptcha, with the secret key
+// of the site.
+func HCaptcha(secret string) CaptchaProvider {
+	return &siteVerify{endpoint: "https://api.hcaptcha.com/siteverify", field: "h-captcha-response", secret: secret}
+}
+
+// ReCaptcha returns a CaptchaProvider for Google reCAPTCHA, v2 or v3,
+// with the secret key of the site.
+func ReCaptcha(secret string) CaptchaProvider {
+	return &siteVerify{endpoint: "https://www.google.com/recaptcha/api/siteverify", field: "g-recaptcha-response", secret: secret}
+}
+
+// siteVerify verifies tokens with the siteverify API shared by hCaptcha
+// and reCAPTCHA.
+type siteVerify struct {
+	endpoint string
+	field    string
+	secret   string
+}
+
+func (p *siteVerify) Field() string {
+	return p.field
+}
+
+func (p *siteVerify) Verify(ctx context.Context, token, remoteIP string) (CaptchaResult, error) {
+	form := url.Values{"secret": {p.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return CaptchaResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return CaptchaResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return CaptchaResult{}, fmt.Errorf("httpx: captcha verification: %s", resp.Status)
+	}
+
+	var body struct {
+		Success  bool     `json:"success"`
+		Score    *float64 `json:"score"`
+		Action   string   `json:"action"`
+		Hostname string   `json:"hostname"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return CaptchaResult{}, fmt.Errorf("httpx: captcha verification: %w", err)
+	}
+	res := CaptchaResult{Success: body.Success, Score: 1, Action: body.Action, Hostname: body.Hostname}
+	if body.Score != nil {
+		res.Score = *body.Score
+	}
+	return res, nil
+}
+
+// CaptchaOptions configures the Captcha middleware.
+type CaptchaOptions struct {
+	// Provider verifies the tokens.
+	Provider CaptchaProvider
+
+	// MinScore is the lowest score accepted. The default accepts any
+	// successfully verified token.
+	MinScore float64
+
+	// Action, when set, is the action the tokens must have been issued
+	// for.
+	Action string
+}
+
+type captchaKey struct{}
+
+// Captcha is a middleware for form posts that verifies the captcha
+// token of the request form with opts.Provider, and rejects requests
+// without a valid token, or scoring below opts.MinScore, with a 403
+// Forbidden StatusError. The result is available to the next handler
+// with CaptchaFrom, for example to require a second factor for low
+// scores:
+//
+//	mux.With(httpx.Captcha(httpx.CaptchaOptions{Provider: httpx.HCaptcha(secret)})).Post("/signup", signup)
+//
+// A provider that can't be reached fails the request with its error.
+func Captcha(opts CaptchaOptions) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			token := r.PostFormValue(opts.Provider.Field())
+			if token == "" {
+				return securityError(r, SecurityCaptchaFailure, http.StatusForbidden, "captcha required")
+			}
+			var ip string
+			if addr := ClientIP(r); addr.IsValid() {
+				ip = addr.String()
+			}
+			res, err := opts.Provider.Verify(r.Context(), token, ip)
+			if err != nil {
+				return err
+			}
+			if !res.Success || res.Score < opts.MinScore || (opts.Action != "" && res.Action != opts.Action) {
+				return securityError(r, SecurityCaptchaFailure, http.StatusForbidden, "captcha verification failed")
+			}
+			return next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), captchaKey{}, res)))
+		})
+	}
+}
+
+// CaptchaFrom returns the captcha verified for the request by Captcha.
+func CaptchaFrom(r *http.Request) (CaptchaResult, bool) {
+	res, ok := r.Context().Value(captchaKey{}).(CaptchaResult)
+	return res, ok
+}
@@ -0,0 +1,31 @@
+// Package cbor registers a CBOR (RFC 8949) codec with httpx, so that
+// httpx.Render and httpx.Bind handle the application/cbor media type.
+// It is imported for its side effect:
+//
+//	import _ "github.com/eriklott/httpx/cbor"
+package cbor
+
+import (
+	"io"
+
+	"github.com/eriklott/httpx"
+	"github.com/fxamacker/cbor/v2"
+)
+
+func init() {
+	httpx.RegisterCodec("application/cbor", Codec{})
+}
+
+// Codec encodes and decodes values as CBOR. Struct fields are named by
+// their cbor tags, falling back to their json tags.
+type Codec struct{}
+
+// Encode writes the CBOR encoding of v to w.
+func (Codec) Encode(w io.Writer, v interface{}) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+// Decode reads a CBOR encoded value from r into v.
+func (Codec) Decode(r io.Reader, v interface{}) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
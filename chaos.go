@@ -0,0 +1,104 @@
+package httpx
+
+import (
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ChaosOptions configures the Chaos middleware.
+type ChaosOptions struct {
+	// Enabled turns fault injection on and off at runtime. When nil,
+	// faults are injected when the HTTPX_CHAOS environment variable is
+	// set to a true value, such as 1, as the middleware is built.
+	Enabled *atomic.Bool
+
+	// Patterns lists the route patterns that faults are injected into,
+	// in path.Match syntax. The default is every route.
+	Patterns []string
+
+	// Latency is the largest delay added to requests. Each request is
+	// delayed by a random duration up to Latency.
+	Latency time.Duration
+
+	// ErrorRate is the fraction of requests, from 0 to 1, that fail with
+	// ErrorStatus instead of reaching the next handler.
+	ErrorRate float64
+
+	// ErrorStatus is the status of the injected errors. The default is
+	// 503 Service Unavailable.
+	ErrorStatus int
+
+	// DropRate is the fraction of requests, from 0 to 1, whose
+	// connection is closed without a response.
+	DropRate float64
+}
+
+// Chaos is a middleware that injects latency, errors and dropped
+// connections into the requests of matching routes, to test how
+// clients and upstream services cope with a misbehaving service without
+// an external proxy:
+//
+//	mux.Use(httpx.Chaos(httpx.ChaosOptions{
+//		Patterns:  []string{"/payments/*"},
+//		Latency:   500 * time.Millisecond,
+//		ErrorRate: 0.05,
+//	}))
+//
+// Chaos is inert unless enabled with opts.Enabled or the HTTPX_CHAOS
+// environment variable, so it can be left in the middleware stack of a
+// production build.
+func Chaos(opts ChaosOptions) Middleware {
+	if opts.ErrorStatus == 0 {
+		opts.ErrorStatus = http.StatusServiceUnavailable
+	}
+	enabled := opts.Enabled
+	if enabled == nil {
+		enabled = new(atomic.Bool)
+		on, _ := strconv.ParseBool(os.Getenv("HTTPX_CHAOS"))
+		enabled.Store(on)
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if !enabled.Load() {
+				return next.ServeHTTP(w, r)
+			}
+			if len(opts.Patterns) > 0 {
+				ri, ok := CurrentRoute(r)
+				if !ok || !matchAny(opts.Patterns, ri.Pattern) {
+					return next.ServeHTTP(w, r)
+				}
+			}
+
+			if opts.Latency > 0 {
+				t := time.NewTimer(time.Duration(rand.Int63n(int64(opts.Latency) + 1)))
+				select {
+				case <-t.C:
+				case <-r.Context().Done():
+					t.Stop()
+					return r.Context().Err()
+				}
+			}
+			if opts.DropRate > 0 && rand.Float64() < opts.DropRate {
+				dropConnection(w)
+			}
+			if opts.ErrorRate > 0 && rand.Float64() < opts.ErrorRate {
+				return Error(opts.ErrorStatus, "injected fault")
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// dropConnection closes the connection of a request without sending a
+// response. It doesn't return.
+func dropConnection(w http.ResponseWriter) {
+	if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+		conn.Close()
+	}
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,288 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxErrorBody is the number of bytes of a non-2xx response body kept
+// in a ResponseError.
+const maxErrorBody = 4 << 10
+
+// ClientMiddleware wraps an http.RoundTripper. It is the client side
+// counterpart of Middleware.
+type ClientMiddleware func(http.RoundTripper) http.RoundTripper
+
+// The RoundTripperFunc type is an adapter to allow the use of ordinary
+// functions as http.RoundTrippers.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls fn(r).
+func (fn RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// Client is an HTTP client that sends requests through a stack of
+// ClientMiddlewares, and reports non-2xx responses as StatusErrors.
+type Client struct {
+	hc          *http.Client
+	base        string
+	middlewares []ClientMiddleware
+}
+
+// NewClient returns a newly initialized Client. Relative request paths
+// are resolved against baseURL, which may be empty. The underlying
+// http.Client is hc, or http.DefaultClient when hc is nil.
+func NewClient(baseURL string, hc *http.Client) *Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Client{
+		hc:          hc,
+		base:        baseURL,
+		middlewares: []ClientMiddleware{},
+	}
+}
+
+// Use appends a middleware to the Client middleware stack.
+func (c *Client) Use(middlewares ...ClientMiddleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// With returns a copy of the Client with additional middlewares.
+func (c *Client) With(middlewares ...ClientMiddleware) *Client {
+	mws := make([]ClientMiddleware, len(c.middlewares), len(c.middlewares)+len(middlewares))
+	copy(mws, c.middlewares)
+	return &Client{
+		hc:          c.hc,
+		base:        c.base,
+		middlewares: append(mws, middlewares...),
+	}
+}
+
+// NewRequest returns a new request for the path resolved against the
+// Client base URL. A body that is an io.Reader is sent as is, any other
+// non-nil body is encoded as JSON.
+func (c *Client) NewRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	u, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rd io.Reader
+	var contentType string
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		rd = b
+	default:
+		buf, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(buf)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+func (c *Client) resolve(path string) (string, error) {
+	if c.base == "" {
+		return path, nil
+	}
+	base, err := url.Parse(c.base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// Do sends the request through the Client middleware stack. A response
+// with a non-2xx status is closed and reported as a *ResponseError.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, &ResponseError{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       body,
+		}
+	}
+	return resp, nil
+}
+
+// transport returns the http.Client wrapped with the middleware stack,
+// the first middleware being the outermost.
+func (c *Client) transport() http.RoundTripper {
+	var rt http.RoundTripper = RoundTripperFunc(c.hc.Do)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		rt = c.middlewares[i](rt)
+	}
+	return rt
+}
+
+// DoJSON sends the request with Do and decodes a JSON response body
+// into v. The body is discarded when v is nil.
+func (c *Client) DoJSON(req *http.Request, v interface{}) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Get sends a GET request for path and decodes the JSON response into v.
+func (c *Client) Get(ctx context.Context, path string, v interface{}) error {
+	return c.send(ctx, http.MethodGet, path, nil, v)
+}
+
+// Post sends a POST request for path with a JSON encoded body and
+// decodes the JSON response into v.
+func (c *Client) Post(ctx context.Context, path string, body, v interface{}) error {
+	return c.send(ctx, http.MethodPost, path, body, v)
+}
+
+// Put sends a PUT request for path with a JSON encoded body and
+// decodes the JSON response into v.
+func (c *Client) Put(ctx context.Context, path string, body, v interface{}) error {
+	return c.send(ctx, http.MethodPut, path, body, v)
+}
+
+// Patch sends a PATCH request for path with a JSON encoded body and
+// decodes the JSON response into v.
+func (c *Client) Patch(ctx context.Context, path string, body, v interface{}) error {
+	return c.send(ctx, http.MethodPatch, path, body, v)
+}
+
+// Delete sends a DELETE request for path and decodes the JSON response
+// into v.
+func (c *Client) Delete(ctx context.Context, path string, v interface{}) error {
+	return c.send(ctx, http.MethodDelete, path, nil, v)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body, v interface{}) error {
+	req, err := c.NewRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	return c.DoJSON(req, v)
+}
+
+// ResponseError reports a non-2xx response received by a Client. It
+// implements StatusError with a 502 Bad Gateway status, so that a
+// handler returning it unchanged reports the failure of the upstream
+// rather than passing its status, such as a 401 Unauthorized meant for
+// the service, on to the client. Handlers that expect an upstream
+// status, such as a 404 Not Found, check StatusCode.
+type ResponseError struct {
+	StatusCode int
+	Header     http.Header
+
+	// Body holds up to 4 KiB of the response body, for logging it
+	// deliberately. It is left out of the message of the error, which
+	// may reach error pages and logs.
+	Body []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("httpx: upstream responded %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Status returns 502 Bad Gateway.
+func (e *ResponseError) Status() int {
+	return http.StatusBadGateway
+}
+
+// BearerAuth is a ClientMiddleware that sets the Authorization header
+// of each request to a bearer token returned by token.
+func BearerAuth(token func(context.Context) (string, error)) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			tok, err := token(r.Context())
+			if err != nil {
+				return nil, err
+			}
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+tok)
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// SetRequestHeader is a ClientMiddleware that sets a header on each
+// request.
+func SetRequestHeader(key, value string) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.Header.Set(key, value)
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+// ForwardDeadline is a ClientMiddleware that forwards the deadline of
+// the request context, such as one declared by a route Budget, reduced
+// by margin. The margin leaves the calling handler time to handle the
+// outbound response before its own deadline passes.
+func ForwardDeadline(margin time.Duration) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			deadline, ok := r.Context().Deadline()
+			if !ok {
+				return next.RoundTrip(r)
+			}
+			ctx, cancel := context.WithDeadline(r.Context(), deadline.Add(-margin))
+			resp, err := next.RoundTrip(r.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+// cancelBody releases a request context once its response body is
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
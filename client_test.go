@@ -0,0 +1,35 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseErrorThroughHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "token sk_live_123 expired", http.StatusUnauthorized)
+	}))
+	defer upstream.Close()
+	c := NewClient(upstream.URL, nil)
+
+	m := NewMux()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		err := c.Get(r.Context(), "/rates", nil)
+		var rErr *ResponseError
+		if !errors.As(err, &rErr) || rErr.StatusCode != http.StatusUnauthorized || !strings.Contains(string(rErr.Body), "sk_live_123") {
+			t.Errorf("got error %#v, want a ResponseError with the upstream status and body", err)
+		}
+		return err
+	})
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status %d, want 502", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "sk_live_123") {
+		t.Errorf("upstream body leaked into the response: %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,124 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/eriklott/httpx/internal/record"
+)
+
+// Coalesce is a middleware that collapses concurrent identical GET
+// requests into a single execution of the next handler, whose response
+// is written to every waiting request. It protects expensive read
+// endpoints from a thundering herd, for example when a popular cache
+// entry expires.
+//
+// Requests are identical when key returns the same string for them. A
+// nil key uses the request URI and the Accept, Authorization and Cookie
+// headers, so that the requests of different users aren't coalesced; a
+// custom key must cover every input the response depends on, such as
+// the user of a personalized response. The response is buffered, so
+// Coalesce doesn't suit streaming handlers. An error returned by the
+// next handler is returned to every waiting request.
+//
+// The shared execution runs with the context of the first request
+// detached from its cancellation, keeping its deadline, so that the
+// client of the first request going away doesn't fail the others. When
+// the shared execution is canceled nonetheless, the waiting requests
+// run the next handler themselves, and when it panics, they fail with
+// a 500 Internal Server Error StatusError.
+func Coalesce(key func(r *http.Request) string) Middleware {
+	if key == nil {
+		key = func(r *http.Request) string {
+			return strings.Join([]string{r.URL.RequestURI(), r.Header.Get("Accept"),
+				r.Header.Get("Authorization"), strings.Join(r.Header.Values("Cookie"), "; ")}, "\x00")
+		}
+	}
+	var (
+		mu      sync.Mutex
+		flights = map[string]*flight{}
+	)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if r.Method != http.MethodGet {
+				return next.ServeHTTP(w, r)
+			}
+			k := key(r)
+
+			mu.Lock()
+			f, ok := flights[k]
+			if ok {
+				mu.Unlock()
+				select {
+				case <-f.done:
+				case <-r.Context().Done():
+					return r.Context().Err()
+				}
+				switch {
+				case f.panicked:
+					return Error(http.StatusInternalServerError, "coalesced request failed")
+				case errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded):
+					return next.ServeHTTP(w, r)
+				}
+				return f.replay(w)
+			}
+			f = &flight{done: make(chan struct{})}
+			flights[k] = f
+			mu.Unlock()
+
+			ctx := context.WithoutCancel(r.Context())
+			if deadline, ok := r.Context().Deadline(); ok {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, deadline)
+				defer cancel()
+			}
+			func() {
+				f.panicked = true
+				defer func() {
+					mu.Lock()
+					delete(flights, k)
+					mu.Unlock()
+					close(f.done)
+				}()
+				f.err = next.ServeHTTP(f, r.WithContext(ctx))
+				f.panicked = false
+				if f.err == nil {
+					// Record the status of an empty response, so that
+					// the replays only read the recording.
+					f.WriteHeader(http.StatusOK)
+				}
+			}()
+			return f.replay(w)
+		})
+	}
+}
+
+// flight is the execution of a handler shared by coalesced requests. It
+// buffers the response, to be replayed to each of them.
+type flight struct {
+	record.Recorder
+	done chan struct{}
+	err  error
+
+	// panicked is set when the handler panicked.
+	panicked bool
+}
+
+func (f *flight) replay(w http.ResponseWriter) error {
+	if f.Status == 0 && f.err != nil {
+		return f.err
+	}
+	h := w.Header()
+	for k, v := range f.Header() {
+		h[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(f.Status)
+	if _, err := w.Write(f.Body.Bytes()); err != nil {
+		return err
+	}
+	return f.err
+}
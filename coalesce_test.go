@@ -0,0 +1,121 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// coalesced starts a leader request for path, with ctx, then serves a
+// follower request for path while the leader is in flight.
+func coalesced(t *testing.T, m *Mux, ctx context.Context, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	started := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		close(started)
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+	}()
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestCoalesceLeaderGone(t *testing.T) {
+	m := NewMux()
+	m.Use(Coalesce(nil))
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+			return r.Context().Err()
+		}
+		_, err := w.Write([]byte("ok"))
+		return err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	rec := coalesced(t, m, ctx, "/")
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Errorf("follower got %d %q, want 200 \"ok\"", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCoalesceLeaderPanic(t *testing.T) {
+	m := NewMux()
+	m.Use(Coalesce(nil))
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(30 * time.Millisecond)
+		panic("boom")
+	})
+
+	rec := coalesced(t, m, context.Background(), "/")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("follower got %d, want 500", rec.Code)
+	}
+}
+
+// TestCoalesceEmptyResponse replays an empty response to concurrent
+// followers. Run with -race.
+func TestCoalesceEmptyResponse(t *testing.T) {
+	m := NewMux()
+	m.Use(Coalesce(nil))
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+				t.Errorf("got %d %q, want an empty 200", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestCoalesceCredentials checks that the concurrent requests of
+// different users aren't coalesced by the default key.
+func TestCoalesceCredentials(t *testing.T) {
+	m := NewMux()
+	m.Use(Coalesce(nil))
+	m.Get("/me", func(w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(30 * time.Millisecond)
+		_, err := w.Write([]byte(r.Header.Get("Authorization") + r.Header.Get("Cookie")))
+		return err
+	})
+
+	users := []struct{ header, value string }{
+		{"Authorization", "Bearer ada"},
+		{"Authorization", "Bearer bob"},
+		{"Cookie", "session=ada"},
+		{"Cookie", "session=bob"},
+	}
+	var wg sync.WaitGroup
+	for _, u := range users {
+		wg.Add(1)
+		go func(header, value string) {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodGet, "/me", nil)
+			r.Header.Set(header, value)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, r)
+			if rec.Body.String() != value {
+				t.Errorf("request with %s %q got the response %q", header, value, rec.Body.String())
+			}
+		}(u.header, u.value)
+	}
+	wg.Wait()
+}
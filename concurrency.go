@@ -0,0 +1,67 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MaxConcurrent is a middleware that limits the number of requests the
+// next handler serves at once to n, to protect expensive handlers such
+// as report generation:
+//
+//	mux.Get("/reports/{id}", report, httpx.WithMiddleware(
+//		httpx.NewChain(httpx.MaxConcurrent(4, 16, 10*time.Second))))
+//
+// Up to queue requests wait for a slot, for at most timeout. Requests
+// beyond the queue, and requests that time out waiting, are rejected
+// with a 503 Service Unavailable StatusError and a Retry-After header.
+// A zero timeout waits until the request is canceled.
+func MaxConcurrent(n, queue int, timeout time.Duration) Middleware {
+	if n < 1 {
+		panic("httpx: MaxConcurrent limit must be positive")
+	}
+	slots := make(chan struct{}, n)
+	var waiting atomic.Int64
+
+	retryAfter := "1"
+	if secs := int((timeout + time.Second - 1) / time.Second); secs > 1 {
+		retryAfter = strconv.Itoa(secs)
+	}
+	busy := func(w http.ResponseWriter) error {
+		w.Header().Set("Retry-After", retryAfter)
+		return Error(http.StatusServiceUnavailable, "too many concurrent requests")
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			select {
+			case slots <- struct{}{}:
+			default:
+				if waiting.Add(1) > int64(queue) {
+					waiting.Add(-1)
+					return busy(w)
+				}
+				var expired <-chan time.Time
+				if timeout > 0 {
+					t := time.NewTimer(timeout)
+					defer t.Stop()
+					expired = t.C
+				}
+				select {
+				case slots <- struct{}{}:
+					waiting.Add(-1)
+				case <-expired:
+					waiting.Add(-1)
+					return busy(w)
+				case <-r.Context().Done():
+					waiting.Add(-1)
+					return r.Context().Err()
+				}
+			}
+			defer func() { <-slots }()
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
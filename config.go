@@ -0,0 +1,221 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MuxConfig is a declarative description of the routes of a Mux, read
+// from a JSON or YAML file:
+//
+//	middlewares: [logger, {name: timeout, options: {duration: 5s}}]
+//	routes:
+//	  - path: /users/{id}
+//	    methods: [GET]
+//	    handler: users.get
+//	    name: user
+//	  - path: /assets
+//	    static: ./public
+//	  - path: /billing
+//	    proxy: http://billing.internal:8080
+//	    strip_prefix: true
+//	    middlewares: [auth]
+type MuxConfig struct {
+	// Middlewares are the middlewares of the Mux stack.
+	Middlewares []MiddlewareConfig `json:"middlewares" yaml:"middlewares"`
+
+	// Routes are the routes of the Mux.
+	Routes []RouteConfig `json:"routes" yaml:"routes"`
+}
+
+// RouteConfig is a route of a MuxConfig. Exactly one of Handler, Static
+// and Proxy must be set.
+type RouteConfig struct {
+	// Path is the route pattern.
+	Path string `json:"path" yaml:"path"`
+
+	// Methods restricts the route to the listed methods. By default,
+	// the route matches any method.
+	Methods []string `json:"methods" yaml:"methods"`
+
+	// Name is the name of the route, for building URLs.
+	Name string `json:"name" yaml:"name"`
+
+	// Middlewares are the route middlewares.
+	Middlewares []MiddlewareConfig `json:"middlewares" yaml:"middlewares"`
+
+	// Handler is the name of a handler registered in code.
+	Handler string `json:"handler" yaml:"handler"`
+
+	// Static is a directory whose files are served under Path.
+	Static string `json:"static" yaml:"static"`
+
+	// Proxy is the URL of an upstream server requests for Path and its
+	// subpaths are forwarded to.
+	Proxy string `json:"proxy" yaml:"proxy"`
+
+	// StripPrefix removes Path from the path of proxied requests.
+	StripPrefix bool `json:"strip_prefix" yaml:"strip_prefix"`
+}
+
+// MiddlewareConfig references a middleware by name, with the options
+// passed to its registered factory. In a config file, a middleware
+// without options can be written as its name alone.
+type MiddlewareConfig struct {
+	Name    string                 `json:"name" yaml:"name"`
+	Options map[string]interface{} `json:"options" yaml:"options"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (mc *MiddlewareConfig) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &mc.Name); err == nil {
+		return nil
+	}
+	type plain MiddlewareConfig
+	return json.Unmarshal(data, (*plain)(mc))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (mc *MiddlewareConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&mc.Name)
+	}
+	type plain MiddlewareConfig
+	return node.Decode((*plain)(mc))
+}
+
+// ReadConfig reads a MuxConfig from a file, as YAML when its extension
+// is .yaml or .yml, and as JSON otherwise.
+func ReadConfig(path string) (MuxConfig, error) {
+	var cfg MuxConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	default:
+		err = json.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("httpx: reading config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Build returns a new Mux with the routes of the config. The handlers
+// referenced by name are looked up in handlers, and the middlewares in
+// middlewares, then among the middlewares registered with
+// RegisterMiddleware. Routes registered in code can be added to the
+// returned Mux. Build reports an error for an unknown name, a route name
+// used by routes of different paths, or an invalid route.
+func (cfg MuxConfig) Build(handlers map[string]Handler, middlewares map[string]Middleware) (*Mux, error) {
+	chain := func(mcs []MiddlewareConfig) (Chain, error) {
+		c := NewChain()
+		for _, mc := range mcs {
+			if mw, ok := middlewares[mc.Name]; ok {
+				c = c.Extend(NewNamed(mc.Name, mw))
+				continue
+			}
+			named, err := LookupMiddleware(mc.Name, mc.Options)
+			if err != nil {
+				return c, err
+			}
+			c = c.Extend(named)
+		}
+		return c, nil
+	}
+
+	m := NewMux()
+	c, err := chain(cfg.Middlewares)
+	if err != nil {
+		return nil, err
+	}
+	m.UseChain(c)
+
+	names := map[string]string{}
+	for _, rc := range cfg.Routes {
+		if rc.Name != "" {
+			if path, ok := names[rc.Name]; ok && path != rc.Path {
+				return nil, fmt.Errorf("httpx: route %s: name %s is already used by route %s", rc.Path, rc.Name, path)
+			}
+			names[rc.Name] = rc.Path
+		}
+		if err := rc.register(m, handlers, chain); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (rc RouteConfig) register(m *Mux, handlers map[string]Handler, chain func([]MiddlewareConfig) (Chain, error)) (err error) {
+	// The Mux panics on invalid routes, which a config file must not
+	// cause.
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("httpx: route %s: %s", rc.Path, strings.TrimPrefix(fmt.Sprint(v), "httpx: "))
+		}
+	}()
+
+	c, err := chain(rc.Middlewares)
+	if err != nil {
+		return fmt.Errorf("httpx: route %s: %w", rc.Path, err)
+	}
+	opts := []RouteOption{WithMiddleware(c)}
+	if rc.Name != "" {
+		opts = append(opts, Name(rc.Name))
+	}
+
+	set := 0
+	for _, s := range []string{rc.Handler, rc.Static, rc.Proxy} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("httpx: route %s must have exactly one of handler, static and proxy", rc.Path)
+	}
+
+	switch {
+	case rc.Proxy != "":
+		target, err := url.Parse(rc.Proxy)
+		if err != nil {
+			return fmt.Errorf("httpx: route %s: %w", rc.Path, err)
+		}
+		var po ProxyOptions
+		if rc.StripPrefix {
+			po.StripPrefix = strings.TrimSuffix(rc.Path, "/")
+		}
+		m.Proxy(rc.Path, target, po, opts...)
+		return nil
+
+	case rc.Static != "":
+		prefix := strings.TrimSuffix(rc.Path, "/")
+		h := StripPrefix(prefix, FileServer(http.Dir(rc.Static)))
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			m.Method(method, prefix+"/*", h, opts...)
+		}
+		return nil
+	}
+
+	h, ok := handlers[rc.Handler]
+	if !ok {
+		return fmt.Errorf("httpx: route %s: unknown handler %q", rc.Path, rc.Handler)
+	}
+	if len(rc.Methods) == 0 {
+		m.Handle(rc.Path, h, opts...)
+		return nil
+	}
+	for _, method := range rc.Methods {
+		m.Method(strings.ToUpper(method), rc.Path, h, opts...)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package httpx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildProxyName(t *testing.T) {
+	cfg := MuxConfig{Routes: []RouteConfig{{Path: "/billing", Proxy: "http://billing.internal", Name: "billing"}}}
+	m, err := cfg.Build(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := m.URL("billing"); err != nil || u != "/billing" {
+		t.Errorf("URL(billing) = %q, %v, want /billing", u, err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		routes []RouteConfig
+		want   string
+	}{
+		{"name collision", []RouteConfig{
+			{Path: "/a", Proxy: "http://a.internal", Name: "n"},
+			{Path: "/b", Proxy: "http://b.internal", Name: "n"},
+		}, "name n is already used"},
+		{"invalid pattern", []RouteConfig{{Path: "/a/{id", Proxy: "http://a.internal"}}, "route /a/{id"},
+	} {
+		_, err := MuxConfig{Routes: tc.routes}.Build(nil, nil)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: error %v, want one containing %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Budget returns a RouteOption that declares a latency budget for a
+// route. The request context of the route handler carries a deadline
+// of the budget from the time the request is routed, or any earlier
+// deadline already set on the request.
+//
+// Handlers can read the deadline with Deadline, and a Client using the
+// ForwardDeadline middleware passes a reduced deadline on to outbound
+// requests made with the request context. An error returned by the
+// handler after the budget is exhausted is reported as a 504 Gateway
+// Timeout.
+func Budget(d time.Duration) RouteOption {
+	return WithMiddleware(NewNamed("budget", Timeout(d)))
+}
+
+// Timeout is a middleware that sets a deadline of d on the request
+// context. An error returned by the next handler after the deadline
+// has passed is reported as a 504 Gateway Timeout.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			err := next.ServeHTTP(w, r.WithContext(ctx))
+			if err == nil {
+				return nil
+			}
+			if _, ok := err.(StatusError); !ok && ctx.Err() == context.DeadlineExceeded {
+				return Error(http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout))
+			}
+			return err
+		})
+	}
+}
+
+// Deadline returns the time by which the request must be handled, as
+// declared by a route's Budget or an enclosing Timeout. The ok result
+// is false when the request has no deadline.
+func Deadline(r *http.Request) (deadline time.Time, ok bool) {
+	return r.Context().Deadline()
+}
+
+// Remaining returns the time left before the request deadline, and
+// false when the request has no deadline.
+func Remaining(r *http.Request) (time.Duration, bool) {
+	deadline, ok := Deadline(r)
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
@@ -0,0 +1,76 @@
+package httpx
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// DebugRoutes returns a handler that lists the routes of the Mux that
+// routed the request, with their methods, names, handlers, middlewares
+// and metadata, as an HTML table for browsers or as JSON. It exposes
+// the inner workings of a service, so mount it only in development
+// builds:
+//
+//	if dev {
+//		mux.Get("/debug/routes", httpx.DebugRoutes())
+//	}
+func DebugRoutes() Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		ri, ok := CurrentRoute(r)
+		if !ok || ri.table == nil {
+			return Error(http.StatusNotFound, "no routes")
+		}
+		ri.table.mu.Lock()
+		routes := make([]RouteInfo, len(ri.table.routes))
+		copy(routes, ri.table.routes)
+		ri.table.mu.Unlock()
+
+		if Negotiate(r, "application/json", "text/html") == "text/html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			return debugRoutesPage.Execute(w, routes)
+		}
+
+		type route struct {
+			Method      string                 `json:"method"`
+			Pattern     string                 `json:"pattern"`
+			Name        string                 `json:"name,omitempty"`
+			Handler     string                 `json:"handler"`
+			Middlewares []string               `json:"middlewares"`
+			Metadata    map[string]interface{} `json:"metadata,omitempty"`
+		}
+		list := make([]route, len(routes))
+		for i, ri := range routes {
+			list[i] = route{ri.Method, ri.Pattern, ri.Name, ri.Handler, ri.Middlewares, ri.Metadata}
+		}
+		return JSON(w, http.StatusOK, list)
+	})
+}
+
+var debugRoutesPage = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Routes</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 4px 12px; border-bottom: 1px solid #ddd; vertical-align: top; }
+code { font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>Routes</h1>
+<table>
+<tr><th>Method</th><th>Pattern</th><th>Name</th><th>Handler</th><th>Middlewares</th><th>Metadata</th></tr>
+{{range .}}<tr>
+<td>{{.Method}}</td>
+<td><code>{{.Pattern}}</code></td>
+<td>{{.Name}}</td>
+<td><code>{{.Handler}}</code></td>
+<td>{{range $i, $m := .Middlewares}}{{if $i}}, {{end}}{{$m}}{{end}}</td>
+<td>{{range $k, $v := .Metadata}}<code>{{$k}}</code>: {{printf "%v" $v}}<br>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,87 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// deprecationKey is the route metadata key declared by Deprecated.
+const deprecationKey = "deprecation"
+
+// Deprecation is the deprecation of a route declared with Deprecated,
+// reported as the "deprecation" metadata of the route by Mux.Routes.
+type Deprecation struct {
+	// Deprecated is the time the route was deprecated at, sent in the
+	// Deprecation header.
+	Deprecated time.Time
+
+	// Sunset is the time after which the route stops being served, or
+	// the zero time when it isn't planned.
+	Sunset time.Time
+
+	// Link is the URL of the documentation of the deprecation, such as
+	// a migration guide, or "".
+	Link string
+
+	calls   atomic.Int64
+	lastLog atomic.Int64
+}
+
+// Calls returns the number of requests made to the route since it was
+// registered, to tell when its clients have migrated.
+func (d *Deprecation) Calls() int64 {
+	return d.calls.Load()
+}
+
+// Deprecated returns a RouteOption that marks a route as deprecated
+// since the time deprecated, with its sunset time and a link to its
+// documentation, either of which may be left out:
+//
+//	v2 := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+//	mux.Get("/v1/users", usersV1, httpx.Deprecated(v2, v2.AddDate(0, 6, 0), "https://example.com/docs/v2-migration"))
+//
+// Responses of the route carry a Deprecation header (RFC 9745) dated
+// deprecated, a Sunset header (RFC 8594) and a Link header to the
+// documentation. Calls are counted, and logged as warnings with the
+// logger of the request, at most once a minute per route, along with
+// the number of calls. Once the sunset time has passed, requests to the
+// route fail with a 410 Gone StatusError.
+func Deprecated(deprecated, sunset time.Time, link string) RouteOption {
+	d := &Deprecation{Deprecated: deprecated, Sunset: sunset, Link: link}
+	deprecation := "@" + strconv.FormatInt(deprecated.Unix(), 10)
+	mw := func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			calls := d.calls.Add(1)
+			now := time.Now()
+			if last := d.lastLog.Load(); now.UnixNano()-last >= int64(time.Minute) && d.lastLog.CompareAndSwap(last, now.UnixNano()) {
+				args := []interface{}{"method", r.Method, "path", r.URL.Path, "calls", calls}
+				if ri, ok := CurrentRoute(r); ok {
+					args = append(args, "route", ri.Pattern)
+				}
+				if !sunset.IsZero() {
+					args = append(args, "sunset", sunset)
+				}
+				LoggerFrom(r).WarnContext(r.Context(), "deprecated route called", args...)
+			}
+
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			if link != "" {
+				h.Add("Link", "<"+link+`>; rel="deprecation"`)
+			}
+			if !sunset.IsZero() && now.After(sunset) {
+				return Error(http.StatusGone, "this endpoint was removed")
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+	return func(ro *routeOptions) {
+		Meta(deprecationKey, d)(ro)
+		WithMiddleware(NewNamed("deprecated", mw))(ro)
+	}
+}
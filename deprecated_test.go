@@ -0,0 +1,33 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestDeprecated checks the headers of a deprecated route, and that it
+// is gone after its sunset.
+func TestDeprecated(t *testing.T) {
+	since := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMux()
+	h := func(w http.ResponseWriter, r *http.Request) error { return nil }
+	m.Get("/v1", h, Deprecated(since, time.Now().Add(time.Hour), "https://example.com/v2"))
+	m.Get("/v0", h, Deprecated(since, time.Now().Add(-time.Hour), ""))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1", nil))
+	if got := rec.Header().Get("Deprecation"); got != "@1780272000" {
+		t.Errorf("Deprecation %q, want @1780272000", got)
+	}
+	if rec.Header().Get("Sunset") == "" || rec.Header().Get("Link") != `<https://example.com/v2>; rel="deprecation"` {
+		t.Errorf("headers %v", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v0", nil))
+	if rec.Code != http.StatusGone {
+		t.Errorf("status after sunset %d, want 410", rec.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// Detach returns a context that carries the values of ctx, such as the
+// request ID, logger, trace and authentication values of a request,
+// but is not canceled when ctx is and has no deadline. Work started by
+// a handler that must outlive the request uses a detached context.
+func Detach(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// Go runs fn in a new goroutine with a context detached from the
+// request, once the response to the request has been written, so the
+// work doesn't delay the response and goes on after it. Outside of a
+// request routed by a Mux, where AfterResponse is unavailable, fn
+// starts immediately. A panic in fn is recovered, and it and an error
+// returned by fn are logged with the logger of the request:
+//
+//	httpx.Go(r, func(ctx context.Context) error {
+//		return mailer.SendWelcome(ctx, user)
+//	})
+func Go(r *http.Request, fn func(ctx context.Context) error) {
+	logger := LoggerFrom(r)
+	run := func(ctx context.Context) {
+		defer func() {
+			if v := recover(); v != nil {
+				logger.ErrorContext(ctx, "background work panicked",
+					"panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+			}
+		}()
+		if err := fn(ctx); err != nil {
+			logger.ErrorContext(ctx, "background work failed", "error", err.Error())
+		}
+	}
+	if AfterResponse(r, func(ctx context.Context) { go run(ctx) }) {
+		return
+	}
+	go run(Detach(r.Context()))
+}
@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/eriklott/httpx/internal/record"
+)
+
+// Do routes a synthetic request with method, path and body through the
+// Mux, its middlewares and the route handler, in-process, and returns
+// the response once the handler has completed. It lets handlers compose
+// the responses of other routes, as batch endpoints do, without a
+// network hop:
+//
+//	resp, err := mux.Do(r.Context(), "GET", "/users/42", nil)
+//
+// The request carries ctx, so sub-requests made from a handler share
+// its deadline and values. Errors returned by the route handler are
+// written to the response as for any request; Do itself fails only for
+// a malformed method or path. A panic of the handler reaches the caller
+// of Do, which must recover it when it calls Do from a goroutine of its
+// own, as net/http only recovers panics of the connection goroutine.
+func (m *Mux) Do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	r.RequestURI = r.URL.RequestURI()
+	return m.DoRequest(r), nil
+}
+
+// DoRequest is like Do, for a request built by the caller, such as one
+// carrying headers.
+func (m *Mux) DoRequest(r *http.Request) *http.Response {
+	rec := &record.Recorder{}
+	m.ServeHTTP(rec, r)
+	if rec.Status == 0 {
+		rec.Status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(rec.Status) + " " + http.StatusText(rec.Status),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header(),
+		Body:          io.NopCloser(&rec.Body),
+		ContentLength: int64(rec.Body.Len()),
+		Request:       r,
+	}
+}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Attachment writes content as a download saved under filename. The
+// Content-Type is derived from the filename extension, or sniffed from
+// the content. A content that is an io.ReadSeeker is served with
+// ServeContent, so downloads can be resumed with range requests; any
+// other content is streamed. Errors reading content are returned.
+func Attachment(w http.ResponseWriter, r *http.Request, content io.Reader, filename string) error {
+	return serveDisposition(w, r, content, "attachment", filename)
+}
+
+// Inline is like Attachment, but asks the browser to display the
+// content rather than download it. The filename is used when the user
+// saves the content.
+func Inline(w http.ResponseWriter, r *http.Request, content io.Reader, filename string) error {
+	return serveDisposition(w, r, content, "inline", filename)
+}
+
+func serveDisposition(w http.ResponseWriter, r *http.Request, content io.Reader, disposition, filename string) error {
+	w.Header().Set("Content-Disposition", ContentDisposition(disposition, filename))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if ct := mime.TypeByExtension(filepath.Ext(filename)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	if rs, ok := content.(io.ReadSeeker); ok {
+		return ServeContent(w, r, filename, time.Time{}, rs)
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		var buf [512]byte
+		n, err := io.ReadFull(content, buf[:])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		w.Header().Set("Content-Type", http.DetectContentType(buf[:n]))
+		content = io.MultiReader(strings.NewReader(string(buf[:n])), content)
+	}
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err := io.Copy(w, content)
+	return err
+}
+
+// ContentDisposition returns a Content-Disposition header value of the
+// disposition type, "attachment" or "inline", for filename. Names that
+// aren't plain ASCII are encoded as an RFC 5987 filename* parameter,
+// with an ASCII approximation in the filename parameter for older
+// clients.
+func ContentDisposition(disposition, filename string) string {
+	filename = filepath.Base(filename)
+	ascii := make([]byte, 0, len(filename))
+	plain := true
+	for _, c := range []byte(filename) {
+		switch {
+		case c >= 0x80:
+			plain = false
+			if len(ascii) == 0 || ascii[len(ascii)-1] != '_' {
+				ascii = append(ascii, '_')
+			}
+		case c < 0x20 || c == 0x7f || c == '"' || c == '\\':
+			plain = false
+			ascii = append(ascii, '_')
+		default:
+			ascii = append(ascii, c)
+		}
+	}
+
+	v := disposition + `; filename="` + string(ascii) + `"`
+	if !plain {
+		v += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return v
+}
+
+// encodeExtValue percent-encodes s as an RFC 5987 ext-value, keeping
+// only attr-char bytes unencoded.
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
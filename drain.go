@@ -0,0 +1,90 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Drainer tracks in-flight requests so that a service can stop taking
+// new requests and wait for outstanding handlers to finish before it
+// exits. A Drainer must be created with NewDrainer.
+type Drainer struct {
+	mu       sync.Mutex
+	inflight int
+	draining bool
+	idle     chan struct{}
+}
+
+// NewDrainer returns a newly initialized Drainer.
+func NewDrainer() *Drainer {
+	return &Drainer{idle: make(chan struct{})}
+}
+
+// Middleware is a middleware that counts the requests it passes to the
+// next handler. Once Drain has been called, new requests are rejected
+// with a 503 Service Unavailable StatusError.
+func (d *Drainer) Middleware(next Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if !d.enter() {
+			w.Header().Set("Connection", "close")
+			return Error(http.StatusServiceUnavailable, "server is shutting down")
+		}
+		defer d.exit()
+		return next.ServeHTTP(w, r)
+	})
+}
+
+// InFlight returns the number of requests being handled.
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inflight
+}
+
+// Draining reports whether Drain has been called.
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Drain stops the Drainer from accepting new requests and blocks until
+// all in-flight requests have finished or ctx is done, in which case
+// the context's error is returned.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		if d.inflight == 0 {
+			close(d.idle)
+		}
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Drainer) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inflight++
+	return true
+}
+
+func (d *Drainer) exit() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inflight--
+	if d.draining && d.inflight == 0 {
+		close(d.idle)
+	}
+}
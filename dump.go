@@ -0,0 +1,232 @@
+package httpx
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// redacted replaces sensitive values in dumped requests and responses.
+const redacted = "[REDACTED]"
+
+// DumpOptions configures the Dump middleware.
+type DumpOptions struct {
+	// Sink receives a record of each request. It is called after the
+	// handler has returned, on the request goroutine.
+	Sink func(*DumpRecord)
+
+	// MaxBodySize caps the number of bytes of each body kept in a
+	// record. The default is 64KB.
+	MaxBodySize int
+
+	// ContentTypes lists the media types, or prefixes of media types
+	// such as "text/", whose bodies are recorded. The default records
+	// JSON, XML, form and text bodies.
+	ContentTypes []string
+
+	// RedactHeaders lists headers whose values are replaced in records.
+	// The default redacts Authorization, Proxy-Authorization, Cookie and
+	// Set-Cookie.
+	RedactHeaders []string
+
+	// RedactFields lists JSON object keys and form fields whose values
+	// are replaced in recorded bodies.
+	RedactFields []string
+}
+
+// DumpRecord is a record of a request and its response made by Dump.
+type DumpRecord struct {
+	Method            string
+	URL               string
+	RequestHeader     http.Header
+	RequestBody       []byte
+	RequestTruncated  bool
+	Status            int
+	ResponseHeader    http.Header
+	ResponseBody      []byte
+	ResponseTruncated bool
+	Duration          time.Duration
+	Err               error
+}
+
+// Dump is a middleware that records requests and responses, including
+// their bodies, for debugging. Bodies are copied as the handler reads
+// and writes them, so streaming handlers are not buffered or delayed;
+// only the bytes the handler actually reads are recorded.
+func Dump(opts DumpOptions) Middleware {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 64 << 10
+	}
+	if opts.ContentTypes == nil {
+		opts.ContentTypes = []string{
+			"application/json", "application/xml", "application/x-www-form-urlencoded",
+			"text/", "+json", "+xml",
+		}
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+	}
+	fieldsRE := redactFieldsRE(opts.RedactFields)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			start := time.Now()
+			rec := &DumpRecord{
+				Method:        r.Method,
+				URL:           r.URL.String(),
+				RequestHeader: redactHeader(r.Header, opts.RedactHeaders),
+			}
+
+			var reqBody *teeBody
+			if r.Body != nil && r.Body != http.NoBody && dumpable(r.Header.Get("Content-Type"), opts.ContentTypes) {
+				reqBody = &teeBody{ReadCloser: r.Body, buf: capBuffer{max: opts.MaxBodySize}}
+				r.Body = reqBody
+			}
+			dw := &dumpWriter{
+				responseWriter: wrapWriter(w),
+				buf:            capBuffer{max: opts.MaxBodySize},
+				types:          opts.ContentTypes,
+			}
+
+			err := next.ServeHTTP(dw, r)
+
+			if reqBody != nil {
+				rec.RequestBody = redactBody(reqBody.buf.b, r.Header.Get("Content-Type"), opts.RedactFields, fieldsRE)
+				rec.RequestTruncated = reqBody.buf.truncated
+			}
+			rec.Status = statusOf(r, dw.responseWriter, err)
+			rec.ResponseHeader = redactHeader(w.Header(), opts.RedactHeaders)
+			rec.ResponseBody = redactBody(dw.buf.b, w.Header().Get("Content-Type"), opts.RedactFields, fieldsRE)
+			rec.ResponseTruncated = dw.buf.truncated
+			rec.Duration = time.Since(start)
+			rec.Err = err
+			if opts.Sink != nil {
+				opts.Sink(rec)
+			}
+			return err
+		})
+	}
+}
+
+// capBuffer keeps the first max bytes written to it.
+type capBuffer struct {
+	b         []byte
+	max       int
+	truncated bool
+}
+
+func (c *capBuffer) Write(p []byte) {
+	if room := c.max - len(c.b); room < len(p) {
+		p = p[:room]
+		c.truncated = true
+	}
+	c.b = append(c.b, p...)
+}
+
+// teeBody records the bytes read from a request body.
+type teeBody struct {
+	io.ReadCloser
+	buf capBuffer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.Write(p[:n])
+	return n, err
+}
+
+// dumpWriter records the bytes written to a response body.
+type dumpWriter struct {
+	*responseWriter
+	buf     capBuffer
+	types   []string
+	checked bool
+	capture bool
+}
+
+func (w *dumpWriter) Write(b []byte) (int, error) {
+	if !w.checked {
+		w.checked = true
+		w.capture = dumpable(w.Header().Get("Content-Type"), w.types)
+	}
+	n, err := w.responseWriter.Write(b)
+	if w.capture {
+		w.buf.Write(b[:n])
+	}
+	return n, err
+}
+
+func dumpable(contentType string, types []string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		if mt == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t)) ||
+			(strings.HasPrefix(t, "+") && strings.HasSuffix(mt, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeader(h http.Header, keys []string) http.Header {
+	h = h.Clone()
+	for _, key := range keys {
+		if vs := h.Values(key); len(vs) > 0 {
+			h[http.CanonicalHeaderKey(key)] = []string{redacted}
+		}
+	}
+	return h
+}
+
+// redactFieldsRE returns a pattern matching string, number and literal
+// values of the JSON object keys in fields, or nil when fields is empty.
+// A pattern is used rather than decoding the body, so truncated bodies
+// are redacted as well.
+func redactFieldsRE(fields []string) *regexp.Regexp {
+	if len(fields) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = regexp.QuoteMeta(f)
+	}
+	return regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[-\w.+]+)`)
+}
+
+func redactBody(body []byte, contentType string, fields []string, re *regexp.Regexp) []byte {
+	if len(body) == 0 || len(fields) == 0 {
+		return body
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	if mt == "application/x-www-form-urlencoded" {
+		return redactForm(body, fields)
+	}
+	return re.ReplaceAll(body, []byte(`${1}"`+redacted+`"`))
+}
+
+// redactForm replaces the values of the form fields in fields. The
+// pairs of the form are redacted one by one rather than parsed with
+// url.ParseQuery, so that malformed and truncated forms, whose last
+// value may end partway through an escape, are redacted as well.
+func redactForm(body []byte, fields []string) []byte {
+	pairs := strings.FieldsFunc(string(body), func(c rune) bool { return c == '&' || c == ';' })
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		for _, f := range fields {
+			if strings.EqualFold(key, f) {
+				pairs[i] = pair[:strings.IndexByte(pair+"=", '=')] + "=" + url.QueryEscape(redacted)
+				break
+			}
+		}
+	}
+	return []byte(strings.Join(pairs, "&"))
+}
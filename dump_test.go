@@ -0,0 +1,30 @@
+package httpx
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRedactForm checks that form fields are redacted in malformed and
+// truncated forms too.
+func TestRedactForm(t *testing.T) {
+	fields := []string{"password"}
+	re := redactFieldsRE(fields)
+	tests := []string{
+		"user=ada&password=s3cret",
+		"user=ada;password=s3cret",
+		"bad=%zz&password=s3cret",
+		"user=ada&password=s3cr%2",
+		"pass%77ord=s3cret&user=ada",
+		"PASSWORD=s3cret",
+	}
+	for _, body := range tests {
+		got := string(redactBody([]byte(body), "application/x-www-form-urlencoded", fields, re))
+		if strings.Contains(got, "s3cr") || !strings.Contains(got, "REDACTED") {
+			t.Errorf("redacted %q to %q", body, got)
+		}
+	}
+	if got := string(redactBody([]byte("user=ada"), "application/x-www-form-urlencoded", fields, re)); got != "user=ada" {
+		t.Errorf("redacted a form without secrets to %q", got)
+	}
+}
@@ -0,0 +1,43 @@
+package httpx
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// DynamicMux is an http.Handler serving requests with a Mux that can be
+// replaced at runtime, for route tables built from configuration. A
+// request is served entirely by the Mux current when it arrived, so
+// swapping doesn't affect in-flight requests. A DynamicMux must be
+// created with NewDynamicMux.
+//
+//	dm := httpx.NewDynamicMux(build(cfg))
+//	srv := httpx.NewServer(":8080", dm)
+//	// on configuration change:
+//	dm.Swap(build(newCfg))
+type DynamicMux struct {
+	current atomic.Pointer[Mux]
+}
+
+// NewDynamicMux returns a DynamicMux serving requests with m.
+func NewDynamicMux(m *Mux) *DynamicMux {
+	d := &DynamicMux{}
+	d.current.Store(m)
+	return d
+}
+
+// Mux returns the Mux currently serving requests.
+func (d *DynamicMux) Mux() *Mux {
+	return d.current.Load()
+}
+
+// Swap replaces the Mux serving new requests with m, and returns the
+// previous one.
+func (d *DynamicMux) Swap(m *Mux) *Mux {
+	return d.current.Swap(m)
+}
+
+// ServeHTTP implements http.Handler.
+func (d *DynamicMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.current.Load().ServeHTTP(w, r)
+}
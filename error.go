@@ -1,6 +1,11 @@
 package httpx
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
 
 type statusError struct {
 	message string
@@ -31,3 +36,38 @@ func Error(status int, message string) error {
 func Errorf(status int, format string, v ...interface{}) error {
 	return Error(status, fmt.Sprintf(format, v...))
 }
+
+// A HeaderProvider is an error declaring headers for the response the
+// Mux writes for it, such as the Retry-After of Unavailable.
+type HeaderProvider interface {
+	Headers() http.Header
+}
+
+// A BodyWriter is an error writing the body of its own response, in
+// the error format of the protocol it belongs to, such as the errors of
+// GraphQL. WriteError lets it write the response in place of its
+// message.
+type BodyWriter interface {
+	WriteBody(w http.ResponseWriter, status int)
+}
+
+type headerError struct {
+	statusError
+	header http.Header
+}
+
+func (e *headerError) Headers() http.Header {
+	return e.header
+}
+
+// Unavailable returns a 503 Service Unavailable StatusError whose
+// response has a Retry-After header of after, rounded up to whole
+// seconds, asking clients to retry later. A zero after sets no
+// Retry-After.
+func Unavailable(after time.Duration, message string) error {
+	e := &headerError{statusError: statusError{message, http.StatusServiceUnavailable}}
+	if after > 0 {
+		e.header = http.Header{"Retry-After": {strconv.FormatInt(int64((after+time.Second-1)/time.Second), 10)}}
+	}
+	return e
+}
@@ -0,0 +1,75 @@
+package httpx
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// A CodedError is a StatusError carrying a stable application error
+// code, such as "user_not_found", for clients to branch on rather than
+// on messages, which change and may be translated. The Mux writes the
+// code with the message of a CodedError in a JSON body:
+//
+//	{"code": "user_not_found", "message": "no user with id 42"}
+type CodedError interface {
+	StatusError
+	Code() string
+}
+
+type codedError struct {
+	statusError
+	code string
+}
+
+func (e *codedError) Code() string {
+	return e.code
+}
+
+// Coded returns a CodedError with status, code and message. Codes
+// should be registered with RegisterErrorCode, so that they can be
+// documented.
+func Coded(status int, code, message string) error {
+	return &codedError{statusError{message, status}, code}
+}
+
+// ErrorCode documents an application error code.
+type ErrorCode struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+// errorCodes is the registry of documented error codes.
+var errorCodes = struct {
+	sync.RWMutex
+	byCode map[string]ErrorCode
+}{byCode: map[string]ErrorCode{
+	"invalid_fields": {Code: "invalid_fields", Status: http.StatusUnprocessableEntity, Description: "Fields of the request are invalid; see fields."},
+}}
+
+// RegisterErrorCode documents code, returned with status, replacing any
+// previous registration of the code. Registered codes are listed by
+// ErrorCodes, for API documentation such as the error schema added by
+// the httpx/openapi package:
+//
+//	func init() {
+//		httpx.RegisterErrorCode("user_not_found", http.StatusNotFound, "The user doesn't exist.")
+//	}
+func RegisterErrorCode(code string, status int, description string) {
+	errorCodes.Lock()
+	defer errorCodes.Unlock()
+	errorCodes.byCode[code] = ErrorCode{Code: code, Status: status, Description: description}
+}
+
+// ErrorCodes returns the registered error codes, sorted by code.
+func ErrorCodes() []ErrorCode {
+	errorCodes.RLock()
+	defer errorCodes.RUnlock()
+	codes := make([]ErrorCode, 0, len(errorCodes.byCode))
+	for _, c := range errorCodes.byCode {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
@@ -0,0 +1,99 @@
+package httpx
+
+import (
+	"net/http"
+	"sync"
+)
+
+// An ErrorFunc observes or transforms the error returned by a handler,
+// returning the error to pass on, which may be err itself, another
+// error, or nil to swallow it.
+//
+// Errors travel back up the middleware stack, so any middleware can
+// handle the errors of the handlers after it, as an ErrorFunc run by
+// TransformErrors does. A middleware earlier in the stack sees the
+// error as transformed by those after it. A handler that already wrote
+// its response can't have it replaced: the Mux logs the final error of
+// such a request instead of writing it.
+type ErrorFunc func(r *http.Request, err error) error
+
+// TransformErrors is a middleware that passes the errors returned by
+// the next handler through fns, in order, for example to turn domain
+// errors into StatusErrors:
+//
+//	mux.Use(httpx.TransformErrors(func(r *http.Request, err error) error {
+//		if errors.Is(err, store.ErrConflict) {
+//			return httpx.Coded(http.StatusConflict, "conflict", err.Error())
+//		}
+//		return err
+//	}))
+func TransformErrors(fns ...ErrorFunc) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			err := next.ServeHTTP(w, r)
+			for _, fn := range fns {
+				if err == nil {
+					break
+				}
+				err = fn(r, err)
+			}
+			return err
+		})
+	}
+}
+
+// ObserveErrors is a middleware that calls fn with the errors returned
+// by the next handler, such as to count them, and passes them on
+// unchanged.
+func ObserveErrors(fn func(r *http.Request, err error)) Middleware {
+	return TransformErrors(func(r *http.Request, err error) error {
+		fn(r, err)
+		return err
+	})
+}
+
+// errorMappers is the registry of the functions registered with
+// MapError.
+var errorMappers struct {
+	sync.RWMutex
+	fns []func(err error) (StatusError, bool)
+}
+
+// MapError registers fn to translate the errors returned by handlers
+// that are not StatusErrors, such as domain errors, before the Mux
+// reports and writes them. The functions are consulted in the order
+// they were registered, and the first to return true wins; errors that
+// none maps are sent as 500 Internal Server Error:
+//
+//	httpx.MapError(func(err error) (httpx.StatusError, bool) {
+//		switch {
+//		case errors.Is(err, sql.ErrNoRows):
+//			return httpx.Error(http.StatusNotFound, "not found").(httpx.StatusError), true
+//		case errors.Is(err, context.DeadlineExceeded):
+//			return httpx.Error(http.StatusGatewayTimeout, "timeout").(httpx.StatusError), true
+//		}
+//		return nil, false
+//	})
+//
+// MapError is usually called from init functions.
+func MapError(fn func(err error) (StatusError, bool)) {
+	errorMappers.Lock()
+	defer errorMappers.Unlock()
+	errorMappers.fns = append(errorMappers.fns, fn)
+}
+
+// mapError returns err translated by the first matching function
+// registered with MapError, or err itself.
+func mapError(err error) error {
+	if _, ok := err.(StatusError); ok {
+		return err
+	}
+	errorMappers.RLock()
+	defer errorMappers.RUnlock()
+	for _, fn := range errorMappers.fns {
+		if sErr, ok := fn(err); ok {
+			return sErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+)
+
+// ErrorPage is the data of the templates of ErrorPages.
+type ErrorPage struct {
+	Status     int
+	StatusText string
+
+	// Message is the message of the error. It is the status text for
+	// server errors that are not StatusErrors, whose messages may
+	// reveal internal details.
+	Message string
+
+	Err     error
+	Request *http.Request
+}
+
+// ErrorPages returns an ErrorWriter rendering the HTML templates of
+// fsys, for the routes that serve pages rather than an API. An error is
+// rendered with the template named after its status, such as 404.html,
+// or else after its status class, such as 4xx.html, or else with
+// error.html, executed with an ErrorPage. Errors without a template,
+// and errors whose template fails, are written by WriteError.
+//
+//	//go:embed errors
+//	var errorTemplates embed.FS
+//
+//	pages, err := httpx.ErrorPages(errorTemplates)
+//	mux.OnError(pages)
+//	mux.Route("/api", func(api *httpx.Mux) {
+//		api.OnError(httpx.WriteError)
+//	})
+//
+// The templates are parsed once, by ErrorPages, and can share
+// definitions, such as a layout.
+func ErrorPages(fsys fs.FS) (ErrorWriter, error) {
+	tmpl, err := template.ParseFS(fsys, "*.html")
+	if err != nil {
+		return nil, fmt.Errorf("httpx: error pages: %w", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		var page *template.Template
+		for _, name := range []string{strconv.Itoa(status) + ".html", strconv.Itoa(status/100) + "xx.html", "error.html"} {
+			if page = tmpl.Lookup(name); page != nil {
+				break
+			}
+		}
+		if page == nil {
+			WriteError(w, r, status, err)
+			return
+		}
+
+		data := ErrorPage{
+			Status:     status,
+			StatusText: http.StatusText(status),
+			Message:    err.Error(),
+			Err:        err,
+			Request:    r,
+		}
+		if _, ok := err.(StatusError); !ok && status >= 500 {
+			data.Message = data.StatusText
+		}
+		var buf bytes.Buffer
+		if terr := page.Execute(&buf, data); terr != nil {
+			LoggerFrom(r).ErrorContext(r.Context(), "error page template failed",
+				"template", page.Name(), "error", terr.Error())
+			WriteError(w, r, status, err)
+			return
+		}
+
+		h := w.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		buf.WriteTo(w)
+	}, nil
+}
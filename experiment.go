@@ -0,0 +1,157 @@
+package httpx
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Variant is a handler variant of an experiment.
+type Variant struct {
+	// Name identifies the variant, such as "control" or "new-layout".
+	Name string
+
+	// Weight is the share of the traffic of the experiment sent to the
+	// variant, relative to the weights of the other variants, such as
+	// a percentage.
+	Weight int
+
+	Handler Handler
+}
+
+// ExperimentOptions configures an Experiment.
+type ExperimentOptions struct {
+	// Cookie is the name of the cookie keeping the variant assigned to
+	// a client. The default is "exp_" followed by the name of the
+	// experiment. Clients that don't keep cookies are assigned by
+	// Bucket.
+	Cookie string
+
+	// MaxAge is the lifetime of the cookie. The default is 30 days.
+	MaxAge time.Duration
+
+	// Header, when set, is a request header naming the variant to use,
+	// such as X-Variant, for tests and previews to pick a variant.
+	Header string
+
+	// Bucket, when set, returns the key a client is assigned by, such
+	// as the ID of its Principal or tenant, so that the client gets the
+	// same variant across devices. Clients for which it returns "", and
+	// all clients when it is nil, are assigned at random.
+	Bucket func(r *http.Request) string
+}
+
+type experimentsKey struct{}
+
+// Experiments returns the variants assigned to the request by the
+// experiments it went through, by experiment name, for logs and
+// metrics.
+func Experiments(r *http.Request) map[string]string {
+	assigned, _ := r.Context().Value(experimentsKey{}).(map[string]string)
+	return assigned
+}
+
+// Experiment returns a handler splitting the traffic of the experiment
+// name between variants, in proportion of their weights:
+//
+//	mux.Get("/", httpx.Experiment("home", []httpx.Variant{
+//		{Name: "control", Weight: 90, Handler: home},
+//		{Name: "hero", Weight: 10, Handler: homeWithHero},
+//	}, httpx.ExperimentOptions{}).ServeHTTP)
+//
+// A client is assigned the variant named by opts.Header, or else the
+// variant of its cookie, or else a variant drawn by its Bucket key, or
+// at random. A drawn variant is kept in a cookie, so that the client
+// sticks to it. The variant is recorded on the request, where
+// Experiments returns it, and on its logger, as the attribute
+// "experiment." followed by name.
+//
+// Experiment panics when given no variant with a positive weight.
+func Experiment(name string, variants []Variant, opts ExperimentOptions) Handler {
+	total := 0
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		panic("httpx: experiment " + name + " has no variant with a positive weight")
+	}
+	if opts.Cookie == "" {
+		opts.Cookie = "exp_" + name
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 30 * 24 * time.Hour
+	}
+	byName := map[string]*Variant{}
+	for i := range variants {
+		byName[variants[i].Name] = &variants[i]
+	}
+
+	// pick returns the variant at point n of the cumulated weights.
+	pick := func(n int) *Variant {
+		for i := range variants {
+			if variants[i].Weight <= 0 {
+				continue
+			}
+			if n < variants[i].Weight {
+				return &variants[i]
+			}
+			n -= variants[i].Weight
+		}
+		return &variants[len(variants)-1]
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		var v *Variant
+		if opts.Header != "" {
+			v = byName[r.Header.Get(opts.Header)]
+		}
+		sticky := v != nil
+		if v == nil {
+			if c, err := r.Cookie(opts.Cookie); err == nil {
+				if v = byName[c.Value]; v != nil && v.Weight > 0 {
+					sticky = true
+				} else {
+					v = nil
+				}
+			}
+		}
+		if v == nil {
+			key := ""
+			if opts.Bucket != nil {
+				key = opts.Bucket(r)
+			}
+			if key != "" {
+				h := fnv.New32a()
+				h.Write([]byte(name + "\x00" + key))
+				v = pick(int(h.Sum32() % uint32(total)))
+			} else {
+				v = pick(rand.Intn(total))
+			}
+		}
+		if !sticky {
+			http.SetCookie(w, &http.Cookie{
+				Name:     opts.Cookie,
+				Value:    v.Name,
+				Path:     "/",
+				MaxAge:   int(opts.MaxAge / time.Second),
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		assigned := map[string]string{name: v.Name}
+		for exp, variant := range Experiments(r) {
+			if _, ok := assigned[exp]; !ok {
+				assigned[exp] = variant
+			}
+		}
+		ctx := context.WithValue(r.Context(), experimentsKey{}, assigned)
+		ctx = context.WithValue(ctx, loggerKey{}, LoggerFrom(r).With("experiment."+name, v.Name))
+		return v.Handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
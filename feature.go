@@ -0,0 +1,186 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlagSubject is who a feature flag is evaluated for.
+type FlagSubject struct {
+	// Tenant is the ID of the tenant of the request, or "".
+	Tenant string
+
+	// User is the ID of the Principal of the request, or "".
+	User string
+}
+
+// flagSubject returns the FlagSubject of r.
+func flagSubject(r *http.Request) FlagSubject {
+	var s FlagSubject
+	if t, ok := Tenant(r); ok {
+		s.Tenant = t.ID
+	}
+	if p, ok := PrincipalFrom(r); ok {
+		s.User = p.ID
+	}
+	return s
+}
+
+// A FlagProvider evaluates feature flags. Implementations adapt the
+// source of the flags of the service's choice, such as the environment,
+// a file, or a remote flag service.
+type FlagProvider interface {
+	// Enabled reports whether flag is on for s. Unknown flags are off.
+	Enabled(ctx context.Context, flag string, s FlagSubject) (bool, error)
+}
+
+// FlagProviderFunc adapts a function to a FlagProvider, such as one
+// calling the SDK of a remote flag service.
+type FlagProviderFunc func(ctx context.Context, flag string, s FlagSubject) (bool, error)
+
+// Enabled calls fn.
+func (fn FlagProviderFunc) Enabled(ctx context.Context, flag string, s FlagSubject) (bool, error) {
+	return fn(ctx, flag, s)
+}
+
+// FlagRule is the state of a feature flag of Flags.
+type FlagRule struct {
+	// Enabled turns the flag on for everyone.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Tenants and Users list the tenants and users the flag is on for
+	// when it isn't enabled for everyone.
+	Tenants []string `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+	Users   []string `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+// on reports whether the rule turns its flag on for s.
+func (rule FlagRule) on(s FlagSubject) bool {
+	return rule.Enabled ||
+		(s.Tenant != "" && contains(rule.Tenants, s.Tenant)) ||
+		(s.User != "" && contains(rule.Users, s.User))
+}
+
+// Flags is a FlagProvider serving a fixed set of flags, by name.
+type Flags map[string]FlagRule
+
+// Enabled reports whether the rule of flag turns it on for s.
+func (f Flags) Enabled(ctx context.Context, flag string, s FlagSubject) (bool, error) {
+	return f[flag].on(s), nil
+}
+
+// ReadFlags reads Flags from a file, as YAML when its extension is
+// .yaml or .yml, and as JSON otherwise:
+//
+//	{"new-checkout": {"tenants": ["acme"]}, "dark-mode": {"enabled": true}}
+func ReadFlags(path string) (Flags, error) {
+	var flags Flags
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &flags)
+	default:
+		err = json.Unmarshal(data, &flags)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("httpx: reading flags %s: %w", path, err)
+	}
+	return flags, nil
+}
+
+// EnvFlags returns a FlagProvider reading flags from environment
+// variables named after them with prefix, uppercased, with dashes and
+// dots replaced by underscores, such as FEATURE_NEW_CHECKOUT for the
+// flag new-checkout and the prefix FEATURE_. A variable holding a
+// boolean, such as true or 0, turns its flag on or off for everyone,
+// and any other value is a comma separated list of the tenants and
+// users it is on for. The environment is read on each evaluation.
+func EnvFlags(prefix string) FlagProvider {
+	name := strings.NewReplacer("-", "_", ".", "_")
+	return FlagProviderFunc(func(ctx context.Context, flag string, s FlagSubject) (bool, error) {
+		v := strings.TrimSpace(os.Getenv(prefix + strings.ToUpper(name.Replace(flag))))
+		if v == "" {
+			return false, nil
+		}
+		if on, err := strconv.ParseBool(v); err == nil {
+			return on, nil
+		}
+		ids := strings.Split(v, ",")
+		for i := range ids {
+			ids[i] = strings.TrimSpace(ids[i])
+		}
+		return FlagRule{Tenants: ids, Users: ids}.on(s), nil
+	})
+}
+
+const featureKey = "feature.flag"
+
+// FeatureGate is a middleware that rejects requests for which flag is
+// off, for the tenant and Principal of the request, with a 404 Not
+// Found StatusError, so that unreleased routes stay hidden:
+//
+//	mux.With(httpx.FeatureGate("new-checkout", flags)).Post("/checkout/v2", checkout)
+//
+// A provider may fail the evaluation with a StatusError of its own,
+// such as a 403 Forbidden for a feature outside the plan of the
+// tenant, which is returned as is. FeatureGate runs after ResolveTenant
+// and the auth middlewares, for the tenant and user to be known.
+func FeatureGate(flag string, provider FlagProvider) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if err := checkFeature(r, flag, provider); err != nil {
+				return err
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Feature returns a RouteOption that declares the feature flag gating
+// a route, checked by the FeatureGates middleware.
+func Feature(flag string) RouteOption {
+	return Meta(featureKey, flag)
+}
+
+// FeatureGates is a middleware that gates the routes declared with
+// Feature behind their flag, as FeatureGate does:
+//
+//	mux.Use(httpx.FeatureGates(flags))
+//	mux.Post("/checkout/v2", checkout, httpx.Feature("new-checkout"))
+func FeatureGates(provider FlagProvider) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if ri, ok := CurrentRoute(r); ok {
+				if flag, ok := ri.Metadata[featureKey].(string); ok {
+					if err := checkFeature(r, flag, provider); err != nil {
+						return err
+					}
+				}
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkFeature returns a StatusError when flag is off for r.
+func checkFeature(r *http.Request, flag string, provider FlagProvider) error {
+	on, err := provider.Enabled(r.Context(), flag, flagSubject(r))
+	if err != nil {
+		return err
+	}
+	if !on {
+		return Error(http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	}
+	return nil
+}
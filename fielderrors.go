@@ -0,0 +1,82 @@
+package httpx
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// FieldError is the validation failure of a request field.
+type FieldError struct {
+	// Field is the name of the field, as in the request, such as
+	// "address.city" or "items[0].name".
+	Field string `json:"field"`
+
+	// Message describes the failure.
+	Message string `json:"message"`
+}
+
+// FieldErrors aggregates the validation failures of the fields of a
+// request. It is a CodedError with a 422 Unprocessable Entity status
+// and the code "invalid_fields", written by the Mux as a JSON body
+// listing the failures, for clients to display next to form inputs:
+//
+//	{"code": "invalid_fields", "message": "...", "fields": [{"field": "email", "message": "is required"}]}
+//
+// Bind and BindQuery return FieldErrors for values they can't decode,
+// and Bind returns the error of a Validator, which usually builds
+// FieldErrors:
+//
+//	func (u *User) Validate() error {
+//		var errs httpx.FieldErrors
+//		if u.Email == "" {
+//			errs.Add("email", "is required")
+//		}
+//		return errs.Err()
+//	}
+type FieldErrors []FieldError
+
+// Add appends a failure of field.
+func (e *FieldErrors) Add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// Err returns e as an error, or nil when e is empty.
+func (e FieldErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e FieldErrors) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid fields: ")
+	for i, fe := range e {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(fe.Field + ": " + fe.Message)
+	}
+	return b.String()
+}
+
+// Status returns 422 Unprocessable Entity.
+func (e FieldErrors) Status() int {
+	return http.StatusUnprocessableEntity
+}
+
+// Code returns "invalid_fields".
+func (e FieldErrors) Code() string {
+	return "invalid_fields"
+}
+
+// sort orders the failures by field.
+func (e FieldErrors) sort() {
+	sort.SliceStable(e, func(i, j int) bool { return e[i].Field < e[j].Field })
+}
+
+// A Validator validates a value decoded by Bind.
+type Validator interface {
+	Validate() error
+}
@@ -0,0 +1,131 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/eriklott/httpx/internal/signed"
+)
+
+// flashCookie is the name of the cookie holding flash messages.
+const flashCookie = "_flash"
+
+// FlashMessage is a one-time notice shown to the user on the next page
+// they view.
+type FlashMessage struct {
+	Level   string `json:"l"`
+	Message string `json:"m"`
+}
+
+type flashKey struct{}
+
+// flashState holds the flash messages of a request.
+type flashState struct {
+	mu       sync.Mutex
+	incoming []FlashMessage
+	consumed bool
+	pending  []FlashMessage
+	saved    bool
+}
+
+// UseFlash is a middleware that carries flash messages between requests
+// in a cookie signed with key, so that Flash and Flashes can be used
+// by the handlers it wraps. Messages set with Flash are saved when the
+// response headers are written, which makes them available to the
+// request that follows a redirect.
+func UseFlash(key []byte) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			st := &flashState{}
+			if c, err := r.Cookie(flashCookie); err == nil {
+				if !signed.Decode(key, c.Value, &st.incoming) {
+					st.incoming = nil
+				}
+			}
+			r = r.WithContext(context.WithValue(r.Context(), flashKey{}, st))
+
+			fw := &flashWriter{ResponseWriter: w, save: func() { st.save(w.Header(), key) }}
+			err := next.ServeHTTP(fw, r)
+			fw.saveOnce()
+			return err
+		})
+	}
+}
+
+// Flash adds a message for the next page the user views. Flash does
+// nothing when the request is not handled within UseFlash.
+func Flash(r *http.Request, level, msg string) {
+	st, ok := r.Context().Value(flashKey{}).(*flashState)
+	if !ok {
+		return
+	}
+	st.mu.Lock()
+	st.pending = append(st.pending, FlashMessage{Level: level, Message: msg})
+	st.mu.Unlock()
+}
+
+// Flashes returns the flash messages set by the previous request, and
+// clears them so they are shown only once.
+func Flashes(r *http.Request) []FlashMessage {
+	st, ok := r.Context().Value(flashKey{}).(*flashState)
+	if !ok {
+		return nil
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.consumed = true
+	return st.incoming
+}
+
+// save sets the flash cookie on h. Messages not read with Flashes are
+// kept along with the new ones.
+func (st *flashState) save(h http.Header, key []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.saved {
+		return
+	}
+	st.saved = true
+
+	msgs := st.pending
+	if !st.consumed {
+		msgs = append(append([]FlashMessage(nil), st.incoming...), st.pending...)
+	}
+	c := &http.Cookie{Name: flashCookie, Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode}
+	switch {
+	case len(msgs) > 0:
+		c.Value = signed.Encode(key, msgs)
+	case st.consumed && len(st.incoming) > 0:
+		c.MaxAge = -1
+	default:
+		return
+	}
+	h.Add("Set-Cookie", c.String())
+}
+
+// flashWriter saves the flash messages before the response headers are
+// written.
+type flashWriter struct {
+	http.ResponseWriter
+	save func()
+	once sync.Once
+}
+
+func (w *flashWriter) saveOnce() {
+	w.once.Do(w.save)
+}
+
+func (w *flashWriter) WriteHeader(status int) {
+	w.saveOnce()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *flashWriter) Write(b []byte) (int, error) {
+	w.saveOnce()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *flashWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,50 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestFlash carries a flash message across a redirect, and checks that
+// a cookie signed with another key is ignored.
+func TestFlash(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	m := NewMux()
+	m.Use(UseFlash(key))
+	m.Post("/save", func(w http.ResponseWriter, r *http.Request) error {
+		Flash(r, "info", "saved")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return nil
+	})
+	var got []FlashMessage
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		got = Flashes(r)
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/save", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	m.ServeHTTP(httptest.NewRecorder(), r)
+	if len(got) != 1 || got[0] != (FlashMessage{Level: "info", Message: "saved"}) {
+		t.Errorf("got flashes %v", got)
+	}
+
+	forged := NewMux()
+	forged.Use(UseFlash([]byte("another key, also 32 bytes long!")))
+	forged.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		got = Flashes(r)
+		return nil
+	})
+	forged.ServeHTTP(httptest.NewRecorder(), r)
+	if len(got) != 0 {
+		t.Errorf("got flashes %v from a cookie signed with another key", got)
+	}
+}
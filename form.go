@@ -0,0 +1,328 @@
+package httpx
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFormIndex caps slice indexes in form field names, so that a
+// request can't make the decoder allocate a huge slice.
+const maxFormIndex = 1000
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// DecodeForm decodes form values into v, which must be a pointer to a
+// struct. Fields are matched by their form tag, falling back to their
+// json tag and then their name. A tag of "-" skips a field.
+//
+// Field names address nested values: "address.city" sets a field of a
+// nested struct, "tags" repeated or "tags[0]" sets slice elements,
+// "items[1].name" sets a field of a slice element, and "meta[color]"
+// sets a map entry. Values are parsed into strings, booleans, numbers,
+// time.Time (RFC 3339, or HTML date and datetime-local inputs), and
+// any type implementing encoding.TextUnmarshaler. Form values that
+// match no field are ignored. The values that can't be decoded are
+// reported together as FieldErrors.
+func DecodeForm(values url.Values, v interface{}) error {
+	return decodeForm(values, nil, v)
+}
+
+// decodeForm is DecodeForm with the files of a multipart form, which
+// are decoded into *multipart.FileHeader and []*multipart.FileHeader
+// fields.
+func decodeForm(values url.Values, files map[string][]*multipart.FileHeader, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("httpx: DecodeForm requires a non-nil pointer to a struct")
+	}
+
+	var errs FieldErrors
+	for key, vals := range values {
+		if err := decodeField(rv.Elem(), key, vals, nil); err != nil {
+			errs.Add(key, fieldMessage(err))
+		}
+	}
+	for key, fhs := range files {
+		if err := decodeField(rv.Elem(), key, nil, fhs); err != nil {
+			errs.Add(key, fieldMessage(err))
+		}
+	}
+	errs.sort()
+	return errs.Err()
+}
+
+// fieldMessage returns the FieldError message of a decoding error.
+func fieldMessage(err error) string {
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		return err.Error()
+	}
+	switch {
+	case numErr.Err == strconv.ErrRange:
+		return "is out of range"
+	case numErr.Func == "ParseBool":
+		return "must be a boolean"
+	}
+	return "must be a number"
+}
+
+func decodeField(root reflect.Value, key string, vals []string, files []*multipart.FileHeader) error {
+	path, err := parseFormKey(key)
+	if err != nil {
+		return err
+	}
+	return setPath(root, path, vals, files)
+}
+
+// parseFormKey splits a form field name such as "items[0].name" into
+// its path segments.
+func parseFormKey(key string) ([]string, error) {
+	var path []string
+	for key != "" {
+		switch key[0] {
+		case '.':
+			key = key[1:]
+		case '[':
+			end := strings.IndexByte(key, ']')
+			if end < 0 {
+				return nil, errors.New("unterminated [")
+			}
+			path = append(path, key[1:end])
+			key = key[end+1:]
+		default:
+			end := strings.IndexAny(key, ".[")
+			if end < 0 {
+				end = len(key)
+			}
+			path = append(path, key[:end])
+			key = key[end:]
+		}
+	}
+	return path, nil
+}
+
+func setPath(v reflect.Value, path []string, vals []string, files []*multipart.FileHeader) error {
+	for v.Kind() == reflect.Ptr && v.Type() != fileHeaderType {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if len(path) == 0 {
+		if files != nil {
+			return setFiles(v, files)
+		}
+		return setLeaf(v, vals)
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct && !isLeafType(v):
+		idx, ok := formFields(v.Type())[path[0]]
+		if !ok {
+			return nil
+		}
+		for i, x := range idx {
+			if i > 0 && v.Kind() == reflect.Ptr {
+				// Embedded *Struct fields are allocated on the way down,
+				// unless unexported.
+				if v.IsNil() {
+					if !v.CanSet() {
+						return fmt.Errorf("cannot set field of nil embedded %s", v.Type())
+					}
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+			v = v.Field(x)
+		}
+		return setPath(v, path[1:], vals, files)
+
+	case v.Kind() == reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		if err := setLeaf(key, []string{path[0]}); err != nil {
+			return err
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if cur := v.MapIndex(key); cur.IsValid() {
+			elem.Set(cur)
+		}
+		if err := setPath(elem, path[1:], vals, files); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+
+	case v.Kind() == reflect.Slice:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= maxFormIndex {
+			return fmt.Errorf("invalid index %q", path[0])
+		}
+		if i >= v.Len() {
+			grown := reflect.MakeSlice(v.Type(), i+1, i+1)
+			reflect.Copy(grown, v)
+			v.Set(grown)
+		}
+		return setPath(v.Index(i), path[1:], vals, files)
+	}
+	return nil
+}
+
+// isLeafType reports whether a struct value is decoded from a single
+// form value rather than from nested fields.
+func isLeafType(v reflect.Value) bool {
+	return v.Type() == timeType || reflect.PointerTo(v.Type()).Implements(textUnmarshalerType)
+}
+
+func setFiles(v reflect.Value, files []*multipart.FileHeader) error {
+	switch {
+	case v.Type() == fileHeaderType:
+		v.Set(reflect.ValueOf(files[0]))
+	case v.Kind() == reflect.Slice && v.Type().Elem() == fileHeaderType:
+		v.Set(reflect.ValueOf(files))
+	default:
+		return errors.New("file upload into a non-file field")
+	}
+	return nil
+}
+
+func setLeaf(v reflect.Value, vals []string) error {
+	if len(vals) == 0 {
+		return nil
+	}
+	if v.Type() == timeType {
+		t, err := parseFormTime(vals[0])
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(vals[0]))
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(vals[0]))
+			return nil
+		}
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setLeaf(s.Index(i), []string{val}); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setLeaf(v.Elem(), vals)
+	case reflect.String:
+		v.SetString(vals[0])
+	case reflect.Bool:
+		if vals[0] == "" || vals[0] == "on" {
+			v.SetBool(vals[0] == "on")
+			return nil
+		}
+		b, err := strconv.ParseBool(vals[0])
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if vals[0] == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(vals[0], 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if vals[0] == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(vals[0], 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if vals[0] == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(vals[0], v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(vals[0]))
+		}
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// parseFormTime parses a time in RFC 3339 format, or in the formats of
+// HTML date and datetime-local inputs.
+func parseFormTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// fieldCache maps struct types to the index of their fields by form
+// name.
+var fieldCache sync.Map
+
+func formFields(t reflect.Type) map[string][]int {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(map[string][]int)
+	}
+	fields := map[string][]int{}
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		name := sf.Name
+		tag, ok := sf.Tag.Lookup("form")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if n, _, _ := strings.Cut(tag, ","); n != "" {
+			name = n
+		}
+		if name == "-" {
+			continue
+		}
+		fields[name] = sf.Index
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,39 @@
+package httpx
+
+import (
+	"net/url"
+	"testing"
+)
+
+type formBase struct {
+	ID   int    `form:"id"`
+	Name string `form:"name"`
+}
+
+type formBaseRef struct {
+	*formBase
+}
+
+// TestDecodeFormEmbeddedPointer checks that the fields of embedded
+// *Struct fields are decoded, allocating the embedded struct.
+func TestDecodeFormEmbeddedPointer(t *testing.T) {
+	type Base struct {
+		ID   int    `form:"id"`
+		Name string `form:"name"`
+	}
+	var v struct {
+		*Base
+		Age int `form:"age"`
+	}
+	if err := DecodeForm(url.Values{"id": {"7"}, "name": {"ada"}, "age": {"36"}}, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Base == nil || v.ID != 7 || v.Name != "ada" || v.Age != 36 {
+		t.Errorf("decoded %+v, base %+v", v, v.Base)
+	}
+
+	var u formBaseRef
+	if err := DecodeForm(url.Values{"id": {"7"}}, &u); err == nil {
+		t.Error("decoding into a nil unexported embedded pointer succeeded")
+	}
+}
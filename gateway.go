@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// MountGRPCGateway routes the requests for pattern and its subpaths to
+// gw, a grpc-gateway runtime.ServeMux or any other JSON transcoding
+// handler, behind the Mux middleware stack. The gateway sees the full
+// request path, so its HTTP rules must include the pattern:
+//
+//	gw := runtime.NewServeMux()
+//	pb.RegisterUsersHandlerServer(ctx, gw, users)
+//	mux.MountGRPCGateway("/v1", gw)
+//
+// Error responses written by the gateway are returned as StatusErrors
+// with the status and message of the gRPC status, so they go through
+// the handler error path like the errors of other routes.
+func (m *Mux) MountGRPCGateway(pattern string, gw http.Handler, opts ...RouteOption) {
+	h := GRPCGateway(gw)
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern != "" {
+		m.Handle(pattern, h, opts...)
+		opts = []RouteOption{subtreeOptions(opts)}
+	}
+	m.Handle(pattern+"/*", h, opts...)
+}
+
+// GRPCGateway adapts gw to a Handler that returns the error responses
+// written by the gateway as StatusErrors.
+func GRPCGateway(gw http.Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		ec := &errorCapture{ResponseWriter: w}
+		gw.ServeHTTP(ec, r)
+		if ec.status == 0 {
+			return nil
+		}
+		var st struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal([]byte(ec.msg.String()), &st) == nil && st.Message != "" {
+			return Error(ec.status, st.Message)
+		}
+		return ec.err()
+	})
+}
@@ -0,0 +1,167 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// GraphQLRequest is a GraphQL operation sent over HTTP.
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLResponse is the result of a GraphQL operation.
+type GraphQLResponse struct {
+	Data       interface{}            `json:"data,omitempty"`
+	Errors     []GraphQLError         `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLError is an error in the GraphQL response format.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLLocation is a position in a GraphQL document.
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLExecutor executes GraphQL operations against a schema. It
+// adapts the GraphQL library of the service's choice. Errors raised
+// while executing the operation belong in the response; an error
+// returned by the executor fails the HTTP request, and is returned by
+// the GraphQL handler.
+type GraphQLExecutor func(ctx context.Context, req GraphQLRequest) (*GraphQLResponse, error)
+
+// GraphQLOptions configures a GraphQL handler.
+type GraphQLOptions struct {
+	// GraphiQL serves the GraphiQL IDE to browsers loading the endpoint.
+	// It is meant for development.
+	GraphiQL bool
+}
+
+// GraphQL returns a handler for a GraphQL endpoint that runs operations
+// with exec. Operations are read from the query, operationName and
+// variables parameters of GET requests, and from application/json or
+// application/graphql bodies of POST requests:
+//
+//	mux.Handle("/graphql", httpx.GraphQL(exec, httpx.GraphQLOptions{GraphiQL: dev}))
+//
+// Results are written as JSON, with a 200 OK status, including results
+// holding execution errors. Requests that carry no valid operation fail
+// with a StatusError, such as a 400 Bad Request, that WriteError writes
+// in the GraphQL error format.
+func GraphQL(exec GraphQLExecutor, opts GraphQLOptions) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if opts.GraphiQL && r.Method == http.MethodGet && r.URL.Query().Get("query") == "" &&
+			Negotiate(r, "application/json", "text/html") == "text/html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, err := io.WriteString(w, graphiQLPage)
+			return err
+		}
+
+		req, err := parseGraphQLRequest(r)
+		if err != nil {
+			return err
+		}
+
+		resp, err := exec(r.Context(), req)
+		if err != nil {
+			return err
+		}
+		return JSON(w, http.StatusOK, resp)
+	})
+}
+
+// graphQLRequestError is an invalid GraphQL request, written in the
+// GraphQL error format.
+type graphQLRequestError struct {
+	headerError
+}
+
+func graphQLErrorf(status int, format string, v ...interface{}) *graphQLRequestError {
+	return &graphQLRequestError{headerError{statusError: statusError{fmt.Sprintf(format, v...), status}}}
+}
+
+func (e *graphQLRequestError) WriteBody(w http.ResponseWriter, status int) {
+	w.Header().Del("Content-Length")
+	JSON(w, status, GraphQLResponse{Errors: []GraphQLError{{Message: e.message}}})
+}
+
+func parseGraphQLRequest(r *http.Request) (GraphQLRequest, error) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return req, graphQLErrorf(http.StatusBadRequest, "variables parameter is not a JSON object")
+			}
+		}
+		if v := q.Get("extensions"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Extensions); err != nil {
+				return req, graphQLErrorf(http.StatusBadRequest, "extensions parameter is not a JSON object")
+			}
+		}
+	case http.MethodPost:
+		mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mt {
+		case "application/json", "":
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return req, graphQLErrorf(http.StatusBadRequest, "request body is not a valid GraphQL request")
+			}
+		case "application/graphql":
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				return req, err
+			}
+			req.Query = string(b)
+		default:
+			return req, graphQLErrorf(http.StatusUnsupportedMediaType, "unsupported content type %q", mt)
+		}
+	default:
+		err := graphQLErrorf(http.StatusMethodNotAllowed, "GraphQL requests must use GET or POST")
+		err.header = http.Header{"Allow": {"GET, POST"}}
+		return req, err
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return req, graphQLErrorf(http.StatusBadRequest, "missing GraphQL query")
+	}
+	return req, nil
+}
+
+const graphiQLPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GraphiQL</title>
+<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+<style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+</head>
+<body>
+<div id="graphiql"></div>
+<script src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+<script src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+<script src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+<script>
+const fetcher = GraphiQL.createFetcher({ url: window.location.pathname });
+ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, { fetcher }));
+</script>
+</body>
+</html>
+`
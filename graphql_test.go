@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestGraphQLRequestErrors checks that invalid GraphQL requests go
+// through the ErrorWriter of the Mux, and are written in the GraphQL
+// error format by WriteError.
+func TestGraphQLRequestErrors(t *testing.T) {
+	exec := func(ctx context.Context, req GraphQLRequest) (*GraphQLResponse, error) {
+		return &GraphQLResponse{Data: "ok"}, nil
+	}
+	m := NewMux()
+	m.Handle("/graphql", GraphQL(exec, GraphQLOptions{}))
+
+	tests := []struct {
+		method, body string
+		status       int
+		allow        string
+	}{
+		{http.MethodPost, "{", http.StatusBadRequest, ""},
+		{http.MethodPost, `{"query": " "}`, http.StatusBadRequest, ""},
+		{http.MethodPut, "", http.StatusMethodNotAllowed, "GET, POST"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(tt.method, "/graphql", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/json")
+		m.ServeHTTP(rec, r)
+		if rec.Code != tt.status || rec.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %q: status %d, Allow %q", tt.method, tt.body, rec.Code, rec.Header().Get("Allow"))
+		}
+		var resp GraphQLResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Errors) != 1 {
+			t.Errorf("%s %q: body %q is not a GraphQL error", tt.method, tt.body, rec.Body.String())
+		}
+	}
+
+	var written []int
+	m.OnError(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+		written = append(written, status)
+		WriteError(w, r, status, err)
+	})
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader("{")))
+	if len(written) != 1 || written[0] != http.StatusBadRequest {
+		t.Errorf("ErrorWriter called with %v", written)
+	}
+}
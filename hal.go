@@ -0,0 +1,63 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// RouteLink returns a link of relation rel to the named route of the
+// Mux that routed the request, built with params as by Mux.URL. The
+// link can be sent in a Link header with AddLinks, or in the _links of
+// a HAL resource:
+//
+//	self, err := httpx.RouteLink(r, "self", "user", "id", u.ID)
+func RouteLink(r *http.Request, rel, name string, params ...string) (Link, error) {
+	u, err := URLFor(r, name, params...)
+	if err != nil {
+		return Link{}, err
+	}
+	return Link{URL: u, Rel: rel}, nil
+}
+
+// HALLink is a link object of a HAL (application/hal+json) resource.
+type HALLink struct {
+	Href string `json:"href"`
+}
+
+// HALLinks is the _links object of a HAL resource, mapping each
+// relation to its links. A relation with a single link is encoded as a
+// link object, and one with several links as an array.
+type HALLinks map[string][]HALLink
+
+// NewHALLinks returns the HALLinks of links, such as those returned by
+// RouteLink, PageLinks or CursorLinks:
+//
+//	resp := struct {
+//		Links httpx.HALLinks `json:"_links"`
+//		Items []Item         `json:"items"`
+//	}{httpx.NewHALLinks(httpx.PageLinks(r, page, total)...), items}
+func NewHALLinks(links ...Link) HALLinks {
+	hl := HALLinks{}
+	for _, l := range links {
+		hl.Add(l)
+	}
+	return hl
+}
+
+// Add adds a link to its relation.
+func (hl HALLinks) Add(l Link) {
+	hl[l.Rel] = append(hl[l.Rel], HALLink{Href: l.URL})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (hl HALLinks) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(hl))
+	for rel, links := range hl {
+		if len(links) == 1 {
+			m[rel] = links[0]
+		} else {
+			m[rel] = links
+		}
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,71 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headHandler returns a handler that serves HEAD requests by executing
+// h with a headWriter.
+func headHandler(h Handler) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		hw := &headWriter{ResponseWriter: w}
+		err := h.ServeHTTP(hw, r)
+		if err == nil || hw.status != 0 {
+			hw.finish()
+		}
+		return err
+	})
+}
+
+// headWriter discards the response body, counting its length so that
+// the Content-Length of the GET response can be reported. The status is
+// held back until the handler returns, unless the handler flushes.
+type headWriter struct {
+	http.ResponseWriter
+	status  int
+	length  int64
+	flushed bool
+}
+
+func (w *headWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.length += int64(len(b))
+	return len(b), nil
+}
+
+// Flush sends the response headers, with the Content-Length of the body
+// written so far.
+func (w *headWriter) Flush() {
+	w.finish()
+	w.flushed = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *headWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish writes the held back status, with the Content-Length of the
+// discarded body when the handler didn't set one.
+func (w *headWriter) finish() {
+	if w.flushed {
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	if h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" {
+		h.Set("Content-Length", strconv.FormatInt(w.length, 10))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
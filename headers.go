@@ -0,0 +1,121 @@
+package httpx
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// DeferHeaders is a middleware that calls fn with the response headers
+// just before they are written, whether by the next handler or, for an
+// error it returns, by the Mux. It lets a middleware adjust headers
+// after the handler has set its own:
+//
+//	mux.Use(httpx.DeferHeaders(func(h http.Header) {
+//		if h.Get("Cache-Control") == "" {
+//			h.Set("Cache-Control", "no-store")
+//		}
+//	}))
+func DeferHeaders(fn func(h http.Header)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			dw := &deferWriter{ResponseWriter: w, fn: fn}
+			err := next.ServeHTTP(dw, r)
+			dw.apply()
+			return err
+		})
+	}
+}
+
+// SetHeaders is a middleware that sets the headers of every response,
+// replacing any value set by the next handler.
+func SetHeaders(headers map[string]string) Middleware {
+	return DeferHeaders(func(h http.Header) {
+		for k, v := range headers {
+			h.Set(k, v)
+		}
+	})
+}
+
+// DeleteHeaders is a middleware that removes the named headers from
+// every response, such as headers revealing the software of upstream
+// servers.
+func DeleteHeaders(names ...string) Middleware {
+	return DeferHeaders(func(h http.Header) {
+		for _, name := range names {
+			h.Del(name)
+		}
+	})
+}
+
+// AppendVary is a middleware that adds the named request headers to
+// the Vary header of every response, merged with those added by the
+// next handler.
+func AppendVary(names ...string) Middleware {
+	return DeferHeaders(func(h http.Header) {
+		addVary(h, names...)
+	})
+}
+
+// addVary adds names to the Vary header of h, skipping the names it
+// already lists, and collapses its values into a single line.
+func addVary(h http.Header, names ...string) {
+	var tokens []string
+	seen := map[string]bool{}
+	for _, v := range append(h.Values("Vary"), names...) {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" || seen[strings.ToLower(t)] {
+				continue
+			}
+			seen[strings.ToLower(t)] = true
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) > 0 {
+		h.Set("Vary", strings.Join(tokens, ", "))
+	}
+}
+
+// deferWriter calls fn with the response headers before they are
+// written.
+type deferWriter struct {
+	http.ResponseWriter
+	fn      func(h http.Header)
+	applied bool
+	status  int
+}
+
+func (w *deferWriter) apply() {
+	if !w.applied {
+		w.applied = true
+		w.fn(w.Header())
+	}
+}
+
+func (w *deferWriter) WriteHeader(status int) {
+	if status >= 200 || status == http.StatusSwitchingProtocols {
+		w.status = status
+		w.apply()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deferWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *deferWriter) Flush() {
+	w.apply()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *deferWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *deferWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,70 @@
+package httpx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// An HTMLRenderer executes named HTML templates. It is installed for a
+// request with UseHTMLRenderer and used by HTML.
+type HTMLRenderer interface {
+	RenderHTML(w io.Writer, r *http.Request, name string, data interface{}) error
+}
+
+// htmlWriter carries the HTMLRenderer and request of a response.
+type htmlWriter struct {
+	http.ResponseWriter
+	renderer HTMLRenderer
+	r        *http.Request
+}
+
+func (w *htmlWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// UseHTMLRenderer is a middleware that installs renderer for the HTML
+// helper.
+func UseHTMLRenderer(renderer HTMLRenderer) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return next.ServeHTTP(&htmlWriter{ResponseWriter: w, renderer: renderer, r: r}, r)
+		})
+	}
+}
+
+// HTML renders the named template with data, using the renderer
+// installed by UseHTMLRenderer, and writes it with status. The template
+// is rendered to a buffer first, so a template error writes nothing and
+// is returned to be handled by the error path of the handler.
+func HTML(w http.ResponseWriter, status int, name string, data interface{}) error {
+	hw := findHTMLWriter(w)
+	if hw == nil {
+		return errors.New("httpx: no HTMLRenderer installed for the request")
+	}
+
+	var buf bytes.Buffer
+	if err := hw.renderer.RenderHTML(&buf, hw.r, name, data); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// findHTMLWriter unwraps w until it finds the htmlWriter installed by
+// UseHTMLRenderer.
+func findHTMLWriter(w http.ResponseWriter) *htmlWriter {
+	for {
+		switch t := w.(type) {
+		case *htmlWriter:
+			return t
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
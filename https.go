@@ -0,0 +1,135 @@
+package httpx
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// HTTPSRedirect configures a RedirectToHTTPS handler. The zero value
+// permanently redirects each request to the same host over HTTPS.
+type HTTPSRedirect struct {
+	// Host is the canonical host requests are redirected to. The
+	// default is the host of the request.
+	Host string
+
+	// Port is the HTTPS port, when it is not 443.
+	Port string
+
+	// Code is the redirect status code. The default is 308 Permanent
+	// Redirect, which preserves the request method and body.
+	Code int
+
+	// ACME, when set, answers HTTP-01 challenges for the manager
+	// instead of redirecting them.
+	ACME *autocert.Manager
+}
+
+// RedirectToHTTPS returns a handler that redirects plain HTTP requests
+// to HTTPS, preserving their path and query. It is meant to be served
+// on port 80, usually through Server.RedirectHTTP.
+func RedirectToHTTPS(opts HTTPSRedirect) Handler {
+	code := opts.Code
+	if code == 0 {
+		code = http.StatusPermanentRedirect
+	}
+	var challenge http.Handler
+	if opts.ACME != nil {
+		challenge = opts.ACME.HTTPHandler(nil)
+	}
+
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if challenge != nil && strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+			challenge.ServeHTTP(w, r)
+			return nil
+		}
+
+		host := opts.Host
+		if host == "" {
+			host = r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+		}
+		if host == "" {
+			return Error(http.StatusBadRequest, "missing host")
+		}
+		if opts.Port != "" && opts.Port != "443" {
+			host = net.JoinHostPort(host, opts.Port)
+		}
+
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+		http.Redirect(w, r, u.String(), code)
+		return nil
+	})
+}
+
+// RedirectHTTP makes the Server also listen for plain HTTP on addr,
+// usually ":80", and redirect those requests to HTTPS. When the Server
+// was configured with AutoTLS, the HTTP listener also answers ACME
+// HTTP-01 challenges. The HTTP listener is started by ListenAndServeTLS
+// and stopped by Shutdown along with the Server.
+func (s *Server) RedirectHTTP(addr string, opts HTTPSRedirect) {
+	if opts.ACME == nil {
+		opts.ACME = s.acme
+	}
+	s.redirect = &http.Server{
+		Addr:              addr,
+		Handler:           adaptor(RedirectToHTTPS(opts), nil),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// HSTSPolicy is an HTTP Strict Transport Security policy.
+type HSTSPolicy struct {
+	// MaxAge is the time browsers remember to only use HTTPS.
+	MaxAge time.Duration
+
+	// IncludeSubdomains applies the policy to all subdomains.
+	IncludeSubdomains bool
+
+	// Preload signals consent to inclusion in browser preload lists.
+	// Preloading requires a MaxAge of at least a year and
+	// IncludeSubdomains.
+	Preload bool
+}
+
+// HSTSPreload is a policy eligible for browser preload lists.
+var HSTSPreload = HSTSPolicy{
+	MaxAge:            2 * 365 * 24 * time.Hour,
+	IncludeSubdomains: true,
+	Preload:           true,
+}
+
+// String returns the policy as a Strict-Transport-Security header value.
+func (p HSTSPolicy) String() string {
+	v := "max-age=" + strconv.FormatInt(int64(p.MaxAge/time.Second), 10)
+	if p.IncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if p.Preload {
+		v += "; preload"
+	}
+	return v
+}
+
+// HSTS is a middleware that sets the Strict-Transport-Security header
+// on responses to requests received over TLS. Browsers ignore the
+// header on plain HTTP responses.
+func HSTS(policy HSTSPolicy) Middleware {
+	value := policy.String()
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if r.TLS != nil {
+				w.Header().Set("Strict-Transport-Security", value)
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -41,11 +41,16 @@ func RedirectHandler(url string, code int) Handler {
 // As a special case, the returned file server redirects any request
 // ending in "/index.html" to the same path, without the final
 // "index.html".
+//
+// Range requests are answered with 206 Partial Content responses, as
+// with ServeContent. Error responses, such as a 404 for a missing file
+// or a 416 for an unsatisfiable range, are returned as StatusErrors.
 func FileServer(root http.FileSystem) Handler {
 	fs := http.FileServer(root)
 	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
-		fs.ServeHTTP(w, r)
-		return nil
+		ec := &errorCapture{ResponseWriter: w}
+		fs.ServeHTTP(ec, r)
+		return ec.err()
 	})
 }
 
@@ -0,0 +1,167 @@
+package httpxtest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/eriklott/httpx"
+)
+
+// Call is a request received by a Stub.
+type Call struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+
+	// Params holds the URL params of the request, when it was routed
+	// by a Mux.
+	Params map[string]string
+}
+
+// Stub is a Handler double that records the requests it receives and
+// responds with a scripted status, body and errors. The zero value
+// responds with a 200 OK and an empty body. A Stub is safe for
+// concurrent use.
+//
+//	stub := &httpxtest.Stub{Status: http.StatusCreated}
+//	stub.Fail(httpx.Error(http.StatusConflict, "exists"))
+//	h := httpx.NewChain(auth, rateLimit).Then(stub)
+type Stub struct {
+	// Status is the status of the responses. The default is 200 OK.
+	Status int
+
+	// Header holds headers set on the responses.
+	Header http.Header
+
+	// Body is the body of the responses.
+	Body string
+
+	// Trace, when set, records the requests reaching the Stub under
+	// Name, after the middlewares wrapping it.
+	Trace *Trace
+	Name  string
+
+	mu    sync.Mutex
+	errs  []error
+	calls []Call
+}
+
+// Fail scripts the errors returned by the following calls of the Stub,
+// one per call, in order. A nil error responds normally. Once the
+// errors are used up, the Stub responds normally.
+func (s *Stub) Fail(errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, errs...)
+}
+
+// ServeHTTP records the request and responds as scripted.
+func (s *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	call := Call{Method: r.Method, URL: r.URL.String(), Header: r.Header.Clone(), Params: httpx.URLParams(r)}
+	if r.Body != nil {
+		call.Body, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(call.Body))
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	var err error
+	if len(s.errs) > 0 {
+		err, s.errs = s.errs[0], s.errs[1:]
+	}
+	s.mu.Unlock()
+
+	if s.Trace != nil {
+		s.Trace.record(s.Name)
+	}
+	if err != nil {
+		return err
+	}
+	for key, values := range s.Header {
+		w.Header()[key] = values
+	}
+	if s.Status != 0 {
+		w.WriteHeader(s.Status)
+	}
+	_, err = io.WriteString(w, s.Body)
+	return err
+}
+
+// Calls returns the requests received by the Stub, in order.
+func (s *Stub) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Called returns the number of requests received by the Stub.
+func (s *Stub) Called() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+// Trace records the order in which middlewares and handlers see the
+// requests of a test, to check the composition of a chain:
+//
+//	var trace httpxtest.Trace
+//	stub := &httpxtest.Stub{Trace: &trace, Name: "handler"}
+//	h := httpx.NewChain(trace.Spy("auth", auth), trace.Spy("ratelimit", rateLimit)).Then(stub)
+//	h.ServeHTTP(httptest.NewRecorder(), req)
+//	trace.AssertOrder(t, "auth", "ratelimit", "handler")
+//
+// A Trace is safe for concurrent use.
+type Trace struct {
+	mu     sync.Mutex
+	events []string
+}
+
+// Spy returns mw wrapped to record name when a request reaches mw.
+// A nil mw records name and passes requests to the next handler.
+func (tr *Trace) Spy(name string, mw httpx.Middleware) httpx.Middleware {
+	return func(next httpx.Handler) httpx.Handler {
+		inner := next
+		if mw != nil {
+			inner = mw(next)
+		}
+		return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			tr.record(name)
+			return inner.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (tr *Trace) record(name string) {
+	tr.mu.Lock()
+	tr.events = append(tr.events, name)
+	tr.mu.Unlock()
+}
+
+// Events returns the recorded names, in order.
+func (tr *Trace) Events() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]string(nil), tr.events...)
+}
+
+// Reset clears the recorded names.
+func (tr *Trace) Reset() {
+	tr.mu.Lock()
+	tr.events = nil
+	tr.mu.Unlock()
+}
+
+// AssertOrder fails t unless the recorded names are exactly names, in
+// order. A middleware that short-circuits a request, such as an auth
+// middleware rejecting it, leaves the names after it unrecorded.
+func (tr *Trace) AssertOrder(t testing.TB, names ...string) {
+	t.Helper()
+	if events := tr.Events(); !reflect.DeepEqual(events, names) && !(len(events) == 0 && len(names) == 0) {
+		t.Errorf("httpxtest: trace = %q, want %q", events, names)
+	}
+}
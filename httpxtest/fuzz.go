@@ -0,0 +1,151 @@
+package httpxtest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// FuzzOptions configures Fuzz.
+type FuzzOptions struct {
+	// Patterns lists the routing patterns of the requests, whose {name}
+	// params and trailing * are replaced by fuzzed values, for example
+	// "/users/{id}". Requests with an entirely fuzzed path are sent as
+	// well.
+	Patterns []string
+
+	// Methods lists the request methods. The default is GET, HEAD,
+	// POST, PUT, PATCH, DELETE and OPTIONS.
+	Methods []string
+
+	// Seeds are requests added to the seed corpus, usually valid
+	// requests for the fuzzer to mutate.
+	Seeds []*http.Request
+
+	// AllowServerErrors accepts 5xx responses. By default, a 5xx
+	// response fails the input, as it usually shows an error that
+	// should have been a 4xx.
+	AllowServerErrors bool
+
+	// Check, when set, is called with each request and its response
+	// for further assertions.
+	Check func(t *testing.T, r *http.Request, resp *http.Response)
+}
+
+// Fuzz drives the Go fuzzing engine against h, with requests of random
+// methods, path params, headers and bodies. An input fails when h
+// panics or responds with an invalid status code, or, unless allowed,
+// with a server error:
+//
+//	func FuzzCreateUser(f *testing.F) {
+//		httpxtest.Fuzz(f, newMux(), httpxtest.FuzzOptions{
+//			Patterns: []string{"/users", "/users/{id}"},
+//			Seeds:    []*http.Request{httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"a"}`))},
+//		})
+//	}
+//
+// Headers are fuzzed as a block of "Key: value" lines.
+func Fuzz(f *testing.F, h http.Handler, opts FuzzOptions) {
+	f.Helper()
+	// The last pattern fuzzes the whole path, and is used for seeds.
+	patterns := append(opts.Patterns[:len(opts.Patterns):len(opts.Patterns)], "/*")
+	if len(opts.Methods) == 0 {
+		opts.Methods = []string{
+			http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodOptions,
+		}
+	}
+
+	f.Add(uint8(0), uint8(0), "1", "", []byte(nil))
+	for _, r := range opts.Seeds {
+		var header bytes.Buffer
+		r.Header.Write(&header)
+		var body []byte
+		if r.Body != nil {
+			var buf bytes.Buffer
+			buf.ReadFrom(r.Body)
+			body = buf.Bytes()
+		}
+		f.Add(uint8(indexOf(opts.Methods, r.Method)), uint8(len(patterns)-1), strings.TrimPrefix(r.URL.Path, "/"), header.String(), body)
+	}
+
+	f.Fuzz(func(t *testing.T, method, pattern uint8, param, header string, body []byte) {
+		path := fuzzPath(patterns[int(pattern)%len(patterns)], param)
+		r := httptest.NewRequest(opts.Methods[int(method)%len(opts.Methods)], "/", bytes.NewReader(body))
+		r.URL.Path, r.URL.RawPath = path, ""
+		if p, err := url.PathUnescape(path); err == nil && p != path {
+			r.URL.Path, r.URL.RawPath = p, path
+		}
+		r.RequestURI = r.URL.RequestURI()
+		for _, line := range strings.Split(header, "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if key = strings.TrimSpace(key); ok && validHeaderKey(key) {
+				r.Header.Add(key, strings.TrimSpace(value))
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		resp := rec.Result()
+		switch {
+		case resp.StatusCode < 100 || resp.StatusCode > 599:
+			t.Fatalf("%s %s: invalid status %d", r.Method, r.URL, resp.StatusCode)
+		case resp.StatusCode >= 500 && !opts.AllowServerErrors:
+			t.Fatalf("%s %s: status %d: %s", r.Method, r.URL, resp.StatusCode, rec.Body.String())
+		}
+		if opts.Check != nil {
+			opts.Check(t, r, resp)
+		}
+	})
+}
+
+// fuzzPath returns pattern with its params and wildcard replaced by
+// value, escaped as a path segment except in a wildcard.
+func fuzzPath(pattern, value string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '{':
+			depth := 1
+			for i++; i < len(pattern) && depth > 0; i++ {
+				switch pattern[i] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+				}
+			}
+			i--
+			b.WriteString(url.PathEscape(value))
+		case c == '*' && i == len(pattern)-1:
+			b.WriteString(value)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func validHeaderKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range []byte(key) {
+		if c <= ' ' || c >= 0x7f || strings.IndexByte("\"(),/:;<=>?@[\\]{}", c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(values []string, v string) int {
+	for i, value := range values {
+		if value == v {
+			return i
+		}
+	}
+	return 0
+}
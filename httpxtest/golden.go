@@ -0,0 +1,254 @@
+// Package httpxtest provides utilities for testing httpx handlers and
+// Muxes. Record and Replay turn real traffic into golden files that
+// protect an API against accidental changes of behavior:
+//
+//	// Record fixtures while a test exercises the service.
+//	func TestRecord(t *testing.T) {
+//		srv := httptest.NewServer(httpxtest.Record(t, newMux(), "testdata/golden"))
+//		defer srv.Close()
+//		runScenarios(t, srv.URL)
+//	}
+//
+//	// Assert that the service still responds the same way.
+//	func TestGolden(t *testing.T) {
+//		httpxtest.Replay(t, newMux(), "testdata/golden", httpxtest.ReplayOptions{})
+//	}
+package httpxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eriklott/httpx/internal/record"
+)
+
+// Fixture is a request and its response, as stored in a golden file.
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is the request of a Fixture.
+type FixtureRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// FixtureResponse is the response of a Fixture.
+type FixtureResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Record returns a handler that passes requests to h and writes each
+// request and its response as a Fixture to a golden file in dir. Files
+// are numbered in the order the requests complete, after the files
+// already in dir, and named after the request method and path. Files
+// that can't be written fail t.
+//
+// Record keeps whole bodies in memory and is meant for recording
+// sessions, not for production traffic.
+func Record(t testing.TB, h http.Handler, dir string) http.Handler {
+	var mu sync.Mutex
+	seq := -1
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody []byte
+		if r.Body != nil {
+			reqBody, _ = io.ReadAll(r.Body)
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(reqBody))
+		}
+		fx := Fixture{Request: FixtureRequest{
+			Method: r.Method,
+			URL:    r.URL.RequestURI(),
+			Header: r.Header.Clone(),
+			Body:   string(reqBody),
+		}}
+		if r.Host != "" {
+			fx.Request.Header.Set("Host", r.Host)
+		}
+
+		rw := &record.Tee{ResponseWriter: w}
+		h.ServeHTTP(rw, r)
+		if rw.Status == 0 {
+			rw.Status = http.StatusOK
+		}
+		fx.Response = FixtureResponse{Status: rw.Status, Header: w.Header().Clone(), Body: rw.Body.String()}
+		if _, ok := fx.Response.Header["Content-Type"]; !ok && rw.Body.Len() > 0 {
+			// Record the type sniffed by net/http, as the client saw it.
+			fx.Response.Header.Set("Content-Type", http.DetectContentType(rw.Body.Bytes()))
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if seq < 0 {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Errorf("httpxtest: %v", err)
+			}
+			files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+			seq = len(files)
+		}
+		seq++
+		if err := writeFixture(filepath.Join(dir, fixtureName(seq, r)), fx); err != nil {
+			t.Errorf("httpxtest: %v", err)
+		}
+	})
+}
+
+// fixtureName returns the file name of the seq-th fixture, for r.
+func fixtureName(seq int, r *http.Request) string {
+	name := strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+			return c
+		}
+		return '-'
+	}, strings.Trim(r.URL.Path, "/"))
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return fmt.Sprintf("%04d-%s-%s.json", seq, r.Method, name)
+}
+
+func writeFixture(path string, fx Fixture) error {
+	b, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	// IgnoreHeaders lists response headers that aren't compared, such
+	// as headers holding timestamps or request ids. Date is never
+	// compared.
+	IgnoreHeaders []string
+
+	// Update rewrites the responses of the fixtures with the responses
+	// of the handler, instead of comparing them, to accept intended
+	// changes of behavior. Update is also turned on by setting the
+	// HTTPXTEST_UPDATE environment variable to a true value.
+	Update bool
+}
+
+// Replay sends the request of each Fixture in dir to h, in file name
+// order, and fails a subtest named after the file when the response
+// differs from the recorded one. JSON bodies are compared by value, so
+// that the order of object keys doesn't matter.
+func Replay(t *testing.T, h http.Handler, dir string, opts ReplayOptions) {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("httpxtest: no fixtures in %s", dir)
+	}
+	sort.Strings(files)
+	if update, _ := strconv.ParseBool(os.Getenv("HTTPXTEST_UPDATE")); update {
+		opts.Update = true
+	}
+	ignored := map[string]bool{"Date": true}
+	for _, key := range opts.IgnoreHeaders {
+		ignored[http.CanonicalHeaderKey(key)] = true
+	}
+
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".json"), func(t *testing.T) {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fx Fixture
+			if err := json.Unmarshal(b, &fx); err != nil {
+				t.Fatalf("httpxtest: %s: %v", file, err)
+			}
+
+			req := httptest.NewRequest(fx.Request.Method, fx.Request.URL, strings.NewReader(fx.Request.Body))
+			for key, values := range fx.Request.Header {
+				if key == "Host" {
+					req.Host = values[0]
+					continue
+				}
+				req.Header[key] = values
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			got := FixtureResponse{Status: rec.Code, Header: rec.Header().Clone(), Body: rec.Body.String()}
+
+			if opts.Update {
+				fx.Response = got
+				if err := writeFixture(file, fx); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want := fx.Response
+			if got.Status != want.Status {
+				t.Errorf("status = %d, want %d", got.Status, want.Status)
+			}
+			for _, key := range headerKeys(want.Header, got.Header) {
+				if ignored[key] {
+					continue
+				}
+				if g, w := got.Header[key], want.Header[key]; !reflect.DeepEqual(g, w) {
+					t.Errorf("header %s = %q, want %q", key, g, w)
+				}
+			}
+			if !equalBodies(got.Body, want.Body, want.Header.Get("Content-Type")) {
+				t.Errorf("body = %s\nwant %s", got.Body, want.Body)
+			}
+		})
+	}
+}
+
+// headerKeys returns the sorted keys of a and b.
+func headerKeys(a, b http.Header) []string {
+	set := map[string]bool{}
+	for key := range a {
+		set[key] = true
+	}
+	for key := range b {
+		set[key] = true
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// equalBodies reports whether two bodies of contentType are equal,
+// comparing JSON bodies by value.
+func equalBodies(a, b, contentType string) bool {
+	if a == b {
+		return true
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	if mt != "application/json" && !strings.HasSuffix(mt, "+json") {
+		return false
+	}
+	var av, bv interface{}
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
@@ -0,0 +1,27 @@
+package httpxtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// TestRecordReplay records fixtures and replays them against the same
+// handler.
+func TestRecordReplay(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"path": "` + r.URL.Path + `", "ok": true}`))
+	})
+	dir := t.TempDir()
+	rec := Record(t, h, dir)
+	for _, path := range []string{"/a", "/b/c"} {
+		rec.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 2 {
+		t.Fatalf("recorded %d fixtures, want 2", len(files))
+	}
+	Replay(t, h, dir, ReplayOptions{})
+}
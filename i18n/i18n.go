@@ -0,0 +1,280 @@
+// Package i18n localizes responses. A Bundle holds the messages of the
+// supported locales, loaded from JSON files, and its Middleware
+// negotiates the locale of each request and installs a translator for
+// httpx.T:
+//
+//	//go:embed locales
+//	var locales embed.FS
+//
+//	bundle, err := i18n.Load(locales, "locales", "en")
+//	mux.Use(bundle.Middleware(i18n.Options{}))
+//	mux.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+//		return httpx.JSON(w, http.StatusOK, httpx.T(r, "welcome", name))
+//	})
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eriklott/httpx"
+	"golang.org/x/text/language"
+)
+
+// Bundle holds the messages of a set of locales.
+type Bundle struct {
+	tags     []language.Tag
+	matcher  language.Matcher
+	messages map[language.Tag]map[string]string
+}
+
+// Load returns a Bundle of the message files in dir of fsys. Each file
+// is named after the BCP 47 tag of its locale, such as fr-CA.json, and
+// holds a JSON object of messages by key. Messages are formatted with
+// the arguments of httpx.T as by fmt.Sprintf. The fallback locale,
+// which must have a file, is used for requests matching no other
+// locale, and for the keys other locales lack.
+func Load(fsys fs.FS, dir, fallback string) (*Bundle, error) {
+	def, err := language.Parse(fallback)
+	if err != nil {
+		return nil, fmt.Errorf("i18n: fallback locale: %w", err)
+	}
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{tags: []language.Tag{def}, messages: map[language.Tag]map[string]string{}}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(path.Base(file), ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		b.messages[tag] = messages
+		if tag != def {
+			b.tags = append(b.tags, tag)
+		}
+	}
+	if _, ok := b.messages[def]; !ok {
+		return nil, fmt.Errorf("i18n: no messages for fallback locale %s", fallback)
+	}
+	// The matcher prefers the first of equally good locales, so a
+	// language goes before its regional variants.
+	others := b.tags[1:]
+	sort.Slice(others, func(i, j int) bool { return others[i].String() < others[j].String() })
+	b.matcher = language.NewMatcher(b.tags)
+	return b, nil
+}
+
+// Locales returns the tags of the locales of the bundle, the fallback
+// first.
+func (b *Bundle) Locales() []string {
+	locales := make([]string, len(b.tags))
+	for i, tag := range b.tags {
+		locales[i] = tag.String()
+	}
+	return locales
+}
+
+// Match returns the locale of the bundle best matching the preferences,
+// BCP 47 tags or Accept-Language header values, or the fallback locale.
+func (b *Bundle) Match(preferences ...string) string {
+	return b.match(preferences...).String()
+}
+
+func (b *Bundle) match(preferences ...string) language.Tag {
+	var prefs []language.Tag
+	for _, p := range preferences {
+		tags, _, err := language.ParseAcceptLanguage(p)
+		if err == nil {
+			prefs = append(prefs, tags...)
+		}
+	}
+	_, i, conf := b.matcher.Match(prefs...)
+	if conf == language.No {
+		return b.tags[0]
+	}
+	return b.tags[i]
+}
+
+// Translator returns the translator of the locale of the bundle best
+// matching locale, for translating outside of requests.
+func (b *Bundle) Translator(locale string) httpx.Translator {
+	return b.translator(b.match(locale))
+}
+
+func (b *Bundle) translator(tag language.Tag) *translator {
+	t := &translator{locale: tag.String()}
+	for ; ; tag = tag.Parent() {
+		if messages, ok := b.messages[tag]; ok {
+			t.chain = append(t.chain, messages)
+		}
+		if tag == language.Und {
+			break
+		}
+	}
+	if tag := b.tags[0]; t.locale != tag.String() {
+		t.chain = append(t.chain, b.messages[tag])
+	}
+	return t
+}
+
+// translator looks messages up in the messages of its locale, of the
+// parents of the locale, then of the fallback locale.
+type translator struct {
+	locale string
+	chain  []map[string]string
+}
+
+func (t *translator) Locale() string {
+	return t.locale
+}
+
+func (t *translator) Translate(key string, args ...interface{}) (string, bool) {
+	for _, messages := range t.chain {
+		if msg, ok := messages[key]; ok {
+			if len(args) == 0 {
+				return msg, true
+			}
+			return fmt.Sprintf(msg, args...), true
+		}
+	}
+	return "", false
+}
+
+// Options configures the Middleware of a Bundle.
+type Options struct {
+	// Query is the name of the query parameter overriding the locale
+	// negotiated from the Accept-Language header. The default is
+	// "lang".
+	Query string
+
+	// Cookie is the name of the cookie overriding the locale, for
+	// clients that picked one. The query parameter takes precedence.
+	// The default is "lang".
+	Cookie string
+}
+
+// Middleware returns a middleware that negotiates the locale of each
+// request and installs its translator, for httpx.T and httpx.Locale.
+// The locale is that of the query parameter or cookie named by opts,
+// when set to one of the locales of the bundle, and is otherwise
+// negotiated from the Accept-Language header.
+//
+// Responses, including error responses, carry the locale in their
+// Content-Language header. The messages of the StatusErrors returned by
+// the next handler are translated too, so that API clients get errors
+// in their language: the code of a CodedError is looked up as
+// error.<code>, such as error.user_not_found, then the message itself
+// as a key, then error.<status>, such as error.404, and the message is
+// kept when the bundle has none of them. The negotiation is recorded
+// for httpx.AutoVary.
+func (b *Bundle) Middleware(opts Options) httpx.Middleware {
+	if opts.Query == "" {
+		opts.Query = "lang"
+	}
+	if opts.Cookie == "" {
+		opts.Cookie = "lang"
+	}
+	return func(next httpx.Handler) httpx.Handler {
+		return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			tag, ok := b.override(r, opts)
+			if !ok {
+				httpx.VaryBy(r, "Accept-Language")
+				tag = b.match(r.Header.Values("Accept-Language")...)
+			}
+			t := b.translator(tag)
+			w.Header().Set("Content-Language", t.locale)
+			return localizeError(t, next.ServeHTTP(w, httpx.WithTranslator(r, t)))
+		})
+	}
+}
+
+// localizeError returns err with its message translated by t, when err
+// is a StatusError whose code as error.<code>, message, or status as
+// error.<status>, is a message key.
+func localizeError(t *translator, err error) error {
+	sErr, ok := err.(httpx.StatusError)
+	if !ok {
+		return err
+	}
+	keys := []string{sErr.Error(), "error." + strconv.Itoa(sErr.Status())}
+	var cErr httpx.CodedError
+	if errors.As(err, &cErr) {
+		keys = append([]string{"error." + cErr.Code()}, keys...)
+	}
+	for _, key := range keys {
+		if msg, ok := t.Translate(key); ok {
+			return &localizedError{StatusError: sErr, message: msg}
+		}
+	}
+	return err
+}
+
+// localizedError is a StatusError with a translated message.
+type localizedError struct {
+	httpx.StatusError
+	message string
+}
+
+func (e *localizedError) Error() string {
+	return e.message
+}
+
+func (e *localizedError) Unwrap() error {
+	return e.StatusError
+}
+
+// override returns the locale picked with the query parameter or cookie
+// of opts, when it is one of the locales of the bundle.
+func (b *Bundle) override(r *http.Request, opts Options) (language.Tag, bool) {
+	var picked []string
+	if v := r.URL.Query().Get(opts.Query); v != "" {
+		picked = append(picked, v)
+	}
+	if c, err := r.Cookie(opts.Cookie); err == nil && c.Value != "" {
+		picked = append(picked, c.Value)
+	}
+	for _, v := range picked {
+		tag, err := language.Parse(v)
+		if err != nil {
+			continue
+		}
+		if _, ok := b.messages[tag]; ok {
+			return tag, true
+		}
+	}
+	return language.Und, false
+}
+
+// Funcs returns the template functions translating into the locale of
+// r, for an httpx.HTMLRenderer to add to its templates: t, which is
+// httpx.T, and locale, which is httpx.Locale.
+//
+//	<h1>{{t "welcome" .Name}}</h1>
+func Funcs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			return httpx.T(r, key, args...)
+		},
+		"locale": func() string {
+			return httpx.Locale(r)
+		},
+	}
+}
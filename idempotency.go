@@ -0,0 +1,253 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the state of an idempotency key kept in an
+// IdempotencyStore.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request that first used the key, so a
+	// different request reusing it can be detected.
+	Fingerprint string
+
+	// Done is false while the first request is being handled, and true
+	// once its response is recorded.
+	Done bool
+
+	// Status, Header and Body are the recorded response.
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore stores the IdempotencyRecords of idempotency keys.
+// Implementations backed by a shared database or cache let replays work
+// across instances of a service.
+type IdempotencyStore interface {
+	// Begin stores rec under key for ttl, unless a record is already
+	// stored, which is then returned.
+	Begin(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error)
+
+	// Complete replaces the record of key with rec, for ttl.
+	Complete(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error
+
+	// Release deletes the record of key, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// NewMemoryIdempotencyStore returns an IdempotencyStore that keeps the
+// records in memory, for single instance services and tests.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]memoryIdempotencyEntry{}}
+}
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]memoryIdempotencyEntry
+}
+
+type memoryIdempotencyEntry struct {
+	rec     IdempotencyRecord
+	expires time.Time
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if e, ok := s.records[key]; ok && now.Before(e.expires) {
+		return &e.rec, nil
+	}
+	s.records[key] = memoryIdempotencyEntry{rec: rec, expires: now.Add(ttl)}
+	if len(s.records)%1024 == 0 {
+		for k, e := range s.records {
+			if !now.Before(e.expires) {
+				delete(s.records, k)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryIdempotencyEntry{rec: rec, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// IdempotencyOptions configures the Idempotency middleware.
+type IdempotencyOptions struct {
+	// Store keeps the recorded responses.
+	Store IdempotencyStore
+
+	// TTL is how long a response is replayed for. The default is 24
+	// hours.
+	TTL time.Duration
+
+	// Header is the request header carrying the key. The default is
+	// Idempotency-Key.
+	Header string
+
+	// Required makes requests without a key fail with a 400 Bad Request
+	// StatusError.
+	Required bool
+
+	// Scope, when set, returns a namespace for the keys of a request,
+	// such as the authenticated account, so clients can't replay each
+	// other's responses.
+	Scope func(r *http.Request) string
+
+	// MaxBodySize is the size of the largest request body accepted. The
+	// default is 1 MiB.
+	MaxBodySize int64
+}
+
+// Idempotency is a middleware that makes POST and PATCH requests
+// carrying an idempotency key safe to retry. The response to the first
+// request with a key is recorded in opts.Store, and replayed to any
+// retry within opts.TTL with an Idempotent-Replayed header, without the
+// next handler being called again.
+//
+// A retry sent while the first request is still being handled fails
+// with a 409 Conflict StatusError, and a key reused for a request with
+// a different method, path or body fails with a 422 Unprocessable
+// Entity StatusError. Responses with a 5xx status, and errors returned
+// by the next handler, are not recorded, so the request can be retried.
+func Idempotency(opts IdempotencyOptions) Middleware {
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	if opts.Header == "" {
+		opts.Header = "Idempotency-Key"
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 1 << 20
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+				return next.ServeHTTP(w, r)
+			}
+			key := r.Header.Get(opts.Header)
+			if key == "" {
+				if opts.Required {
+					return Errorf(http.StatusBadRequest, "missing %s header", opts.Header)
+				}
+				return next.ServeHTTP(w, r)
+			}
+			if len(key) > 255 {
+				return Errorf(http.StatusBadRequest, "%s header is too long", opts.Header)
+			}
+			if opts.Scope != nil {
+				key = opts.Scope(r) + "\x00" + key
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBodySize+1))
+			if err != nil {
+				return err
+			}
+			if int64(len(body)) > opts.MaxBodySize {
+				return Error(http.StatusRequestEntityTooLarge, "request body too large")
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			sum := sha256.New()
+			io.WriteString(sum, r.Method+" "+r.URL.RequestURI()+"\n")
+			sum.Write(body)
+			fingerprint := hex.EncodeToString(sum.Sum(nil))
+
+			ctx := r.Context()
+			prev, err := opts.Store.Begin(ctx, key, IdempotencyRecord{Fingerprint: fingerprint}, opts.TTL)
+			if err != nil {
+				return err
+			}
+			if prev != nil {
+				switch {
+				case prev.Fingerprint != fingerprint:
+					return Errorf(http.StatusUnprocessableEntity, "%s was used for a different request", opts.Header)
+				case !prev.Done:
+					w.Header().Set("Retry-After", "1")
+					return Errorf(http.StatusConflict, "a request with this %s is in progress", opts.Header)
+				}
+				return replayIdempotent(w, prev)
+			}
+
+			cw := &captureWriter{responseWriter: wrapWriter(w)}
+			err = next.ServeHTTP(cw, r)
+			status := statusOf(r, cw.responseWriter, err)
+			if err != nil || status >= 500 {
+				opts.Store.Release(context.WithoutCancel(ctx), key)
+				return err
+			}
+			rec := IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Done:        true,
+				Status:      status,
+				Header:      cw.header,
+				Body:        cw.body.Bytes(),
+			}
+			if rec.Header == nil {
+				rec.Header = w.Header().Clone()
+			}
+			if err := opts.Store.Complete(context.WithoutCancel(ctx), key, rec, opts.TTL); err != nil {
+				opts.Store.Release(context.WithoutCancel(ctx), key)
+			}
+			return nil
+		})
+	}
+}
+
+// replayIdempotent writes a recorded response.
+func replayIdempotent(w http.ResponseWriter, rec *IdempotencyRecord) error {
+	h := w.Header()
+	for k, v := range rec.Header {
+		h[k] = v
+	}
+	h.Set("Idempotent-Replayed", "true")
+	h.Set("Content-Length", strconv.Itoa(len(rec.Body)))
+	w.WriteHeader(rec.Status)
+	_, err := w.Write(rec.Body)
+	return err
+}
+
+// captureWriter records the header and body of a response as they are
+// written.
+type captureWriter struct {
+	*responseWriter
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.header == nil {
+		w.header = w.Header().Clone()
+	}
+	w.responseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.header == nil {
+		w.header = w.Header().Clone()
+	}
+	n, err := w.responseWriter.Write(b)
+	w.body.Write(b[:n])
+	return n, err
+}
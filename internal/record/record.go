@@ -0,0 +1,86 @@
+// Package record provides the http.ResponseWriters that httpx and its
+// subpackages use to record responses.
+package record
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Recorder is an http.ResponseWriter recording a response in memory,
+// for handlers whose response is replayed or inspected rather than sent
+// to a client. The zero value is ready to use.
+type Recorder struct {
+	// Status is the status of the response, or 0 until it is written.
+	// Informational statuses are not recorded.
+	Status int
+
+	// Body holds the body of the response, unless Discard is set.
+	Body bytes.Buffer
+
+	// Discard drops the body of the response, for responses whose
+	// status is all that matters.
+	Discard bool
+
+	header http.Header
+}
+
+// Header returns the headers of the response. Once the status is
+// written, they are a copy of the headers at that time, so that the
+// handler can't change them anymore.
+func (w *Recorder) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *Recorder) WriteHeader(status int) {
+	if w.Status == 0 && status >= 200 {
+		w.Status = status
+		w.header = w.Header().Clone()
+	}
+}
+
+func (w *Recorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.Discard {
+		return len(b), nil
+	}
+	return w.Body.Write(b)
+}
+
+// Flush does nothing, as the response is recorded whole.
+func (w *Recorder) Flush() {}
+
+// Tee is an http.ResponseWriter writing a response to ResponseWriter
+// while keeping a copy of its status and body.
+type Tee struct {
+	http.ResponseWriter
+
+	// Status is the status of the response, or 0 until it is written.
+	// Informational statuses are not recorded.
+	Status int
+
+	// Body holds a copy of the body of the response.
+	Body bytes.Buffer
+}
+
+func (w *Tee) WriteHeader(status int) {
+	if w.Status == 0 && status >= 200 {
+		w.Status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *Tee) Write(b []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	w.Body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *Tee) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// IPFilter is a middleware that rejects requests by client address with
+// a 403 Forbidden StatusError. A client matching any deny prefix is
+// rejected. When allow is not empty, a client must also match one of
+// its prefixes.
+//
+// The client address is read with ClientIP, so IPFilter should be used
+// after RealIP when the service runs behind proxies. Like any
+// middleware, it can be applied to a Group or Route with Use:
+//
+//	mux.Route("/admin", func(m *httpx.Mux) {
+//		m.Use(httpx.IPFilter(officeNets, nil))
+//	})
+func IPFilter(allow, deny []netip.Prefix) Middleware {
+	return IPFilterFunc(func() ([]netip.Prefix, []netip.Prefix) {
+		return allow, deny
+	})
+}
+
+// IPFilterFunc is like IPFilter, but reads the allow and deny lists
+// from provider on each request, so the lists can be reloaded at
+// runtime. Provider must be safe for concurrent use.
+func IPFilterFunc(provider func() (allow, deny []netip.Prefix)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			allow, deny := provider()
+			if !ipAllowed(ClientIP(r), allow, deny) {
+				return securityError(r, SecurityIPBlocked, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ipAllowed(ip netip.Addr, allow, deny []netip.Prefix) bool {
+	if !ip.IsValid() {
+		return false
+	}
+	if containsAddr(deny, ip) {
+		return false
+	}
+	return len(allow) == 0 || containsAddr(allow, ip)
+}
+
+func containsAddr(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+// Package jsonapi registers a JSON:API (https://jsonapi.org) codec with
+// httpx, so that httpx.Render and httpx.Bind handle the
+// application/vnd.api+json media type. Values are converted to and
+// from resource objects by the serializers registered for their types:
+//
+//	jsonapi.Register(func(a *Article) jsonapi.Resource {
+//		return jsonapi.Resource{
+//			Type:       "articles",
+//			ID:         a.ID,
+//			Attributes: a,
+//			Relationships: map[string]jsonapi.Relationship{
+//				"author": jsonapi.ToOne("people", a.AuthorID),
+//			},
+//		}
+//	})
+//
+// A client accepting application/vnd.api+json then receives the
+// article rendered by httpx.Render as a JSON:API document. A Document
+// value sets the top-level links, meta and included resources.
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/eriklott/httpx"
+)
+
+// MediaType is the JSON:API media type.
+const MediaType = "application/vnd.api+json"
+
+func init() {
+	httpx.RegisterCodec(MediaType, Codec{})
+}
+
+// Resource is a JSON:API resource object.
+type Resource struct {
+	Type          string                  `json:"type"`
+	ID            string                  `json:"id,omitempty"`
+	Attributes    interface{}             `json:"attributes,omitempty"`
+	Relationships map[string]Relationship `json:"relationships,omitempty"`
+	Links         map[string]string       `json:"links,omitempty"`
+	Meta          map[string]interface{}  `json:"meta,omitempty"`
+}
+
+// Identifier is a JSON:API resource identifier object.
+type Identifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relationship is a JSON:API relationship object. Its Data is an
+// Identifier, a slice of Identifiers, or nil for an empty to-one
+// relationship.
+type Relationship struct {
+	Data  interface{}       `json:"data"`
+	Links map[string]string `json:"links,omitempty"`
+}
+
+// ToOne returns a to-one relationship to the resource of typ and id,
+// which is empty when id is.
+func ToOne(typ, id string) Relationship {
+	if id == "" {
+		return Relationship{}
+	}
+	return Relationship{Data: Identifier{Type: typ, ID: id}}
+}
+
+// ToMany returns a to-many relationship to the resources of typ and
+// ids.
+func ToMany(typ string, ids ...string) Relationship {
+	data := make([]Identifier, len(ids))
+	for i, id := range ids {
+		data[i] = Identifier{Type: typ, ID: id}
+	}
+	return Relationship{Data: data}
+}
+
+// Document is a JSON:API top-level document. Its Data is a value of a
+// registered type, a slice of such values, or a Resource. The Included
+// values are serialized the same way.
+type Document struct {
+	Data     interface{}
+	Included []interface{}
+	Links    map[string]string
+	Meta     map[string]interface{}
+}
+
+// document is the wire format of a Document.
+type document struct {
+	Data     interface{}            `json:"data"`
+	Included []Resource             `json:"included,omitempty"`
+	Links    map[string]string      `json:"links,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+}
+
+// serializers holds the registered serializers by value type.
+var serializers sync.Map // map[reflect.Type]reflect.Value
+
+// Register registers fn as the serializer of the values of type T. It
+// replaces any serializer previously registered for T.
+func Register[T any](fn func(T) Resource) {
+	serializers.Store(reflect.TypeOf((*T)(nil)).Elem(), reflect.ValueOf(fn))
+}
+
+// Serialize returns the resource object of v, a Resource or a value of
+// a registered type.
+func Serialize(v interface{}) (Resource, error) {
+	switch r := v.(type) {
+	case Resource:
+		return r, nil
+	case *Resource:
+		return *r, nil
+	}
+	fn, ok := serializers.Load(reflect.TypeOf(v))
+	if !ok {
+		return Resource{}, fmt.Errorf("jsonapi: no serializer registered for %T", v)
+	}
+	out := fn.(reflect.Value).Call([]reflect.Value{reflect.ValueOf(v)})
+	return out[0].Interface().(Resource), nil
+}
+
+// Codec encodes values as JSON:API documents, and decodes the resource
+// object of a document.
+type Codec struct{}
+
+// Encode writes v to w as a JSON:API document. A v that is neither a
+// Document nor of a registered type, nor a slice of such values, is an
+// error.
+func (Codec) Encode(w io.Writer, v interface{}) error {
+	doc, ok := v.(Document)
+	if p, isPtr := v.(*Document); isPtr {
+		doc, ok = *p, true
+	}
+	if !ok {
+		doc = Document{Data: v}
+	}
+
+	out := document{Links: doc.Links, Meta: doc.Meta}
+	data, err := serializeData(doc.Data)
+	if err != nil {
+		return err
+	}
+	out.Data = data
+	for _, inc := range doc.Included {
+		res, err := Serialize(inc)
+		if err != nil {
+			return err
+		}
+		out.Included = append(out.Included, res)
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+// serializeData returns the primary data of a document holding v.
+func serializeData(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return Serialize(v)
+	}
+	resources := make([]Resource, rv.Len())
+	for i := range resources {
+		res, err := Serialize(rv.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		resources[i] = res
+	}
+	return resources, nil
+}
+
+// Decode reads a JSON:API document holding a single resource object
+// from r. When v is a *Resource, the resource object is stored in it;
+// otherwise the attributes of the resource are decoded into v.
+func (Codec) Decode(r io.Reader, v interface{}) error {
+	var doc struct {
+		Data *struct {
+			Type          string                  `json:"type"`
+			ID            string                  `json:"id"`
+			Attributes    json.RawMessage         `json:"attributes"`
+			Relationships map[string]Relationship `json:"relationships"`
+			Links         map[string]string       `json:"links"`
+			Meta          map[string]interface{}  `json:"meta"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	if doc.Data == nil {
+		return errors.New("jsonapi: document has no resource object")
+	}
+	if res, ok := v.(*Resource); ok {
+		*res = Resource{
+			Type:          doc.Data.Type,
+			ID:            doc.Data.ID,
+			Relationships: doc.Data.Relationships,
+			Links:         doc.Data.Links,
+			Meta:          doc.Data.Meta,
+		}
+		if len(doc.Data.Attributes) > 0 {
+			var attrs map[string]interface{}
+			if err := json.Unmarshal(doc.Data.Attributes, &attrs); err != nil {
+				return err
+			}
+			res.Attributes = attrs
+		}
+		return nil
+	}
+	if len(doc.Data.Attributes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(doc.Data.Attributes, v)
+}
@@ -0,0 +1,224 @@
+// Package jsonrpc serves JSON-RPC 2.0 over HTTP. Methods are Go
+// functions registered by name, and a Server is mounted as a single
+// httpx.Handler:
+//
+//	rpc := jsonrpc.NewServer()
+//	rpc.Register("user.get", func(ctx context.Context, p GetUserParams) (*User, error) {
+//		return users.Get(ctx, p.ID)
+//	})
+//	mux.Post("/rpc", rpc.ServeHTTP)
+//
+// Batch requests and notifications are supported. Errors returned by
+// methods are mapped to JSON-RPC error objects: an *Error is sent as
+// is, an httpx.StatusError with code ServerError and its status in the
+// error data, and any other error as an InternalError whose message
+// is not disclosed.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/eriklott/httpx"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+
+	// ServerError is the code of the errors of methods that return an
+	// httpx.StatusError. Codes from -32000 to -32099 are reserved for
+	// implementation defined server errors.
+	ServerError = -32000
+)
+
+// Error is a JSON-RPC error object. Methods return an *Error to choose
+// the code and data of their errors.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code)
+}
+
+// Errorf returns an *Error with code and a formatted message.
+func Errorf(code int, format string, v ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, v...)}
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// method is a registered method function.
+type method struct {
+	fn     reflect.Value
+	params reflect.Type // nil when the method takes no params
+}
+
+// Server dispatches JSON-RPC requests to registered methods. A Server
+// must be created with NewServer.
+type Server struct {
+	mu      sync.RWMutex
+	methods map[string]method
+}
+
+// NewServer returns a newly initialized Server.
+func NewServer() *Server {
+	return &Server{methods: map[string]method{}}
+}
+
+// Register registers fn as the method name. The fn must be a function
+// of one of the forms
+//
+//	func(ctx context.Context, params P) (R, error)
+//	func(ctx context.Context) (R, error)
+//
+// where the params of a request are decoded into P, and the result R
+// is encoded as JSON. Register panics when fn has another form.
+func (s *Server) Register(name string, fn interface{}) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() < 1 || t.NumIn() > 2 || t.In(0) != contextType ||
+		t.NumOut() != 2 || t.Out(1) != errorType {
+		panic(fmt.Sprintf("jsonrpc: method %q has an invalid signature %s", name, t))
+	}
+	m := method{fn: v}
+	if t.NumIn() == 2 {
+		m.params = t.In(1)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[name] = m
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// ServeHTTP implements httpx.Handler. Requests must be POSTed as JSON.
+// A request holding only notifications is answered with 204 No Content.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return httpx.Error(http.StatusMethodNotAllowed, "JSON-RPC requests must use POST")
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return httpx.JSON(w, http.StatusOK, errorResponse(nil, &Error{Code: ParseError, Message: "parse error"}))
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil || len(batch) == 0 {
+			return httpx.JSON(w, http.StatusOK, errorResponse(nil, &Error{Code: InvalidRequest, Message: "invalid request"}))
+		}
+		resps := make([]*response, 0, len(batch))
+		for _, msg := range batch {
+			if resp := s.call(r.Context(), msg); resp != nil {
+				resps = append(resps, resp)
+			}
+		}
+		if len(resps) == 0 {
+			return httpx.NoContent(w)
+		}
+		return httpx.JSON(w, http.StatusOK, resps)
+	}
+
+	resp := s.call(r.Context(), raw)
+	if resp == nil {
+		return httpx.NoContent(w)
+	}
+	return httpx.JSON(w, http.StatusOK, resp)
+}
+
+// call runs a single request, and returns its response, or nil for a
+// notification.
+func (s *Server) call(ctx context.Context, msg json.RawMessage) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &Error{Code: InvalidRequest, Message: "invalid request"})
+	}
+
+	result, err := s.invoke(ctx, req)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, toError(err))
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, &Error{Code: InternalError, Message: "internal error"})
+	}
+	return &response{JSONRPC: "2.0", Result: b, ID: req.ID}
+}
+
+func (s *Server) invoke(ctx context.Context, req request) (interface{}, error) {
+	s.mu.RLock()
+	m, ok := s.methods[req.Method]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, Errorf(MethodNotFound, "method %q not found", req.Method)
+	}
+
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	if m.params != nil {
+		p := reflect.New(m.params)
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, p.Interface()); err != nil {
+				return nil, &Error{Code: InvalidParams, Message: "invalid params", Data: err.Error()}
+			}
+		}
+		args = append(args, p.Elem())
+	}
+
+	out := m.fn.Call(args)
+	if err, _ := out[1].Interface().(error); err != nil {
+		return nil, err
+	}
+	return out[0].Interface(), nil
+}
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", Error: err, ID: id}
+}
+
+// toError maps an error returned by a method to an *Error.
+func toError(err error) *Error {
+	switch e := err.(type) {
+	case *Error:
+		return e
+	case httpx.StatusError:
+		return &Error{Code: ServerError, Message: e.Error(), Data: map[string]int{"status": e.Status()}}
+	}
+	return &Error{Code: InternalError, Message: "internal error"}
+}
@@ -0,0 +1,119 @@
+package httpx
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits bounds the resources a listener of a Server gives to clients.
+// The zero value of each field selects a default suited to a service
+// exposed to the internet; a negative timeout disables it.
+type Limits struct {
+	// ReadHeaderTimeout is the time allowed to read the request
+	// headers. The default is 10 seconds.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is the time allowed to read a request, including its
+	// body. The default is no timeout, so that uploads are bounded by
+	// the handlers instead.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the time allowed to write a response. The default
+	// is no timeout, so that streamed responses and long polls work.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the time a keep-alive connection waits for the
+	// next request. The default is 2 minutes.
+	IdleTimeout time.Duration
+
+	// MaxHeaderBytes is the size of the largest request headers. The
+	// default is 64 KiB.
+	MaxHeaderBytes int
+
+	// MaxConns is the number of connections served at once. Further
+	// connections wait in the listen backlog until one is closed. The
+	// default is no limit.
+	MaxConns int
+}
+
+// apply configures hs with the limits, filling in the defaults.
+func (l Limits) apply(hs *http.Server) {
+	hs.ReadHeaderTimeout = timeout(l.ReadHeaderTimeout, 10*time.Second)
+	hs.ReadTimeout = timeout(l.ReadTimeout, 0)
+	hs.WriteTimeout = timeout(l.WriteTimeout, 0)
+	hs.IdleTimeout = timeout(l.IdleTimeout, 2*time.Minute)
+	hs.MaxHeaderBytes = l.MaxHeaderBytes
+	if hs.MaxHeaderBytes <= 0 {
+		hs.MaxHeaderBytes = 64 << 10
+	}
+}
+
+func timeout(d, def time.Duration) time.Duration {
+	switch {
+	case d < 0:
+		return 0
+	case d == 0:
+		return def
+	}
+	return d
+}
+
+// SetLimits configures the limits of the Server listener. The listeners
+// added with Listen have limits of their own.
+func (s *Server) SetLimits(l Limits) {
+	l.apply(s.hs)
+	s.maxConns = l.MaxConns
+}
+
+// limitListener returns l accepting at most n connections at once, or
+// l itself when n isn't positive.
+func limitListener(l net.Listener, n int) net.Listener {
+	if n <= 0 {
+		return l
+	}
+	return &limitedListener{Listener: l, sem: make(chan struct{}, n), done: make(chan struct{})}
+}
+
+// limitedListener is a listener accepting a bounded number of
+// connections at once.
+type limitedListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *limitedListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitedConn{Conn: c, release: func() { <-l.sem }}, nil
+}
+
+func (l *limitedListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+// limitedConn releases its slot of a limitedListener when closed.
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
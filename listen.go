@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation.
+const listenFDsStart = 3
+
+// ListenUnix listens on the unix domain socket at path, removing a
+// stale socket file left by a previous process first. The socket file
+// is given the permission bits of mode, so that a local proxy running
+// as another user can connect, and is removed when the listener is
+// closed.
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("httpx: %s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// ListenAndServeUnix listens on the unix domain socket at path, created
+// with the permission bits of mode, and serves requests until the
+// Server is shut down.
+func (s *Server) ListenAndServeUnix(path string, mode os.FileMode) error {
+	l, err := ListenUnix(path, mode)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// SystemdListeners returns the listeners passed to the process by
+// systemd socket activation, in the order of the socket unit's Listen
+// directives. It returns no listeners when the process was not socket
+// activated. The activation environment variables are unset so that
+// child processes don't inherit the listeners.
+func SystemdListeners() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("httpx: systemd fd %d: %w", fd, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// ServeSystemd serves requests on the first listener passed by systemd
+// socket activation until the Server is shut down.
+func (s *Server) ServeSystemd() error {
+	listeners, err := SystemdListeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		return errors.New("httpx: no systemd socket activation listeners")
+	}
+	for _, l := range listeners[1:] {
+		l.Close()
+	}
+	return s.Serve(listeners[0])
+}
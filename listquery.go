@@ -0,0 +1,139 @@
+package httpx
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Filter operators of a ListQuery.
+const (
+	OpEq  = "eq"
+	OpNe  = "ne"
+	OpLt  = "lt"
+	OpLte = "lte"
+	OpGt  = "gt"
+	OpGte = "gte"
+	OpIn  = "in"
+)
+
+var filterOps = map[string]bool{OpEq: true, OpNe: true, OpLt: true, OpLte: true, OpGt: true, OpGte: true, OpIn: true}
+
+// SortField is a field a collection is sorted by.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is a condition on a field of a collection.
+type Filter struct {
+	Field string
+	Op    string
+
+	// Values holds the operand of the filter. Only the in operator has
+	// more than one value.
+	Values []string
+}
+
+// ListQuery is the sorting and filtering requested for a collection.
+type ListQuery struct {
+	Sort    []SortField
+	Filters []Filter
+}
+
+// ListSpec whitelists the fields a route can sort and filter by.
+type ListSpec struct {
+	// Sortable lists the fields the collection can be sorted by.
+	Sortable []string
+
+	// Filterable lists the fields the collection can be filtered by.
+	Filterable []string
+
+	// DefaultSort is used when the request sets no sort parameter, in
+	// the same syntax, such as "-created_at".
+	DefaultSort string
+}
+
+// ParseListQuery parses the sort and filter query parameters of the
+// request against spec:
+//
+//	?sort=-created_at,name&filter[status]=active&filter[age][gte]=18&filter[role][in]=admin,owner
+//
+// Sort fields are comma separated, with a leading "-" for descending
+// order. Filters are written filter[field]=value for equality, or
+// filter[field][op]=value with one of the eq, ne, lt, lte, gt, gte and
+// in operators; the values of in are comma separated. Filters are
+// returned sorted by field and operator.
+//
+// A field that is not whitelisted, an unknown operator or a malformed
+// parameter is reported as a 400 Bad Request StatusError.
+func ParseListQuery(r *http.Request, spec ListSpec) (ListQuery, error) {
+	var lq ListQuery
+	query := r.URL.Query()
+
+	sortParam := query.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	for _, f := range strings.Split(sortParam, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		sf := SortField{Field: strings.TrimPrefix(f, "-"), Desc: strings.HasPrefix(f, "-")}
+		if !contains(spec.Sortable, sf.Field) {
+			return ListQuery{}, Errorf(http.StatusBadRequest, "cannot sort by %q", sf.Field)
+		}
+		lq.Sort = append(lq.Sort, sf)
+	}
+
+	for key, vals := range query {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		path, err := parseFormKey(key)
+		if err != nil || len(path) < 2 || len(path) > 3 {
+			return ListQuery{}, Errorf(http.StatusBadRequest, "malformed filter %q", key)
+		}
+		f := Filter{Field: path[1], Op: OpEq}
+		if len(path) == 3 {
+			f.Op = path[2]
+		}
+		if !contains(spec.Filterable, f.Field) {
+			return ListQuery{}, Errorf(http.StatusBadRequest, "cannot filter by %q", f.Field)
+		}
+		if !filterOps[f.Op] {
+			return ListQuery{}, Errorf(http.StatusBadRequest, "unknown filter operator %q", f.Op)
+		}
+		if f.Op == OpIn {
+			f.Values = QueryStrings(r, key)
+		} else {
+			f.Values = vals[:1]
+		}
+		lq.Filters = append(lq.Filters, f)
+	}
+	sort.Slice(lq.Filters, func(i, j int) bool {
+		a, b := lq.Filters[i], lq.Filters[j]
+		return a.Field < b.Field || (a.Field == b.Field && a.Op < b.Op)
+	})
+	return lq, nil
+}
+
+// Filter returns the first filter on field, if any.
+func (lq ListQuery) Filter(field string) (Filter, bool) {
+	for _, f := range lq.Filters {
+		if f.Field == field {
+			return f, true
+		}
+	}
+	return Filter{}, false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// A Translator translates messages into a locale. It is installed for a
+// request with WithTranslator, by the middleware of the httpx/i18n
+// package.
+type Translator interface {
+	// Locale returns the BCP 47 tag of the locale, such as "fr-CA".
+	Locale() string
+
+	// Translate returns the message of key, formatted with args, and
+	// false when the locale has no message for key.
+	Translate(key string, args ...interface{}) (string, bool)
+}
+
+type translatorKey struct{}
+
+// WithTranslator returns a shallow copy of r with t set as its
+// Translator.
+func WithTranslator(r *http.Request, t Translator) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), translatorKey{}, t))
+}
+
+// TranslatorFrom returns the Translator of the request.
+func TranslatorFrom(r *http.Request) (Translator, bool) {
+	t, ok := r.Context().Value(translatorKey{}).(Translator)
+	return t, ok
+}
+
+// Locale returns the locale negotiated for the request, or "" when the
+// request has no Translator.
+func Locale(r *http.Request) string {
+	if t, ok := TranslatorFrom(r); ok {
+		return t.Locale()
+	}
+	return ""
+}
+
+// T translates the message of key into the locale of the request,
+// formatted with args as by fmt.Sprintf. Untranslated keys are
+// formatted themselves, so that the key can be the message in the
+// default language:
+//
+//	msg := httpx.T(r, "%d items in your cart", n)
+func T(r *http.Request, key string, args ...interface{}) string {
+	if t, ok := TranslatorFrom(r); ok {
+		if msg, ok := t.Translate(key, args...); ok {
+			return msg
+		}
+	}
+	return formatMessage(key, args)
+}
+
+// formatMessage formats msg with args, taken as a slice so that vet
+// doesn't mistake T for a printf wrapper and flag message keys.
+func formatMessage(msg string, args []interface{}) string {
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,257 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A LockoutStore counts the consecutive failed logins of a key, a user
+// name and client address pair. Attempts are reserved with Attempt
+// before the login runs, and settled with Fail, Release or Reset once
+// its outcome is known, so that concurrent attempts count against the
+// threshold too. Stores must be safe for concurrent use; a store shared
+// by several instances of a service, such as one backed by Redis, locks
+// attackers out of all of them.
+type LockoutStore interface {
+	// Attempt returns the state of key, and atomically adds a pending
+	// attempt to it.
+	Attempt(ctx context.Context, key string) (LockoutState, error)
+
+	// Fail settles a pending attempt of key as a failure.
+	Fail(ctx context.Context, key string) error
+
+	// Release settles a pending attempt of key that neither failed nor
+	// succeeded, such as a malformed or rejected login.
+	Release(ctx context.Context, key string) error
+
+	// Reset settles a pending attempt of key as a successful login, and
+	// forgets the failures of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// LockoutState is the state of a key of a LockoutStore.
+type LockoutState struct {
+	// Failures is the number of consecutive failures of the key.
+	Failures int
+
+	// Pending is the number of attempts of the key in progress.
+	Pending int
+
+	// Last is the time of the last failure.
+	Last time.Time
+}
+
+// NewMemoryLockoutStore returns a LockoutStore keeping the failures in
+// memory, forgotten ttl after the last one.
+func NewMemoryLockoutStore(ttl time.Duration) LockoutStore {
+	return &memoryLockoutStore{ttl: ttl, entries: map[string]*LockoutState{}}
+}
+
+type memoryLockoutStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*LockoutState
+	sweep   time.Time
+}
+
+func (s *memoryLockoutStore) Attempt(ctx context.Context, key string) (LockoutState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.sweep) > s.ttl {
+		for k, e := range s.entries {
+			if e.Pending == 0 && now.Sub(e.Last) > s.ttl {
+				delete(s.entries, k)
+			}
+		}
+		s.sweep = now
+	}
+	e, ok := s.entries[key]
+	if !ok {
+		e = &LockoutState{}
+		s.entries[key] = e
+	}
+	if now.Sub(e.Last) > s.ttl {
+		e.Failures = 0
+	}
+	st := *e
+	e.Pending++
+	return st, nil
+}
+
+func (s *memoryLockoutStore) Fail(ctx context.Context, key string) error {
+	return s.settle(key, func(e *LockoutState) {
+		e.Failures++
+		e.Last = time.Now()
+	})
+}
+
+func (s *memoryLockoutStore) Release(ctx context.Context, key string) error {
+	return s.settle(key, func(e *LockoutState) {})
+}
+
+func (s *memoryLockoutStore) Reset(ctx context.Context, key string) error {
+	return s.settle(key, func(e *LockoutState) {
+		e.Failures = 0
+		e.Last = time.Time{}
+	})
+}
+
+// settle settles a pending attempt of key with fn, dropping the key
+// once it holds no failures and no pending attempts.
+func (s *memoryLockoutStore) settle(key string, fn func(e *LockoutState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &LockoutState{Pending: 1}
+		s.entries[key] = e
+	}
+	if e.Pending > 0 {
+		e.Pending--
+	}
+	fn(e)
+	if e.Failures == 0 && e.Pending == 0 {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// LockoutOptions configures the LoginLockout middleware.
+type LockoutOptions struct {
+	// Store counts the failures. The default is a memory store
+	// forgetting failures after MaxDelay.
+	Store LockoutStore
+
+	// Username returns the user name of a login request. The default is
+	// the "username" form value.
+	Username func(r *http.Request) string
+
+	// Threshold is the number of failures allowed before logins are
+	// delayed. The default is 5.
+	Threshold int
+
+	// BaseDelay is the delay imposed after Threshold failures. It
+	// doubles with each further failure. The default is 1 second.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay. The default is 15 minutes.
+	MaxDelay time.Duration
+
+	// Sink, when set, receives an AuditEvent for each failed and each
+	// rejected login, with the login.failure and login.locked actions.
+	Sink AuditSink
+}
+
+// LoginLockout is a middleware for login routes that slows down
+// password guessing. Once a user name has failed to log in opts.Threshold
+// times in a row from a client address, further logins are rejected
+// with a 429 Too Many Requests StatusError and a Retry-After header
+// until a delay has passed, which doubles with each failure. A
+// successful login resets the count. Logins in progress count as
+// failures until they complete, so that concurrent guesses can't get
+// past the threshold.
+//
+// The outcome of a login is derived from its response status: 401
+// Unauthorized and 403 Forbidden are failures, 2xx and 3xx statuses
+// successes. Unlike a rate limit, only failures count, so legitimate
+// users are unaffected by the attempts of others.
+func LoginLockout(opts LockoutOptions) Middleware {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 5
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 15 * time.Minute
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryLockoutStore(opts.MaxDelay)
+	}
+	if opts.Username == nil {
+		opts.Username = func(r *http.Request) string { return r.PostFormValue("username") }
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			user := opts.Username(r)
+			key := user + "|" + ClientIP(r).String()
+
+			st, err := opts.Store.Attempt(r.Context(), key)
+			if err != nil {
+				return err
+			}
+			if n := st.Failures + st.Pending; n >= opts.Threshold {
+				delay := opts.BaseDelay << uint(min(n-opts.Threshold, 30))
+				if delay <= 0 || delay > opts.MaxDelay {
+					delay = opts.MaxDelay
+				}
+				last := st.Last
+				if st.Pending > 0 {
+					// Attempts in progress count as failures from now.
+					last = time.Now()
+				}
+				if wait := time.Until(last.Add(delay)); wait > 0 {
+					settleLockout(r, opts.Store.Release(r.Context(), key))
+					lockoutEvent(opts.Sink, r, user, "login.locked", http.StatusTooManyRequests)
+					w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+					return securityError(r, SecurityThrottled, http.StatusTooManyRequests, "too many failed logins")
+				}
+			}
+
+			rw := wrapWriter(w)
+			settled := false
+			defer func() {
+				if !settled {
+					settleLockout(r, opts.Store.Release(r.Context(), key))
+				}
+			}()
+			err = next.ServeHTTP(rw, r)
+			settled = true
+			switch status := statusOf(r, rw, err); {
+			case status == http.StatusUnauthorized || status == http.StatusForbidden:
+				settleLockout(r, opts.Store.Fail(r.Context(), key))
+				lockoutEvent(opts.Sink, r, user, "login.failure", status)
+				ReportSecurityEvent(r, SecurityAuthFailure, "login failed for "+strconv.Quote(user))
+			case status < 400:
+				settleLockout(r, opts.Store.Reset(r.Context(), key))
+			default:
+				settleLockout(r, opts.Store.Release(r.Context(), key))
+			}
+			return err
+		})
+	}
+}
+
+// settleLockout logs the error of a LockoutStore settling an attempt.
+func settleLockout(r *http.Request, err error) {
+	if err != nil {
+		LoggerFrom(r).ErrorContext(r.Context(), "login lockout store failed", "error", err.Error())
+	}
+}
+
+func lockoutEvent(sink AuditSink, r *http.Request, user, action string, status int) {
+	if sink == nil {
+		return
+	}
+	event := AuditEvent{
+		Time:    time.Now(),
+		Actor:   user,
+		Action:  action,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Status:  status,
+		Outcome: AuditDenied,
+	}
+	if action == "login.failure" {
+		event.Outcome = AuditFailure
+	}
+	if ri, ok := CurrentRoute(r); ok {
+		event.Route = ri.Pattern
+	}
+	sink.Audit(r.Context(), event)
+}
@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func lockoutRequest(password string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(url.Values{"username": {"ada"}, "password": {password}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// TestLoginLockoutConcurrent checks that parallel guesses get no more
+// tries than the threshold.
+func TestLoginLockoutConcurrent(t *testing.T) {
+	var tries atomic.Int64
+	release := make(chan struct{})
+	m := NewMux()
+	m.Post("/login", func(w http.ResponseWriter, r *http.Request) error {
+		tries.Add(1)
+		<-release
+		return Error(http.StatusUnauthorized, "wrong password")
+	}, WithMiddleware(NewChain(LoginLockout(LockoutOptions{Threshold: 3}))))
+
+	var wg sync.WaitGroup
+	var locked atomic.Int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, lockoutRequest("guess"))
+			if rec.Code == http.StatusTooManyRequests {
+				locked.Add(1)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := tries.Load(); n != 3 {
+		t.Errorf("%d guesses reached the handler, want 3", n)
+	}
+	if n := locked.Load(); n != 7 {
+		t.Errorf("%d guesses were locked out, want 7", n)
+	}
+}
+
+// TestLoginLockoutReset checks that a successful login resets the count
+// of failures.
+func TestLoginLockoutReset(t *testing.T) {
+	m := NewMux()
+	m.Post("/login", func(w http.ResponseWriter, r *http.Request) error {
+		if r.PostFormValue("password") != "right" {
+			return Error(http.StatusUnauthorized, "wrong password")
+		}
+		return nil
+	}, WithMiddleware(NewChain(LoginLockout(LockoutOptions{Threshold: 2}))))
+
+	serve := func(password string) int {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, lockoutRequest(password))
+		return rec.Code
+	}
+	for _, step := range []struct {
+		password string
+		status   int
+	}{
+		{"wrong", 401},
+		{"right", 200},
+		{"wrong", 401},
+		{"wrong", 401},
+		{"right", 429},
+	} {
+		if got := serve(step.password); got != step.status {
+			t.Errorf("login with %s: status %d, want %d", step.password, got, step.status)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+// LoggerOptions configures the Logger middleware.
+type LoggerOptions struct {
+	// RequestIDHeader is the header carrying the ID of a request. An ID
+	// received from the client, or an upstream proxy, is kept; otherwise
+	// a random ID is generated. The ID is echoed in the response header.
+	// The default is X-Request-Id.
+	RequestIDHeader string
+
+	// User, when set, returns attributes identifying the user making a
+	// request, such as claims set on the request context by an auth
+	// middleware. It returns nil for anonymous requests.
+	User func(r *http.Request) []slog.Attr
+
+	// Quiet disables the log record emitted for each request once it
+	// has been handled.
+	Quiet bool
+}
+
+// Logger is a middleware that installs a request-scoped logger, derived
+// from base, for the next handler. The logger carries the request ID,
+// the method and path, the pattern of the route, and the user
+// attributes of the request. Handlers get it with LoggerFrom:
+//
+//	httpx.LoggerFrom(r).Info("user updated", "user", u.ID)
+//
+// Unless opts.Quiet is set, each request is logged once handled, at the
+// Error level for 5xx responses and the Info level otherwise.
+func Logger(base *slog.Logger, opts LoggerOptions) Middleware {
+	if opts.RequestIDHeader == "" {
+		opts.RequestIDHeader = "X-Request-Id"
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			start := time.Now()
+			id := r.Header.Get(opts.RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(opts.RequestIDHeader, id)
+
+			attrs := []interface{}{
+				slog.String("request_id", id),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			}
+			if ri, ok := CurrentRoute(r); ok {
+				attrs = append(attrs, slog.String("route", ri.Pattern))
+			}
+			if opts.User != nil {
+				if user := opts.User(r); len(user) > 0 {
+					args := make([]interface{}, len(user))
+					for i, a := range user {
+						args[i] = a
+					}
+					attrs = append(attrs, slog.Group("user", args...))
+				}
+			}
+			logger := base.With(attrs...)
+
+			ctx := context.WithValue(r.Context(), loggerKey{}, logger)
+			ctx = context.WithValue(ctx, requestIDKey{}, id)
+			rw := wrapWriter(w)
+			err := next.ServeHTTP(rw, r.WithContext(ctx))
+			if opts.Quiet {
+				return err
+			}
+
+			status := statusOf(r, rw, err)
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			args := []interface{}{
+				slog.Int("status", status),
+				slog.Int64("bytes", rw.written),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				args = append(args, slog.String("error", err.Error()))
+			}
+			logger.Log(ctx, level, "request", args...)
+			return err
+		})
+	}
+}
+
+// LoggerFrom returns the request-scoped logger installed by the Logger
+// middleware, or slog.Default when there is none.
+func LoggerFrom(r *http.Request) *slog.Logger {
+	if l, ok := r.Context().Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// RequestID returns the ID of the request assigned by the Logger
+// middleware, or "" when there is none.
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether a request ID received in a header is
+// safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [12]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
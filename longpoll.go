@@ -0,0 +1,34 @@
+package httpx
+
+import (
+	"net/http"
+	"time"
+)
+
+// LongPoll waits up to wait for a value from source and writes it to
+// the response with encode, or as JSON when encode is nil. When no
+// value arrives in time, or source is closed, it responds with 204 No
+// Content so the client polls again. When the client goes away,
+// LongPoll returns the error of the request context.
+//
+//	return httpx.LongPoll(w, r, 30*time.Second, events.Subscribe(r.Context()), nil)
+func LongPoll[T any](w http.ResponseWriter, r *http.Request, wait time.Duration, source <-chan T, encode func(w http.ResponseWriter, v T) error) error {
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case v, ok := <-source:
+		if !ok {
+			return NoContent(w)
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		if encode == nil {
+			return JSON(w, http.StatusOK, v)
+		}
+		return encode(w, v)
+	case <-t.C:
+		return NoContent(w)
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
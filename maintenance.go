@@ -0,0 +1,65 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Maintenance is a middleware that short-circuits requests to handler
+// while on is set. A nil handler responds with MaintenanceHandler using
+// a one minute Retry-After. Requests for the exclude paths, such as
+// health checks, are always passed to the next handler.
+//
+// The flag can be toggled at runtime, for example by an admin route
+// served by MaintenanceToggle.
+func Maintenance(on *atomic.Bool, handler Handler, exclude ...string) Middleware {
+	if handler == nil {
+		handler = MaintenanceHandler(time.Minute)
+	}
+	excluded := make(map[string]bool, len(exclude))
+	for _, path := range exclude {
+		excluded[path] = true
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if on.Load() && !excluded[r.URL.Path] {
+				return handler.ServeHTTP(w, r)
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaintenanceHandler returns a handler that returns an Unavailable
+// error with a Retry-After of retryAfter.
+func MaintenanceHandler(retryAfter time.Duration) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return Unavailable(retryAfter, "service under maintenance")
+	})
+}
+
+// MaintenanceToggle returns an admin handler for the maintenance flag.
+// A POST or PUT request sets the flag, a DELETE request clears it, and
+// any request responds with the resulting state as JSON:
+//
+//	admin.Handle("/maintenance", httpx.MaintenanceToggle(&flag))
+func MaintenanceToggle(on *atomic.Bool) Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodPut:
+			on.Store(true)
+		case http.MethodDelete:
+			on.Store(false)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST, PUT, DELETE")
+			return Error(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, "{\"maintenance\":%t}\n", on.Load())
+		return err
+	})
+}
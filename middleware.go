@@ -1,5 +1,10 @@
 package httpx
 
+import (
+	"reflect"
+	"runtime"
+)
+
 // Middleware for a piece of middleware.
 // Some middleware use this middleware out of the box,
 // so in most cases you can just pass somepackage.New
@@ -11,6 +16,7 @@ type Middleware func(Handler) Handler
 // the same set of middlewares in the same order.
 type Chain struct {
 	middlewares []Middleware
+	names       []string
 }
 
 // NewChain creates a new chain,
@@ -19,9 +25,35 @@ type Chain struct {
 // middlewares are only called upon a call to Then().
 func NewChain(middlewares ...Middleware) Chain {
 	if len(middlewares) == 0 {
-		return Chain{middlewares: []Middleware{}}
+		return Chain{middlewares: []Middleware{}, names: []string{}}
 	}
-	return Chain{middlewares: middlewares}
+	return Chain{middlewares: middlewares, names: make([]string, len(middlewares))}
+}
+
+// NewNamed creates a new chain holding a single middleware labelled
+// with name. Named chains can be combined with Extend, and the labels
+// are reported by Middlewares and by Mux.Routes.
+//
+//	auth := httpx.NewNamed("auth", authMW)
+//	stdChain := httpx.NewChain(m1).Extend(auth)
+//	stdChain.Middlewares() // ["...m1", "auth"]
+func NewNamed(name string, middleware Middleware) Chain {
+	return Chain{middlewares: []Middleware{middleware}, names: []string{name}}
+}
+
+// Middlewares returns the names of the chain's middlewares in the order
+// a request passes through them. Middlewares added without a name are
+// reported by the name of their function.
+func (c Chain) Middlewares() []string {
+	names := make([]string, len(c.middlewares))
+	for i, m := range c.middlewares {
+		if i < len(c.names) && c.names[i] != "" {
+			names[i] = c.names[i]
+			continue
+		}
+		names[i] = funcName(m)
+	}
+	return names
 }
 
 // Then chains the middleware and returns the final Handler.
@@ -79,7 +111,9 @@ func (c Chain) Append(middlewares ...Middleware) Chain {
 	newCons := make([]Middleware, 0, len(c.middlewares)+len(middlewares))
 	newCons = append(newCons, c.middlewares...)
 	newCons = append(newCons, middlewares...)
-	return Chain{newCons}
+	newNames := make([]string, len(newCons))
+	copy(newNames, c.names)
+	return Chain{newCons, newNames}
 }
 
 // Extend extends a chain by adding the specified chain
@@ -104,5 +138,18 @@ func (c Chain) Append(middlewares ...Middleware) Chain {
 //		// requests to aHtml hitting nosurfs success handler go m1 -> nosurf -> m2 -> target-handler
 //		// requests to aHtml hitting nosurfs failure handler go m1 -> nosurf -> m2 -> csrfFail
 func (c Chain) Extend(chain Chain) Chain {
-	return c.Append(chain.middlewares...)
+	ext := c.Append(chain.middlewares...)
+	copy(ext.names[len(c.middlewares):], chain.names)
+	return ext
+}
+
+// funcName returns the name of the function backing a middleware.
+func funcName(m Middleware) string {
+	if m == nil {
+		return "<nil>"
+	}
+	if fn := runtime.FuncForPC(reflect.ValueOf(m).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return "<unknown>"
 }
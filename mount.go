@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Mount routes the requests for pattern and its subpaths to h, a
+// standard http.Handler such as a chi router, with pattern stripped
+// from the request path. Existing subrouters keep working unchanged
+// behind the Mux middleware stack:
+//
+//	legacy := chi.NewRouter()
+//	legacy.Get("/users/{id}", getUser)
+//	mux.Mount("/legacy", legacy) // serves /legacy/users/{id}
+func (m *Mux) Mount(pattern string, h http.Handler, opts ...RouteOption) {
+	pattern = strings.TrimSuffix(pattern, "/")
+	mounted := HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		h.ServeHTTP(w, mountRequest(r, URLParam(r, "*")))
+		return nil
+	})
+	if pattern != "" {
+		m.Handle(pattern, mounted, opts...)
+		opts = []RouteOption{subtreeOptions(opts)}
+	}
+	m.Handle(pattern+"/*", mounted, opts...)
+}
+
+// subtreeOptions returns a RouteOption applying opts to the subtree
+// route of a mount, but for the route name, which names the route of
+// the mount pattern.
+func subtreeOptions(opts []RouteOption) RouteOption {
+	return func(ro *routeOptions) {
+		name := ro.name
+		for _, opt := range opts {
+			opt(ro)
+		}
+		ro.name = name
+	}
+}
+
+// mountRequest returns a shallow copy of r with the path set to rest,
+// the part of the path matched by the wildcard of a mount pattern.
+func mountRequest(r *http.Request, rest string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	u := new(url.URL)
+	*u = *r.URL
+	if r.URL.RawPath != "" {
+		u.RawPath = "/" + rest
+		if p, err := url.PathUnescape(u.RawPath); err == nil {
+			u.Path = p
+		}
+	} else {
+		u.Path = "/" + rest
+	}
+	r2.URL = u
+	return r2
+}
+
+type errSlotKey struct{}
+
+// HTTPMiddleware adapts a standard middleware, such as one written for
+// chi, to a Middleware. The error returned by the next handler is
+// passed through the standard middleware and returned. The middleware
+// is built once, so middlewares keeping state across requests, such as
+// throttlers, behave as they do in a standard stack.
+func HTTPMiddleware(mw func(http.Handler) http.Handler) Middleware {
+	return func(next Handler) Handler {
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slot, ok := r.Context().Value(errSlotKey{}).(*error); ok {
+				*slot = next.ServeHTTP(w, r)
+			}
+		}))
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			var err error
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errSlotKey{}, &err)))
+			return err
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	type seen struct{ path, rawPath, escaped string }
+	var got *seen
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = &seen{r.URL.Path, r.URL.RawPath, r.URL.EscapedPath()}
+	})
+	m := NewMux()
+	m.Mount("/legacy/", h)
+	m.Mount("/files", h)
+	m.Get("/other", func(w http.ResponseWriter, r *http.Request) error { return nil })
+
+	tests := []struct {
+		target string
+		want   *seen
+	}{
+		{"/legacy", &seen{"/", "", "/"}},
+		{"/legacy/", &seen{"/", "", "/"}},
+		{"/legacy/users/42", &seen{"/users/42", "", "/users/42"}},
+		{"/legacy/users/42?x=1", &seen{"/users/42", "", "/users/42"}},
+		{"/files/a%20b.txt", &seen{"/a b.txt", "", "/a%20b.txt"}},
+		{"/files/docs%2Freport/v1", &seen{"/docs/report/v1", "/docs%2Freport/v1", "/docs%2Freport/v1"}},
+		{"/other", nil},
+		{"/legacyx", nil},
+	}
+	for _, tt := range tests {
+		got = nil
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		path := r.URL.Path
+		m.ServeHTTP(httptest.NewRecorder(), r)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("%s: mounted handler called with %+v", tt.target, *got)
+		case tt.want != nil && got == nil:
+			t.Errorf("%s: mounted handler not called", tt.target)
+		case tt.want != nil && *got != *tt.want:
+			t.Errorf("%s: mounted handler saw %+v, want %+v", tt.target, *got, *tt.want)
+		}
+		if r.URL.Path != path {
+			t.Errorf("%s: request path changed to %q", tt.target, r.URL.Path)
+		}
+	}
+}
+
+// TestMountMiddleware checks that the middlewares of the Mux run for
+// mounted handlers, and see the unstripped path.
+func TestMountMiddleware(t *testing.T) {
+	var paths []string
+	m := NewMux()
+	m.Use(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			paths = append(paths, r.URL.Path)
+			return next.ServeHTTP(w, r)
+		})
+	})
+	m.Mount("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a/b", nil))
+	if len(paths) != 2 || paths[0] != "/a/b" || paths[1] != "/a/b" {
+		t.Errorf("paths %q", paths)
+	}
+}
+
+type ctxKey struct{}
+
+// TestHTTPMiddleware checks that a standard middleware wraps the next
+// handler, and that the error of the handler passes through it.
+func TestHTTPMiddleware(t *testing.T) {
+	std := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Block") != "" {
+				http.Error(w, "blocked", http.StatusTeapot)
+				return
+			}
+			w.Header().Set("X-Std", "1")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, "from std")))
+		})
+	}
+	m := NewMux()
+	m.With(HTTPMiddleware(std)).Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		if v, _ := r.Context().Value(ctxKey{}).(string); v != "from std" {
+			t.Errorf("context value %q", v)
+		}
+		return Error(http.StatusConflict, "conflict")
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusConflict || rec.Header().Get("X-Std") != "1" {
+		t.Errorf("status %d, headers %v", rec.Code, rec.Header())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Block", "1")
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, r)
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("blocked: status %d", rec.Code)
+	}
+
+	err := HTTPMiddleware(std)(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return Error(http.StatusConflict, "conflict")
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if se, ok := err.(StatusError); !ok || se.Status() != http.StatusConflict {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+// Package msgpack registers a MessagePack codec with httpx, so that
+// httpx.Render and httpx.Bind handle the application/msgpack and
+// application/x-msgpack media types. It is imported for its side
+// effect:
+//
+//	import _ "github.com/eriklott/httpx/msgpack"
+package msgpack
+
+import (
+	"io"
+
+	"github.com/eriklott/httpx"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func init() {
+	httpx.RegisterCodec("application/msgpack", Codec{})
+	httpx.RegisterCodec("application/x-msgpack", Codec{})
+}
+
+// Codec encodes and decodes values as MessagePack. Struct fields are
+// named by their msgpack tags, falling back to their json tags.
+type Codec struct{}
+
+// Encode writes the MessagePack encoding of v to w.
+func (Codec) Encode(w io.Writer, v interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+// Decode reads a MessagePack encoded value from r into v.
+func (Codec) Decode(r io.Reader, v interface{}) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
@@ -1,9 +1,10 @@
 package httpx
 
 import (
+	"errors"
 	"net/http"
-
-	"github.com/go-chi/chi"
+	"net/url"
+	"strings"
 )
 
 // Mux is a simple HTTP route multiplexer that parses a request path,
@@ -15,36 +16,48 @@ import (
 // particularly useful for writing large REST API services that break a handler
 // into many smaller parts composed of middlewares and end handlers.
 type Mux struct {
-	chi         *chi.Mux
-	middlewares []Middleware
-	prefix      string
+	router *router
+	chain  Chain
+	prefix string
+	routes *routeTable
+	errs   *errorScope
 }
 
 // NewMux returns a newly initialized Mux object
 func NewMux() *Mux {
 	return &Mux{
-		chi:         chi.NewMux(),
-		middlewares: []Middleware{},
+		router: &router{},
+		chain:  NewChain(),
+		routes: &routeTable{},
+		errs:   &errorScope{},
 	}
 }
 
 // Use appends a middleware handler to the Mux middleware stack.
 func (m *Mux) Use(middlewares ...Middleware) {
-	m.middlewares = append(m.middlewares, middlewares...)
+	m.chain = m.chain.Append(middlewares...)
+}
+
+// UseChain appends the middlewares of a chain, along with their names,
+// to the Mux middleware stack.
+func (m *Mux) UseChain(chain Chain) {
+	m.chain = m.chain.Extend(chain)
 }
 
 // With adds inline middlewares for an endpoint handler.
 func (m *Mux) With(middlewares ...Middleware) *Mux {
-	var mws []Middleware
-	mws = make([]Middleware, len(m.middlewares))
-	copy(mws, m.middlewares)
-
-	mws = append(mws, middlewares...)
+	return m.WithChain(NewChain(middlewares...))
+}
 
+// WithChain adds the middlewares of a chain, along with their names,
+// as inline middlewares for an endpoint handler.
+func (m *Mux) WithChain(chain Chain) *Mux {
 	return &Mux{
-		chi:         m.chi,
-		middlewares: mws,
-		prefix:      m.prefix,
+		router: m.router,
+		chain:  m.chain.Extend(chain),
+		prefix: m.prefix,
+		routes: m.routes,
+		errs:   &errorScope{parent: m.errs},
 	}
 }
 
@@ -75,111 +88,240 @@ func (m *Mux) Route(pattern string, fn func(*Mux)) *Mux {
 
 // Handle adds the route `pattern` that matches any http method to
 // execute the `handler` httpx.Handler.
-func (m *Mux) Handle(pattern string, handler Handler) {
-	m.chi.Handle(m.prefix+pattern, adaptor(NewChain(m.middlewares...).Then(handler)))
+func (m *Mux) Handle(pattern string, handler Handler, opts ...RouteOption) {
+	m.router.add("*", m.prefix+pattern, m.routeHandler("*", pattern, handler, opts))
 }
 
 // HandleFunc adds the route `pattern` that matches any http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) HandleFunc(pattern string, handlerFn HandlerFunc) {
-	m.Handle(pattern, handlerFn)
+func (m *Mux) HandleFunc(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Handle(pattern, handlerFn, opts...)
 }
 
 // Method adds the route `pattern` that matches `method` http method to
 // execute the `handler` httpx.Handler.
-func (m *Mux) Method(method, pattern string, h Handler) {
-	m.chi.Method(method, m.prefix+pattern, adaptor(NewChain(m.middlewares...).Then(h)))
+func (m *Mux) Method(method, pattern string, h Handler, opts ...RouteOption) {
+	method = strings.ToUpper(method)
+	m.router.add(method, m.prefix+pattern, m.routeHandler(method, pattern, h, opts))
+	if method == http.MethodGet && m.routes.autoHead {
+		ep := m.routeHandler(http.MethodHead, pattern, headHandler(h), opts)
+		ep.auto = true
+		m.router.add(http.MethodHead, m.prefix+pattern, ep)
+	}
+}
+
+// AutoHead makes the Mux, and any Mux derived from it, serve HEAD
+// requests for each GET route registered afterwards. The GET handler is
+// executed with a ResponseWriter that discards the body but records its
+// length in the Content-Length header. A HEAD route registered
+// explicitly for the same pattern takes precedence.
+func (m *Mux) AutoHead() {
+	m.routes.autoHead = true
+}
+
+// FirstMatch makes the Mux, and any Mux derived from it, resolve
+// overlapping routes in registration order: of the routes of equal
+// Precedence matching a request, the first registered wins, instead of
+// the most specific. FirstMatch must be called before the Mux serves
+// requests.
+func (m *Mux) FirstMatch() {
+	m.router.firstMatch = true
+	m.router.ordered = true
 }
 
 // MethodFunc adds the route `pattern` that matches `method` http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) MethodFunc(method, pattern string, handlerFn HandlerFunc) {
-	m.Method(method, pattern, handlerFn)
+func (m *Mux) MethodFunc(method, pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(method, pattern, handlerFn, opts...)
 }
 
 // Connect adds the route `pattern` that matches a CONNECT http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Connect(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodConnect, pattern, handlerFn)
+func (m *Mux) Connect(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodConnect, pattern, handlerFn, opts...)
 }
 
 // Delete adds the route `pattern` that matches a DELETE http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Delete(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodDelete, pattern, handlerFn)
+func (m *Mux) Delete(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodDelete, pattern, handlerFn, opts...)
 }
 
 // Get adds the route `pattern` that matches a GET http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Get(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodGet, pattern, handlerFn)
+func (m *Mux) Get(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodGet, pattern, handlerFn, opts...)
 }
 
 // Head adds the route `pattern` that matches a HEAD http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Head(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodHead, pattern, handlerFn)
+func (m *Mux) Head(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodHead, pattern, handlerFn, opts...)
 }
 
 // Options adds the route `pattern` that matches a OPTIONS http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Options(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodOptions, pattern, handlerFn)
+func (m *Mux) Options(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodOptions, pattern, handlerFn, opts...)
 }
 
 // Patch adds the route `pattern` that matches a PATCH http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Patch(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodPatch, pattern, handlerFn)
+func (m *Mux) Patch(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodPatch, pattern, handlerFn, opts...)
 }
 
 // Post adds the route `pattern` that matches a POST http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Post(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodPost, pattern, handlerFn)
+func (m *Mux) Post(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodPost, pattern, handlerFn, opts...)
 }
 
 // Put adds the route `pattern` that matches a PUT http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Put(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodPut, pattern, handlerFn)
+func (m *Mux) Put(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodPut, pattern, handlerFn, opts...)
 }
 
 // Trace adds the route `pattern` that matches a TRACE http method to
 // execute the `handlerFn` httpx.HandlerFunc.
-func (m *Mux) Trace(pattern string, handlerFn HandlerFunc) {
-	m.Method(http.MethodTrace, pattern, handlerFn)
+func (m *Mux) Trace(pattern string, handlerFn HandlerFunc, opts ...RouteOption) {
+	m.Method(http.MethodTrace, pattern, handlerFn, opts...)
 }
 
-// NotFound sets a custom http.HandlerFunc for routing paths that could
-// not be found. The default 404 handler is `http.NotFound`.
+// Proxy adds the routes `pattern` and its subtree `pattern/*` that match
+// any http method to forward requests to target with a ReverseProxy. A
+// Name route option names the route of `pattern`.
+func (m *Mux) Proxy(pattern string, target *url.URL, opts ProxyOptions, routeOpts ...RouteOption) {
+	h := ReverseProxy(target, opts)
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern != "" {
+		m.Handle(pattern, h, routeOpts...)
+		routeOpts = []RouteOption{subtreeOptions(routeOpts)}
+	}
+	m.Handle(pattern+"/*", h, routeOpts...)
+}
+
+// NotFound sets the handler for routing paths that could not be found.
+// The default handler returns a 404 Not Found StatusError.
 func (m *Mux) NotFound(handlerFn HandlerFunc) {
-	m.chi.NotFound(adaptor(handlerFn))
+	m.router.notFound = handlerFn
 }
 
-// MethodNotAllowed sets a custom http.HandlerFunc for routing paths where the
-// method is unresolved. The default handler returns a 405 with an empty body.
+// Fallback sets the handler of the paths under the prefix of the Mux
+// that match no route, for any method. Unlike the NotFound handler, h
+// runs behind the middleware stack and route options of the Mux, and
+// its errors are written by the ErrorWriter of the Mux. The part of the
+// path after the prefix is its "*" URL param.
+//
+// Fallbacks of Muxes derived with Route take precedence under their
+// prefix, so a single-page application can serve its index for any
+// unknown path while its API responds with 404 errors:
+//
+//	mux.Fallback(spaIndex)
+//	mux.Route("/api", func(api *httpx.Mux) {
+//		api.Fallback(httpx.ErrorHandler(http.StatusNotFound, "no such endpoint"))
+//	})
+//
+// Paths matching routes of other methods only are still answered by
+// the MethodNotAllowed handler.
+func (m *Mux) Fallback(h Handler, opts ...RouteOption) {
+	prefix := m.prefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	ep := m.newEndpoint("*", prefix[len(m.prefix):]+"*", h, opts)
+	for i, fb := range m.router.fallbacks {
+		if fb.prefix == prefix {
+			m.router.fallbacks[i].ep = ep
+			return
+		}
+	}
+	m.router.fallbacks = append(m.router.fallbacks, fallback{prefix: prefix, ep: ep})
+}
+
+// MethodNotAllowed sets the handler for routing paths where the method
+// is unresolved. The Allow header of the response lists the methods of
+// the path. The default handler returns a 405 Method Not Allowed
+// StatusError.
 func (m *Mux) MethodNotAllowed(handlerFn HandlerFunc) {
-	m.chi.NotFound(adaptor(handlerFn))
+	m.router.methodNotAllowed = handlerFn
 }
 
 // URLParam returns the url parameter from a http.Request object.
 func URLParam(r *http.Request, key string) string {
-	return chi.URLParam(r, key)
+	if rc := callOf(r); rc != nil {
+		return rc.params.get(key)
+	}
+	return ""
+}
+
+// URLParams returns all url parameters of a http.Request object.
+func URLParams(r *http.Request) map[string]string {
+	params := map[string]string{}
+	if rc := callOf(r); rc != nil {
+		for i, key := range rc.params.keys {
+			if key != "*" {
+				params[key] = rc.params.values[i]
+			}
+		}
+	}
+	return params
 }
 
 // ServeHTTP implements the standard go http.Handler interface.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	m.chi.ServeHTTP(w, r)
+	if opts := m.routes.sanitize; opts != nil {
+		if err := opts.check(r); err != nil {
+			m.routes.securityEvent(r, "", SecurityMalformedRequest, err.Error())
+			rw := m.routes.getWriter(w)
+			serve(HandlerFunc(func(http.ResponseWriter, *http.Request) error { return err }), rw, r, m.routes, m.errs)
+			m.routes.putWriter(rw)
+			return
+		}
+	}
+	m.router.serve(w, r, m.routes, m.errs)
 }
 
-func adaptor(next Handler) http.HandlerFunc {
+// adaptor converts a Handler to an http.HandlerFunc.
+func adaptor(next Handler, t *routeTable) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := next.ServeHTTP(w, r); err != nil {
-			if sErr, ok := err.(StatusError); ok {
-				http.Error(w, err.Error(), sErr.Status())
-			}
-			http.Error(w, err.Error(), 500)
-		}
+		serve(next, wrapWriter(w), r, t, nil)
 	})
 }
+
+// serve calls next, then writes an error it returns as a response with
+// the ErrorWriter of errs, with the status of the error, or 500 for
+// errors that are not StatusErrors once mapped by MapError, and the
+// headers of a HeaderProvider. When the handler already started the
+// response, the error can't be sent to the client anymore, and it is
+// logged instead.
+func serve(next Handler, rw *responseWriter, r *http.Request, t *routeTable, errs *errorScope) {
+	err := next.ServeHTTP(rw, r)
+	if err == nil {
+		return
+	}
+	if ClientGone(r, err) {
+		LoggerFrom(r).DebugContext(r.Context(), "client closed request",
+			"method", r.Method, "path", r.URL.Path, "error", err.Error())
+		return
+	}
+	err = mapError(err)
+	t.report(r, err)
+	if rw.status != 0 {
+		LoggerFrom(r).ErrorContext(r.Context(), "handler error after response was written",
+			"method", r.Method, "path", r.URL.Path, "status", rw.status, "error", err.Error())
+		return
+	}
+	status := http.StatusInternalServerError
+	if sErr, ok := err.(StatusError); ok {
+		status = sErr.Status()
+	}
+	var hp HeaderProvider
+	if errors.As(err, &hp) {
+		for k, v := range hp.Headers() {
+			rw.Header()[k] = v
+		}
+	}
+	errs.writer()(rw, r, status, err)
+}
@@ -0,0 +1,30 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeCancellation(t *testing.T) {
+	m := NewMux()
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("fetching rates: %w", context.Canceled)
+	})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("server-side cancellation: status %d, want 500", rec.Code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if rec.Body.Len() != 0 {
+		t.Errorf("client gone: body %q written, want none", rec.Body.String())
+	}
+}
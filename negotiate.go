@@ -0,0 +1,109 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptRange is a media range of an Accept header.
+type acceptRange struct {
+	typ, subtype string
+	params       map[string]string
+	q            float64
+}
+
+// parseAccept parses the media ranges of an Accept header. Ranges with
+// a malformed media type are skipped.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(fields[0]))
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+
+		ar := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, p := range fields[1:] {
+			key, val, _ := strings.Cut(strings.TrimSpace(p), "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			val = strings.Trim(strings.TrimSpace(val), `"`)
+			if key == "q" {
+				q, err := strconv.ParseFloat(val, 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				ar.q = q
+				continue
+			}
+			if ar.params == nil {
+				ar.params = map[string]string{}
+			}
+			ar.params[key] = val
+		}
+		ranges = append(ranges, ar)
+	}
+	return ranges
+}
+
+// match returns the specificity with which the range matches the media
+// type, or -1 when it doesn't match.
+func (ar acceptRange) match(typ, subtype string, params map[string]string) int {
+	switch {
+	case ar.typ == "*" && ar.subtype == "*":
+		return 0
+	case ar.typ != typ:
+		return -1
+	case ar.subtype == "*":
+		return 1
+	case ar.subtype != subtype:
+		return -1
+	}
+	for k, v := range ar.params {
+		if params[k] != v {
+			return -1
+		}
+	}
+	return 2 + len(ar.params)
+}
+
+// Negotiate returns the offered media type best matching the Accept
+// header of the request, following RFC 9110: the quality of an offer is
+// that of the most specific media range matching it, and an offer with
+// a quality of zero is not acceptable. Among offers of equal quality,
+// the first is preferred. When the request has no Accept header, the
+// first offer is returned. Negotiate returns "" when no offer is
+// acceptable. The negotiation is recorded for AutoVary.
+func Negotiate(r *http.Request, offers ...string) string {
+	VaryBy(r, "Accept")
+	header := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := parseAccept(header)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		ors := parseAccept(offer)
+		if len(ors) == 0 {
+			continue
+		}
+		o := ors[0]
+
+		q, spec := 0.0, -1
+		for _, ar := range ranges {
+			if s := ar.match(o.typ, o.subtype, o.params); s > spec {
+				q, spec = ar.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,362 @@
+// Package oidc logs users in to server-rendered applications with
+// OpenID Connect. A RelyingParty serves the login, callback and logout
+// routes, runs the authorization code flow with state, nonce and PKCE,
+// verifies the ID token, and keeps its subject and chosen claims in a
+// signed session cookie:
+//
+//	rp, err := oidc.New(ctx, oidc.Config{
+//		Issuer:       "https://accounts.example.com",
+//		ClientID:     clientID,
+//		ClientSecret: clientSecret,
+//		RedirectURL:  "https://app.example.com/auth/callback",
+//		SessionKey:   sessionKey,
+//	})
+//	mux.Use(rp.Session)
+//	mux.Get("/auth/login", rp.Login())
+//	mux.Get("/auth/callback", rp.Callback())
+//	mux.Post("/auth/logout", rp.Logout())
+//	mux.With(rp.Require).Get("/account", account)
+//
+// Handlers get the claims of the user with httpx.User, or the user as
+// an httpx.Principal with httpx.PrincipalFrom.
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/eriklott/httpx"
+	"github.com/eriklott/httpx/internal/signed"
+	"golang.org/x/oauth2"
+)
+
+// Config configures a RelyingParty.
+type Config struct {
+	// Issuer is the URL of the OpenID provider, whose configuration is
+	// discovered from Issuer/.well-known/openid-configuration.
+	Issuer string
+
+	// ClientID and ClientSecret are the credentials of the application
+	// registered with the provider.
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the absolute URL of the Callback route.
+	RedirectURL string
+
+	// Scopes are the scopes requested. The default is openid, profile
+	// and email.
+	Scopes []string
+
+	// SessionKey signs the session and login cookies. It should be 32
+	// random bytes or more.
+	SessionKey []byte
+
+	// SessionTTL is the lifetime of a session. The default is the
+	// lifetime of the ID token, capped to 24 hours.
+	SessionTTL time.Duration
+
+	// CookieName is the name of the session cookie. The default is
+	// "_session".
+	CookieName string
+
+	// InsecureCookies sets the cookies without the Secure attribute, for
+	// development over plain HTTP.
+	InsecureCookies bool
+
+	// AfterLogout is the URL users are sent to after logging out. The
+	// default is "/".
+	AfterLogout string
+
+	// SessionClaims lists the claims of the ID token kept in the session
+	// cookie along with sub, for Principal and httpx.User. The default
+	// is name, email and roles. Cookies are limited to about 4 KB, so
+	// the session can't hold every claim of an ordinary ID token.
+	SessionClaims []string
+
+	// Principal builds the Principal of a user from the claims kept in
+	// the session. The default uses the sub claim as ID and the roles
+	// claim, if any, as Roles.
+	Principal func(claims map[string]interface{}) httpx.Principal
+}
+
+// RelyingParty runs the OpenID Connect login flow of an application.
+type RelyingParty struct {
+	cfg        Config
+	oauth      oauth2.Config
+	verifier   *oidc.IDTokenVerifier
+	endSession string
+}
+
+// New returns a RelyingParty for cfg, discovering the configuration of
+// the provider.
+func New(ctx context.Context, cfg Config) (*RelyingParty, error) {
+	if len(cfg.SessionKey) < 32 {
+		return nil, errors.New("oidc: SessionKey must be at least 32 bytes")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "_session"
+	}
+	if cfg.AfterLogout == "" {
+		cfg.AfterLogout = "/"
+	}
+	if len(cfg.SessionClaims) == 0 {
+		cfg.SessionClaims = []string{"name", "email", "roles"}
+	}
+	if cfg.Principal == nil {
+		cfg.Principal = defaultPrincipal
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	var meta struct {
+		EndSession string `json:"end_session_endpoint"`
+	}
+	provider.Claims(&meta)
+
+	return &RelyingParty{
+		cfg: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		},
+		verifier:   provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		endSession: meta.EndSession,
+	}, nil
+}
+
+// loginCookie is the name of the cookie carrying the state of a login
+// between the Login and Callback routes.
+const loginCookie = "_oidc_login"
+
+// loginState is the state of a login in progress.
+type loginState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r"`
+}
+
+// session is the content of the session cookie: the sub claim and the
+// SessionClaims of the ID token.
+type session struct {
+	Claims  map[string]interface{} `json:"c"`
+	Expires int64                  `json:"e"`
+}
+
+// Login returns a handler that starts a login, redirecting the user to
+// the provider. The user is sent back to the local path of the
+// return_to query param once logged in, or to "/".
+func (rp *RelyingParty) Login() httpx.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		st := loginState{
+			State:    randomString(),
+			Nonce:    randomString(),
+			Verifier: oauth2.GenerateVerifier(),
+			ReturnTo: localPath(r.URL.Query().Get("return_to")),
+		}
+		rp.setCookie(w, loginCookie, signed.Encode(rp.cfg.SessionKey, st), 10*time.Minute)
+		u := rp.oauth.AuthCodeURL(st.State, oidc.Nonce(st.Nonce), oauth2.S256ChallengeOption(st.Verifier))
+		http.Redirect(w, r, u, http.StatusFound)
+		return nil
+	}
+}
+
+// Callback returns the handler of the redirect URL. It checks the state
+// of the login, exchanges the authorization code for tokens, verifies
+// the ID token and its nonce, and starts a session with its subject and
+// the SessionClaims.
+// A failed login is a 400 Bad Request StatusError, or a 401
+// Unauthorized StatusError when the provider refused it.
+func (rp *RelyingParty) Callback() httpx.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		var st loginState
+		c, err := r.Cookie(loginCookie)
+		if err != nil || !signed.Decode(rp.cfg.SessionKey, c.Value, &st) {
+			return httpx.Error(http.StatusBadRequest, "no login in progress")
+		}
+		rp.setCookie(w, loginCookie, "", -1)
+
+		q := r.URL.Query()
+		if e := q.Get("error"); e != "" {
+			return httpx.Errorf(http.StatusUnauthorized, "login failed: %s", e)
+		}
+		if q.Get("state") == "" || !hmac.Equal([]byte(q.Get("state")), []byte(st.State)) {
+			return httpx.Error(http.StatusBadRequest, "invalid login state")
+		}
+
+		token, err := rp.oauth.Exchange(r.Context(), q.Get("code"), oauth2.VerifierOption(st.Verifier))
+		if err != nil {
+			return httpx.Errorf(http.StatusUnauthorized, "login failed: %v", err)
+		}
+		raw, ok := token.Extra("id_token").(string)
+		if !ok {
+			return httpx.Error(http.StatusUnauthorized, "login failed: no ID token")
+		}
+		idToken, err := rp.verifier.Verify(r.Context(), raw)
+		if err != nil {
+			return httpx.Errorf(http.StatusUnauthorized, "login failed: %v", err)
+		}
+		if !hmac.Equal([]byte(idToken.Nonce), []byte(st.Nonce)) {
+			return httpx.Error(http.StatusUnauthorized, "login failed: invalid nonce")
+		}
+		var all map[string]interface{}
+		if err := idToken.Claims(&all); err != nil {
+			return err
+		}
+		claims := map[string]interface{}{"sub": idToken.Subject}
+		for _, name := range rp.cfg.SessionClaims {
+			if v, ok := all[name]; ok {
+				claims[name] = v
+			}
+		}
+
+		ttl := rp.cfg.SessionTTL
+		if ttl <= 0 {
+			ttl = time.Until(idToken.Expiry)
+			if ttl <= 0 || ttl > 24*time.Hour {
+				ttl = 24 * time.Hour
+			}
+		}
+		s := session{Claims: claims, Expires: time.Now().Add(ttl).Unix()}
+		rp.setCookie(w, rp.cfg.CookieName, signed.Encode(rp.cfg.SessionKey, s), ttl)
+		http.Redirect(w, r, st.ReturnTo, http.StatusFound)
+		return nil
+	}
+}
+
+// Logout returns a handler that ends the session, and the session with
+// the provider when it supports RP-initiated logout. It should be
+// routed for POST requests, so that other sites can't log users out
+// with a link.
+func (rp *RelyingParty) Logout() httpx.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		rp.setCookie(w, rp.cfg.CookieName, "", -1)
+		target := rp.cfg.AfterLogout
+		if rp.endSession != "" {
+			u, err := url.Parse(rp.endSession)
+			if err != nil {
+				return err
+			}
+			q := u.Query()
+			q.Set("client_id", rp.cfg.ClientID)
+			if after, err := r.URL.Parse(rp.cfg.AfterLogout); err == nil && after.IsAbs() {
+				q.Set("post_logout_redirect_uri", after.String())
+			}
+			u.RawQuery = q.Encode()
+			target = u.String()
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+		return nil
+	}
+}
+
+// Session is a middleware that sets the Principal of the requests of
+// logged in users, from their session cookie. Requests without a valid
+// session are passed on anonymous.
+func (rp *RelyingParty) Session(next httpx.Handler) httpx.Handler {
+	return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		c, err := r.Cookie(rp.cfg.CookieName)
+		if err != nil {
+			return next.ServeHTTP(w, r)
+		}
+		var s session
+		if !signed.Decode(rp.cfg.SessionKey, c.Value, &s) || time.Now().Unix() >= s.Expires {
+			return next.ServeHTTP(w, r)
+		}
+		p := rp.cfg.Principal(s.Claims)
+		p.Claims = s.Claims
+		return next.ServeHTTP(w, httpx.WithPrincipal(r, &p))
+	})
+}
+
+// Require is a middleware that redirects anonymous users to the login
+// route, found next to the redirect URL as "login", and back once they
+// are logged in. Anonymous requests other than GET and HEAD are
+// rejected with a 401 Unauthorized StatusError instead. Require must
+// run after Session.
+func (rp *RelyingParty) Require(next httpx.Handler) httpx.Handler {
+	login := "login"
+	if u, err := url.Parse(rp.cfg.RedirectURL); err == nil {
+		login = u.ResolveReference(&url.URL{Path: "login"}).Path
+	}
+	return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if _, ok := httpx.PrincipalFrom(r); ok {
+			return next.ServeHTTP(w, r)
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return httpx.Error(http.StatusUnauthorized, "login required")
+		}
+		http.Redirect(w, r, login+"?return_to="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		return nil
+	})
+}
+
+func (rp *RelyingParty) setCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !rp.cfg.InsecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if ttl < 0 {
+		c.MaxAge = -1
+	} else {
+		c.MaxAge = int(ttl.Seconds())
+	}
+	http.SetCookie(w, c)
+}
+
+func defaultPrincipal(claims map[string]interface{}) httpx.Principal {
+	p := httpx.Principal{}
+	p.ID, _ = claims["sub"].(string)
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				p.Roles = append(p.Roles, s)
+			}
+		}
+	}
+	return p
+}
+
+// localPath returns path when it is a path on the same site, and "/"
+// otherwise, so that logins can't redirect to other sites. Browsers
+// read a backslash as a slash and drop tabs and newlines, and
+// http.Redirect cleans dot segments, so "/a/../\evil.com" and
+// "/\t/evil.com" would both redirect to evil.com.
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.ContainsRune(path, '\\') {
+		return "/"
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] < 0x20 || path[i] == 0x7f {
+			return "/"
+		}
+	}
+	return path
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,319 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriklott/httpx"
+	"github.com/eriklott/httpx/internal/signed"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// newProvider starts an OpenID provider that issues ID tokens for the
+// user "user-1". The token endpoint takes the nonce of the ID token as
+// the authorization code, so that tests choose the nonce.
+func newProvider(t *testing.T) *httptest.Server {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                srv.URL,
+			"authorization_endpoint":                srv.URL + "/authorize",
+			"token_endpoint":                        srv.URL + "/token",
+			"jwks_uri":                              srv.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []interface{}{map[string]string{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "k1",
+			"n": b64(key.N.Bytes()),
+			"e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+		claims, _ := json.Marshal(map[string]interface{}{
+			"iss":     srv.URL,
+			"aud":     "client",
+			"sub":     "user-1",
+			"iat":     time.Now().Unix(),
+			"exp":     time.Now().Add(time.Hour).Unix(),
+			"nonce":   r.FormValue("code"),
+			"name":    "Ann",
+			"roles":   []string{"admin"},
+			"picture": "https://example.com/ann.png",
+		})
+		msg := b64(header) + "." + b64(claims)
+		sum := sha256.Sum256([]byte(msg))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Error(err)
+		}
+		writeJSON(w, map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     msg + "." + b64(sig),
+		})
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newRelyingParty(t *testing.T, issuer string) *RelyingParty {
+	t.Helper()
+	rp, err := New(context.Background(), Config{
+		Issuer:      issuer,
+		ClientID:    "client",
+		RedirectURL: "https://app.example.com/auth/callback",
+		SessionKey:  testKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rp
+}
+
+// login runs the Login handler and returns the login cookie and the
+// state and nonce sent to the provider.
+func login(t *testing.T, rp *RelyingParty, returnTo string) (c *http.Cookie, state, nonce string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/auth/login?return_to="+url.QueryEscape(returnTo), nil)
+	if err := rp.Login().ServeHTTP(rec, r); err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != loginCookie {
+		t.Fatalf("cookies %v", cookies)
+	}
+	return cookies[0], u.Query().Get("state"), u.Query().Get("nonce")
+}
+
+// callback runs the Callback handler with query and cookie c, and
+// returns its recorder and error.
+func callback(rp *RelyingParty, query url.Values, c *http.Cookie) (*httptest.ResponseRecorder, error) {
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/auth/callback?"+query.Encode(), nil)
+	if c != nil {
+		r.AddCookie(c)
+	}
+	return rec, rp.Callback().ServeHTTP(rec, r)
+}
+
+func status(err error) int {
+	if se, ok := err.(httpx.StatusError); ok {
+		return se.Status()
+	}
+	return 0
+}
+
+func TestLogin(t *testing.T) {
+	rp := newRelyingParty(t, newProvider(t).URL)
+	c, state, nonce := login(t, rp, "/account?tab=keys")
+	if state == "" || nonce == "" || state == nonce {
+		t.Fatalf("state %q, nonce %q", state, nonce)
+	}
+
+	rec, err := callback(rp, url.Values{"state": {state}, "code": {nonce}}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Location"); got != "/account?tab=keys" {
+		t.Errorf("redirect to %q", got)
+	}
+	var sessionCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "_session" {
+			sessionCookie = c
+		}
+	}
+	if sessionCookie == nil {
+		t.Fatal("no session cookie")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/account", nil)
+	r.AddCookie(sessionCookie)
+	var claims map[string]interface{}
+	var p *httpx.Principal
+	rp.Session(httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		p, _ = httpx.PrincipalFrom(r)
+		claims, _ = httpx.User(r)
+		return nil
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if p == nil || p.ID != "user-1" || len(p.Roles) != 1 || p.Roles[0] != "admin" {
+		t.Fatalf("principal %+v", p)
+	}
+	if claims["name"] != "Ann" {
+		t.Errorf("claims %v", claims)
+	}
+	if _, ok := claims["picture"]; ok {
+		t.Errorf("claim not in SessionClaims kept in the session: %v", claims)
+	}
+}
+
+// TestCallbackRejects checks that a callback is rejected when it doesn't
+// belong to the login in progress.
+func TestCallbackRejects(t *testing.T) {
+	rp := newRelyingParty(t, newProvider(t).URL)
+	tests := []struct {
+		name  string
+		query func(state, nonce string) url.Values
+		want  int
+		msg   string
+	}{
+		{"state mismatch", func(state, nonce string) url.Values {
+			return url.Values{"state": {state + "x"}, "code": {nonce}}
+		}, http.StatusBadRequest, "invalid login state"},
+		{"missing state", func(state, nonce string) url.Values {
+			return url.Values{"code": {nonce}}
+		}, http.StatusBadRequest, "invalid login state"},
+		{"nonce mismatch", func(state, nonce string) url.Values {
+			return url.Values{"state": {state}, "code": {"other-nonce"}}
+		}, http.StatusUnauthorized, "invalid nonce"},
+		{"provider error", func(state, nonce string) url.Values {
+			return url.Values{"state": {state}, "error": {"access_denied"}}
+		}, http.StatusUnauthorized, "access_denied"},
+	}
+	for _, tt := range tests {
+		c, state, nonce := login(t, rp, "/")
+		rec, err := callback(rp, tt.query(state, nonce), c)
+		if got := status(err); got != tt.want || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: error %v, want status %d", tt.name, err, tt.want)
+		}
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "_session" {
+				t.Errorf("%s: session cookie set", tt.name)
+			}
+		}
+	}
+
+	_, state, nonce := login(t, rp, "/")
+	if _, err := callback(rp, url.Values{"state": {state}, "code": {nonce}}, nil); status(err) != http.StatusBadRequest {
+		t.Errorf("no login cookie: error %v", err)
+	}
+	forged := &http.Cookie{Name: loginCookie, Value: signed.Encode([]byte("another key, also 32 bytes long."), loginState{
+		State: state, Nonce: nonce,
+	})}
+	if _, err := callback(rp, url.Values{"state": {state}, "code": {nonce}}, forged); status(err) != http.StatusBadRequest {
+		t.Errorf("forged login cookie: error %v", err)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/account", "/account"},
+		{"/account?tab=keys#top", "/account?tab=keys#top"},
+		{"/a/../b", "/a/../b"},
+		{"", "/"},
+		{"account", "/"},
+		{"https://evil.com/", "/"},
+		{"//evil.com", "/"},
+		{"///evil.com", "/"},
+		{"/\\evil.com", "/"},
+		{"/a/../\\evil.com", "/"},
+		{"/\t/evil.com", "/"},
+		{"/\n/evil.com", "/"},
+		{"javascript:alert(1)", "/"},
+	}
+	for _, tt := range tests {
+		if got := localPath(tt.path); got != tt.want {
+			t.Errorf("localPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Login keeps only the local path of return_to.
+	rp := newRelyingParty(t, newProvider(t).URL)
+	c, state, nonce := login(t, rp, "//evil.com/phish")
+	rec, err := callback(rp, url.Values{"state": {state}, "code": {nonce}}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get("Location"); got != "/" {
+		t.Errorf("redirect to %q", got)
+	}
+}
+
+// TestSession checks that expired, tampered and unsigned session
+// cookies leave the request anonymous.
+func TestSession(t *testing.T) {
+	rp := &RelyingParty{cfg: Config{SessionKey: testKey, CookieName: "_session", Principal: defaultPrincipal}}
+	claims := map[string]interface{}{"sub": "user-1"}
+	valid := signed.Encode(testKey, session{Claims: claims, Expires: time.Now().Add(time.Hour).Unix()})
+	tests := []struct {
+		name, cookie string
+		want         bool
+	}{
+		{"valid", valid, true},
+		{"expired", signed.Encode(testKey, session{Claims: claims, Expires: time.Now().Add(-time.Second).Unix()}), false},
+		{"no expiry", signed.Encode(testKey, session{Claims: claims}), false},
+		{"tampered", "x" + valid, false},
+		{"other key", signed.Encode([]byte("another key, also 32 bytes long."), session{
+			Claims: claims, Expires: time.Now().Add(time.Hour).Unix(),
+		}), false},
+		{"unsigned", "garbage", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: "_session", Value: tt.cookie})
+		var got bool
+		rp.Session(httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			_, got = httpx.PrincipalFrom(r)
+			return nil
+		})).ServeHTTP(httptest.NewRecorder(), r)
+		if got != tt.want {
+			t.Errorf("%s: logged in %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	rp := &RelyingParty{cfg: Config{RedirectURL: "https://app.example.com/auth/callback"}}
+	h := rp.Require(httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		t.Error("anonymous request passed")
+		return nil
+	}))
+
+	rec := httptest.NewRecorder()
+	if err := h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account?tab=keys", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rec.Header().Get("Location"), "/auth/login?return_to=%2Faccount%3Ftab%3Dkeys"; got != want {
+		t.Errorf("redirect to %q, want %q", got, want)
+	}
+	if err := h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/account", nil)); status(err) != http.StatusUnauthorized {
+		t.Errorf("POST: error %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// An ErrorWriter writes the response for an error returned by a
+// handler, with the status the error is sent with. Any headers declared
+// by a HeaderProvider error are already set.
+type ErrorWriter func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// errorScope holds the ErrorWriter set on a Mux with OnError. Each Mux
+// derived with With, Group or Route has its own scope, falling back to
+// that of its parent.
+type errorScope struct {
+	parent *errorScope
+	fn     ErrorWriter
+}
+
+// writer returns the ErrorWriter of the scope, or WriteError.
+func (s *errorScope) writer() ErrorWriter {
+	for ; s != nil; s = s.parent {
+		if s.fn != nil {
+			return s.fn
+		}
+	}
+	return WriteError
+}
+
+// OnError sets the ErrorWriter of the routes of the Mux, and of any Mux
+// derived from it that doesn't set its own, overriding the ErrorWriter
+// of the Mux it was derived from. Set on the root Mux, it also writes
+// the errors of NotFound, MethodNotAllowed and Sanitize. Routes can then
+// render errors their own way without handlers branching on the kind
+// of route:
+//
+//	mux.OnError(func(w http.ResponseWriter, r *http.Request, status int, err error) {
+//		httpx.HTML(w, status, "error.html", err)
+//	})
+//	mux.Route("/api", func(api *httpx.Mux) {
+//		api.OnError(httpx.WriteError)
+//	})
+//
+// The default is WriteError. OnError must be called before the Mux
+// serves requests.
+func (m *Mux) OnError(fn ErrorWriter) {
+	m.errs.fn = fn
+}
+
+// WriteError is the default ErrorWriter. It lets a BodyWriter write its
+// own body, writes the code and message of a CodedError, along with the
+// fields of FieldErrors, as a JSON body, and other errors as their
+// message in plain text.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	var bw BodyWriter
+	if errors.As(err, &bw) {
+		bw.WriteBody(w, status)
+		return
+	}
+
+	var cErr CodedError
+	if !errors.As(err, &cErr) {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	body := struct {
+		Code    string       `json:"code"`
+		Message string       `json:"message"`
+		Fields  []FieldError `json:"fields,omitempty"`
+	}{Code: cErr.Code(), Message: err.Error()}
+	if fields, ok := cErr.(FieldErrors); ok {
+		body.Fields = fields
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
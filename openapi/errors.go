@@ -0,0 +1,45 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eriklott/httpx"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// AddErrorCodes adds an Error schema to the components of doc, for the
+// JSON body written for httpx.CodedErrors, whose code property
+// enumerates the codes registered with httpx.RegisterErrorCode, along
+// with their status and description, and whose fields property lists
+// the failures of httpx.FieldErrors. Operations can reference it as
+// #/components/schemas/Error.
+func AddErrorCodes(doc *openapi3.T) {
+	codes := httpx.ErrorCodes()
+	code := openapi3.NewStringSchema()
+	var desc strings.Builder
+	desc.WriteString("Application error code.\n")
+	for _, c := range codes {
+		code.Enum = append(code.Enum, c.Code)
+		fmt.Fprintf(&desc, "\n- `%s` (%d): %s", c.Code, c.Status, c.Description)
+	}
+	code.Description = desc.String()
+	code.Extensions = map[string]interface{}{"x-error-codes": codes}
+
+	field := openapi3.NewObjectSchema().
+		WithProperty("field", openapi3.NewStringSchema()).
+		WithProperty("message", openapi3.NewStringSchema())
+	schema := openapi3.NewObjectSchema().
+		WithProperty("code", code).
+		WithProperty("message", openapi3.NewStringSchema()).
+		WithProperty("fields", openapi3.NewArraySchema().WithItems(field))
+	schema.Required = []string{"code", "message"}
+
+	if doc.Components == nil {
+		doc.Components = &openapi3.Components{}
+	}
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = openapi3.Schemas{}
+	}
+	doc.Components.Schemas["Error"] = openapi3.NewSchemaRef("", schema)
+}
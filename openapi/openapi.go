@@ -0,0 +1,165 @@
+// Package openapi checks the requests and responses of a Mux against an
+// OpenAPI 3 document. Routes are matched to the operations of the
+// document by their pattern, as httpx patterns and OpenAPI paths share
+// the {param} syntax:
+//
+//	doc, err := openapi.Load("openapi.yaml")
+//	mux.Use(openapi.Contract(doc, openapi.ContractOptions{}))
+//	mux.Get("/users/{id}", getUser) // checked against "/users/{id}" get
+package openapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eriklott/httpx"
+	"github.com/eriklott/httpx/internal/record"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// Load reads an OpenAPI document from a YAML or JSON file and checks
+// that it is valid.
+func Load(path string) (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("openapi: %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// ErrUndocumented is reported by Contract for a request to a route
+// without an operation in the document.
+var ErrUndocumented = errors.New("openapi: route is not documented")
+
+// ContractOptions configures the Contract middleware.
+type ContractOptions struct {
+	// OnViolation is called with each response that doesn't conform to
+	// the document. The default logs the violation with the logger of
+	// the request. Tests usually fail instead:
+	//
+	//	OnViolation: func(r *http.Request, err error) { t.Error(err) }
+	OnViolation func(r *http.Request, err error)
+
+	// IgnoreUndocumented doesn't report the requests to routes without
+	// an operation in the document.
+	IgnoreUndocumented bool
+}
+
+// Contract is a middleware that checks that the responses of the routes
+// conform to their operations in doc: that their status is documented
+// and that their body matches the schema of the status and content
+// type. Violations are reported to opts.OnViolation; the responses are
+// sent unchanged.
+//
+// Contract keeps a copy of each response body for validation, and is
+// meant for development and tests rather than production.
+func Contract(doc *openapi3.T, opts ContractOptions) httpx.Middleware {
+	if opts.OnViolation == nil {
+		opts.OnViolation = func(r *http.Request, err error) {
+			httpx.LoggerFrom(r).Error("openapi contract violation", "error", err)
+		}
+	}
+	return func(next httpx.Handler) httpx.Handler {
+		return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			route, ok := findRoute(doc, r)
+			if !ok {
+				if !opts.IgnoreUndocumented {
+					opts.OnViolation(r, fmt.Errorf("%w: %s %s", ErrUndocumented, r.Method, r.URL.Path))
+				}
+				return next.ServeHTTP(w, r)
+			}
+
+			tw := &record.Tee{ResponseWriter: w}
+			err := next.ServeHTTP(tw, r)
+
+			input := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request:    r,
+					PathParams: httpx.URLParams(r),
+					Route:      route,
+				},
+				Status:  tw.Status,
+				Header:  w.Header(),
+				Options: &openapi3filter.Options{IncludeResponseStatus: true},
+			}
+			switch {
+			case tw.Status == 0 && err != nil:
+				// The Mux writes the error response, as plain text, so
+				// only its status is checked.
+				input.Status = http.StatusInternalServerError
+				if sErr, ok := err.(httpx.StatusError); ok {
+					input.Status = sErr.Status()
+				}
+				input.Options.ExcludeResponseBody = true
+			case tw.Status == 0:
+				input.Status = http.StatusOK
+			}
+			input.SetBodyBytes(tw.Body.Bytes())
+			if verr := openapi3filter.ValidateResponse(r.Context(), input); verr != nil {
+				opts.OnViolation(r, fmt.Errorf("openapi: %s %s: %w", r.Method, route.Path, verr))
+			}
+			return err
+		})
+	}
+}
+
+// findRoute returns the operation of doc for the route of r.
+func findRoute(doc *openapi3.T, r *http.Request) (*routers.Route, bool) {
+	ri, ok := httpx.CurrentRoute(r)
+	if !ok || doc.Paths == nil {
+		return nil, false
+	}
+	path, ok := openAPIPath(ri.Pattern)
+	if !ok {
+		return nil, false
+	}
+	item := doc.Paths.Find(path)
+	if item == nil {
+		return nil, false
+	}
+	op := item.GetOperation(r.Method)
+	if op == nil {
+		return nil, false
+	}
+	return &routers.Route{Spec: doc, Path: path, PathItem: item, Method: r.Method, Operation: op}, true
+}
+
+// openAPIPath converts a routing pattern to an OpenAPI path, dropping
+// the regexps of its params. Patterns ending with a wildcard have no
+// OpenAPI equivalent.
+func openAPIPath(pattern string) (string, bool) {
+	if strings.HasSuffix(pattern, "*") {
+		return "", false
+	}
+	var b strings.Builder
+	depth, skip := 0, false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case depth == 0:
+			if c == '{' {
+				depth, skip = 1, false
+			}
+			b.WriteByte(c)
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				b.WriteByte(c)
+			}
+		case c == ':' && depth == 1:
+			skip = true
+		case !skip:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,115 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/eriklott/httpx"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// ValidateOptions configures the Validate middleware.
+type ValidateOptions struct {
+	// Filter configures the validation. The default checks the params
+	// and body of requests, without checking their security
+	// requirements, which are left to the auth middlewares.
+	Filter *openapi3filter.Options
+
+	// MaxBodyBytes is the size of the largest request body accepted.
+	// Larger requests are rejected with a 413 Request Entity Too Large
+	// httpx.Problem. The default is 1 MiB.
+	MaxBodyBytes int64
+}
+
+// Validate is a middleware that checks the path, query and header
+// params and the body of requests against their operations in doc, and
+// rejects the requests that don't conform with a 400 Bad Request
+// httpx.Problem describing the first problem. Requests to routes without
+// an operation in the document are passed through, as is the body of
+// valid requests.
+//
+// Validation is then declared once, in the document, rather than in
+// each handler:
+//
+//	mux.Use(openapi.Validate(doc, openapi.ValidateOptions{}))
+func Validate(doc *openapi3.T, opts ValidateOptions) httpx.Middleware {
+	if opts.Filter == nil {
+		opts.Filter = &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	return func(next httpx.Handler) httpx.Handler {
+		return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			route, ok := findRoute(doc, r)
+			if !ok {
+				return next.ServeHTTP(w, r)
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes)
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: httpx.URLParams(r),
+				Route:      route,
+				Options:    opts.Filter,
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				var mbErr *http.MaxBytesError
+				if errors.As(err, &mbErr) {
+					return tooLarge(mbErr)
+				}
+				return httpx.NewProblem(http.StatusBadRequest, err.Error())
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidateJSON is a middleware that checks the JSON body of requests
+// against schema, for routes without an OpenAPI operation, and rejects
+// the requests that don't conform with a 400 Bad Request httpx.Problem.
+// The body is read whole, up to opts.MaxBodyBytes, and is available
+// again to the next handler. opts.Filter doesn't apply.
+//
+//	schema := openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema())
+//	validate := openapi.ValidateJSON(schema, openapi.ValidateOptions{})
+//	mux.Post("/users", createUser, httpx.WithMiddleware(httpx.NewChain(validate)))
+func ValidateJSON(schema *openapi3.Schema, opts ValidateOptions) httpx.Middleware {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	return func(next httpx.Handler) httpx.Handler {
+		return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes))
+			if err != nil {
+				var mbErr *http.MaxBytesError
+				if errors.As(err, &mbErr) {
+					return tooLarge(mbErr)
+				}
+				return err
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var v interface{}
+			if err := json.Unmarshal(body, &v); err != nil {
+				return httpx.NewProblem(http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			}
+			if err := schema.VisitJSON(v); err != nil {
+				return httpx.NewProblem(http.StatusBadRequest, "invalid body: "+err.Error())
+			}
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tooLarge returns the 413 Request Entity Too Large httpx.Problem of a
+// body cut short by http.MaxBytesReader.
+func tooLarge(err *http.MaxBytesError) *httpx.Problem {
+	return httpx.NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", err.Limit))
+}
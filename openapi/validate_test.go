@@ -0,0 +1,66 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eriklott/httpx"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// TestValidateBodySize checks that bodies over MaxBodyBytes are
+// rejected with a 413, and that smaller ones reach the handler whole.
+func TestValidateBodySize(t *testing.T) {
+	schema := openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema())
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.3",
+		"info": {"title": "test", "version": "1"},
+		"paths": {"/users": {"post": {
+			"requestBody": {"required": true, "content": {"application/json": {"schema": {
+				"type": "object", "properties": {"name": {"type": "string"}}
+			}}}},
+			"responses": {"201": {"description": "created"}}
+		}}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	opts := ValidateOptions{MaxBodyBytes: 32}
+	handler := func(w http.ResponseWriter, r *http.Request) error {
+		var v struct{ Name string }
+		if err := httpx.Bind(r, &v); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	m := httpx.NewMux()
+	m.With(Validate(doc, opts)).Post("/users", handler)
+	m.With(ValidateJSON(schema, opts)).Post("/json", handler)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"name":"Ann"}`, http.StatusCreated},
+		{`{"name":1}`, http.StatusBadRequest},
+		{`{"name":"` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, path := range []string{"/users", "/json"} {
+		for _, tt := range tests {
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s: status %d, want %d: %s", path, tt.body, rec.Code, tt.want, rec.Body)
+			}
+		}
+	}
+}
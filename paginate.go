@@ -0,0 +1,161 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageLimits configures ParsePage.
+type PageLimits struct {
+	// DefaultPerPage is the page size when the request sets none. The
+	// default is 20.
+	DefaultPerPage int
+
+	// MaxPerPage caps the page size a request can ask for. The default
+	// is 100.
+	MaxPerPage int
+}
+
+// Page is the page of a collection requested by a client, either by
+// page number or by cursor.
+type Page struct {
+	// Number is the 1-based page number, from the page parameter.
+	Number int
+
+	// PerPage is the page size, from the per_page parameter.
+	PerPage int
+
+	// Cursor is the opaque position to continue from, from the cursor
+	// parameter. When set, Number is ignored.
+	Cursor string
+}
+
+// Offset returns the number of items preceding the page.
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.PerPage
+}
+
+// ParsePage reads the page, per_page and cursor query parameters of
+// the request. A page size above limits.MaxPerPage is lowered to it. A
+// malformed or out of range value is reported as a 400 Bad Request
+// StatusError.
+func ParsePage(r *http.Request, limits PageLimits) (Page, error) {
+	if limits.DefaultPerPage <= 0 {
+		limits.DefaultPerPage = 20
+	}
+	if limits.MaxPerPage <= 0 {
+		limits.MaxPerPage = 100
+	}
+
+	number, err := QueryInt(r, "page", 1)
+	if err != nil {
+		return Page{}, err
+	}
+	perPage, err := QueryInt(r, "per_page", limits.DefaultPerPage)
+	if err != nil {
+		return Page{}, err
+	}
+	if number < 1 {
+		return Page{}, Error(http.StatusBadRequest, `query parameter "page" must be at least 1`)
+	}
+	if perPage < 1 {
+		return Page{}, Error(http.StatusBadRequest, `query parameter "per_page" must be at least 1`)
+	}
+	if perPage > limits.MaxPerPage {
+		perPage = limits.MaxPerPage
+	}
+	return Page{Number: number, PerPage: perPage, Cursor: r.URL.Query().Get("cursor")}, nil
+}
+
+// Link is a web link (RFC 8288) sent in a Link header.
+type Link struct {
+	URL string
+	Rel string
+}
+
+// String returns the link in Link header format.
+func (l Link) String() string {
+	return "<" + l.URL + `>; rel="` + l.Rel + `"`
+}
+
+// AddLinks adds links to the Link header of the response.
+func AddLinks(w http.ResponseWriter, links ...Link) {
+	if len(links) == 0 {
+		return
+	}
+	vals := make([]string, len(links))
+	for i, l := range links {
+		vals[i] = l.String()
+	}
+	w.Header().Add("Link", strings.Join(vals, ", "))
+}
+
+// PageLinks returns the first, prev, next and last links of a page of a
+// collection of total items, built from the request URL. Links that
+// don't apply to the page are omitted; when total is negative, the
+// total is unknown and the last link is omitted.
+func PageLinks(r *http.Request, p Page, total int) []Link {
+	link := func(rel string, number int) Link {
+		return Link{URL: withQuery(r.URL, url.Values{
+			"page":     {strconv.Itoa(number)},
+			"per_page": {strconv.Itoa(p.PerPage)},
+		}, "cursor"), Rel: rel}
+	}
+
+	links := []Link{link("first", 1)}
+	if p.Number > 1 {
+		links = append(links, link("prev", p.Number-1))
+	}
+	if total < 0 {
+		return append(links, link("next", p.Number+1))
+	}
+	last := (total + p.PerPage - 1) / p.PerPage
+	if last < 1 {
+		last = 1
+	}
+	if p.Number < last {
+		links = append(links, link("next", p.Number+1))
+	}
+	return append(links, link("last", last))
+}
+
+// CursorLinks returns the next link of a cursor paginated collection,
+// built from the request URL, or no links when next is empty.
+func CursorLinks(r *http.Request, next string) []Link {
+	if next == "" {
+		return nil
+	}
+	return []Link{{URL: withQuery(r.URL, url.Values{"cursor": {next}}, "page"), Rel: "next"}}
+}
+
+// withQuery returns the path and query of u with the parameters of set
+// replaced and the remove parameters deleted.
+func withQuery(u *url.URL, set url.Values, remove ...string) string {
+	q := u.Query()
+	for key, vals := range set {
+		q[key] = vals
+	}
+	for _, key := range remove {
+		q.Del(key)
+	}
+	return (&url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: q.Encode()}).String()
+}
+
+// Paginated writes a page of a collection as a JSON envelope holding
+// the items, the next cursor or page URL when next is not empty, and
+// the total number of items when total is not negative:
+//
+//	{"items": [...], "next": "...", "total": 42}
+func Paginated(w http.ResponseWriter, items interface{}, next string, total int) error {
+	env := struct {
+		Items interface{} `json:"items"`
+		Next  string      `json:"next,omitempty"`
+		Total *int        `json:"total,omitempty"`
+	}{Items: items, Next: next}
+	if total >= 0 {
+		env.Total = &total
+	}
+	return JSON(w, http.StatusOK, env)
+}
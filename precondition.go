@@ -0,0 +1,74 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckPreconditions evaluates the If-Match, If-Unmodified-Since and
+// If-None-Match headers of a state changing request (RFC 9110, section
+// 13.2.2) against the current entity tag and modification time of the
+// target resource. An empty etag or a zero modTime means the resource
+// has no such validator, and an empty etag with a zero modTime means
+// the resource doesn't exist.
+//
+// CheckPreconditions returns a 412 Precondition Failed StatusError when
+// a precondition fails, so PUT and PATCH handlers can implement
+// optimistic locking:
+//
+//	if err := httpx.CheckPreconditions(r, doc.ETag(), doc.Updated); err != nil {
+//		return err
+//	}
+//
+// The If-None-Match header of GET and HEAD requests is not evaluated,
+// as it calls for a 304 Not Modified response rather than an error.
+func CheckPreconditions(r *http.Request, etag string, modTime time.Time) error {
+	exists := etag != "" || !modTime.IsZero()
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, exists, true) {
+			return preconditionFailed("If-Match")
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !modTime.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && modTime.Truncate(time.Second).After(t) {
+			return preconditionFailed("If-Unmodified-Since")
+		}
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if inm := r.Header.Get("If-None-Match"); inm != "" && matchETag(inm, etag, exists, false) {
+			return preconditionFailed("If-None-Match")
+		}
+	}
+	return nil
+}
+
+func preconditionFailed(header string) error {
+	return Errorf(http.StatusPreconditionFailed, "precondition %s failed", header)
+}
+
+// matchETag reports whether the list of entity tags in an If-Match or
+// If-None-Match header matches etag, using strong or weak comparison.
+// The "*" list matches any existing resource.
+func matchETag(header, etag string, exists, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return exists
+	}
+	if etag == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strong {
+			if !strings.HasPrefix(tag, "W/") && !strings.HasPrefix(etag, "W/") && tag == etag {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// A Problem is a StatusError written as a problem details object (RFC
+// 9457, which obsoletes RFC 7807), in an application/problem+json body:
+//
+//	{"title": "Bad Request", "status": 400, "detail": "missing field name"}
+//
+// WriteError lets it write its own body, and WriteProblem writes every
+// error as a Problem, for APIs whose clients expect the format.
+type Problem struct {
+	// Type is a URI identifying the type of the problem, or "" for
+	// about:blank, where Title is the text of the status.
+	Type string `json:"type,omitempty"`
+
+	// Title is a short summary of the type of the problem.
+	Title string `json:"title,omitempty"`
+
+	// StatusCode is the HTTP status of the problem.
+	StatusCode int `json:"status"`
+
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is a URI identifying this occurrence of the problem, or "".
+	Instance string `json:"instance,omitempty"`
+}
+
+// NewProblem returns a Problem of type about:blank with status and
+// detail.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), StatusCode: status, Detail: detail}
+}
+
+// Error returns the detail of the problem, or its title.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// Status returns the status of the problem.
+func (p *Problem) Status() int {
+	return p.StatusCode
+}
+
+// WriteBody writes the problem as an application/problem+json body.
+func (p *Problem) WriteBody(w http.ResponseWriter, status int) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// WriteProblem is an ErrorWriter writing every error as a Problem,
+// those that are not Problems with their status and message as detail:
+//
+//	mux.OnError(httpx.WriteProblem)
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	var p *Problem
+	if !errors.As(err, &p) {
+		p = NewProblem(status, err.Error())
+	}
+	p.WriteBody(w, status)
+}
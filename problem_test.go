@@ -0,0 +1,52 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestProblem checks that Problems are written as problem details by
+// WriteError, and that WriteProblem writes other errors as Problems.
+func TestProblem(t *testing.T) {
+	m := NewMux()
+	m.Use(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return next.ServeHTTP(w, WithPrincipal(r, &Principal{ID: "u1"}))
+		})
+	})
+	m.With(RequireScopes("users:write")).Post("/users", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+	m.Get("/bad", func(w http.ResponseWriter, r *http.Request) error {
+		return NewProblem(http.StatusBadRequest, "missing field name")
+	})
+	m.Get("/fail", func(w http.ResponseWriter, r *http.Request) error {
+		return Error(http.StatusConflict, "taken")
+	})
+
+	check := func(path, method string, status int, detail string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var p Problem
+		if rec.Code != status || rec.Header().Get("Content-Type") != "application/problem+json" {
+			t.Fatalf("%s %s: status %d, Content-Type %q", method, path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil || p.StatusCode != status || p.Detail != detail {
+			t.Errorf("%s %s: body %q", method, path, rec.Body.String())
+		}
+	}
+	check("/bad", http.MethodGet, http.StatusBadRequest, "missing field name")
+	check("/users", http.MethodPost, http.StatusForbidden, "missing scope users:write")
+
+	m.OnError(WriteProblem)
+	check("/fail", http.MethodGet, http.StatusConflict, "taken")
+
+	var sErr StatusError
+	if !errors.As(error(NewProblem(http.StatusBadRequest, "bad")), &sErr) || sErr.Status() != http.StatusBadRequest {
+		t.Error("Problem is not a StatusError with its status")
+	}
+}
@@ -0,0 +1,125 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// ProxyOptions configures a ReverseProxy handler. The zero value
+// forwards requests to the target unchanged.
+type ProxyOptions struct {
+	// StripPrefix is removed from the request path before it is joined
+	// with the target path.
+	StripPrefix string
+
+	// Rewrite, when set, returns the path forwarded upstream, after
+	// StripPrefix has been applied.
+	Rewrite func(path string) string
+
+	// Header holds headers set on each upstream request.
+	Header http.Header
+
+	// RemoveHeader lists headers removed from each upstream request.
+	RemoveHeader []string
+
+	// ResponseHeader holds headers set on each upstream response.
+	ResponseHeader http.Header
+
+	// Transport is used to perform upstream requests. The default is
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Cache, when set, stores the cacheable upstream responses, and
+	// serves requests from them. A ProxyCache can be shared by the
+	// handlers of several routes.
+	Cache *ProxyCache
+}
+
+type proxyErrKey struct{}
+
+// ReverseProxy returns a handler that forwards requests to target.
+// Failures to reach the upstream are returned through the handler error
+// path: a 504 Gateway Timeout StatusError when the upstream timed out,
+// and a 502 Bad Gateway StatusError otherwise.
+//
+// To proxy a subtree, register the handler on a wildcard route:
+//
+//	mux.Handle("/api/*", httpx.ReverseProxy(target, httpx.ProxyOptions{
+//		StripPrefix: "/api",
+//	}))
+func ReverseProxy(target *url.URL, opts ProxyOptions) Handler {
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			path := pr.In.URL.Path
+			if opts.StripPrefix != "" {
+				path = strings.TrimPrefix(path, opts.StripPrefix)
+				if !strings.HasPrefix(path, "/") {
+					path = "/" + path
+				}
+			}
+			if opts.Rewrite != nil {
+				path = opts.Rewrite(path)
+			}
+			pr.Out.URL.Path = path
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.Host = pr.In.Host
+
+			for _, key := range opts.RemoveHeader {
+				pr.Out.Header.Del(key)
+			}
+			for key, values := range opts.Header {
+				pr.Out.Header[http.CanonicalHeaderKey(key)] = values
+			}
+		},
+		Transport: opts.Transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if perr, ok := r.Context().Value(proxyErrKey{}).(*error); ok {
+				*perr = err
+			}
+		},
+	}
+	if len(opts.ResponseHeader) > 0 {
+		rp.ModifyResponse = func(resp *http.Response) error {
+			for key, values := range opts.ResponseHeader {
+				resp.Header[http.CanonicalHeaderKey(key)] = values
+			}
+			return nil
+		}
+	}
+
+	upstream := HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		var perr error
+		ctx := context.WithValue(r.Context(), proxyErrKey{}, &perr)
+		rp.ServeHTTP(w, r.WithContext(ctx))
+		if perr == nil {
+			return nil
+		}
+		return upstreamError(r, perr)
+	})
+	if opts.Cache == nil {
+		return upstream
+	}
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return opts.Cache.serve(w, r, upstream)
+	})
+}
+
+// upstreamError converts an error reaching an upstream into a
+// StatusError.
+func upstreamError(r *http.Request, err error) error {
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		return r.Context().Err()
+	}
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout()) {
+		return Errorf(http.StatusGatewayTimeout, "upstream timeout: %v", err)
+	}
+	return Errorf(http.StatusBadGateway, "upstream error: %v", err)
+}
@@ -0,0 +1,397 @@
+package httpx
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eriklott/httpx/internal/record"
+)
+
+// ProxyCache is a shared HTTP cache (RFC 9111) of the responses of the
+// upstreams of ReverseProxy handlers, kept in memory. It lets a proxy
+// act as an edge in front of slow services:
+//
+//	cache := httpx.NewProxyCache(64 << 20)
+//	mux.Handle("/api/*", httpx.ReverseProxy(target, httpx.ProxyOptions{
+//		StripPrefix: "/api",
+//		Cache:       cache,
+//	}))
+//
+// Responses to GET requests are stored when their Cache-Control,
+// Expires or validators allow it, keyed by the host and URI of the
+// request and the request headers named by their Vary header. Fresh
+// responses are served from the cache, to GET and HEAD requests, and
+// stale responses with an ETag or Last-Modified header are revalidated
+// with a conditional upstream request. Concurrent requests missing the
+// same entry are coalesced into a single upstream request. Responses
+// carry an X-Cache header of HIT, MISS or REVALIDATED, and an Age
+// header when served from the cache.
+//
+// Responses marked private or no-store, responses setting cookies, and
+// responses to requests carrying an Authorization header, unless marked
+// public, are not stored. Requests with methods other than GET and HEAD
+// are forwarded and evict the entry of their URI.
+//
+// Responses are buffered while being fetched, so a ProxyCache doesn't
+// suit streamed responses.
+type ProxyCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // of *proxyEntry, most recently used first
+	entries map[string]*list.Element
+	flights map[string]*proxyFlight
+}
+
+// NewProxyCache returns a ProxyCache holding up to maxBytes of
+// responses, evicting the least recently used ones beyond.
+func NewProxyCache(maxBytes int64) *ProxyCache {
+	return &ProxyCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+		flights:  map[string]*proxyFlight{},
+	}
+}
+
+// proxyEntry is a response stored in a ProxyCache.
+type proxyEntry struct {
+	key        string
+	vary       []string
+	varyValues []string
+
+	status int
+	header http.Header
+	body   []byte
+
+	stored  time.Time
+	age     time.Duration // Age of the response when stored
+	fresh   time.Duration // freshness lifetime
+	noCache bool          // revalidated before each use
+}
+
+// proxyFlight is an upstream request shared by coalesced requests.
+type proxyFlight struct {
+	done  chan struct{}
+	entry *proxyEntry // stored response, or nil
+}
+
+// serve answers r from the cache, or with upstream, which performs the
+// upstream request.
+func (c *ProxyCache) serve(w http.ResponseWriter, r *http.Request, upstream HandlerFunc) error {
+	key := r.Host + r.URL.RequestURI()
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		c.evict(key)
+		return upstream(w, r)
+	}
+	reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+	if _, ok := reqCC["no-store"]; ok {
+		return upstream(w, r)
+	}
+
+	now := time.Now()
+	e := c.lookup(key, r)
+	if _, ok := reqCC["no-cache"]; !ok && e != nil && e.isFresh(now) {
+		return e.write(w, r, now, "HIT")
+	}
+	if r.Method == http.MethodHead {
+		return upstream(w, r)
+	}
+
+	c.mu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-r.Context().Done():
+			return r.Context().Err()
+		}
+		if f.entry != nil && f.entry.matches(r) {
+			return f.entry.write(w, r, time.Now(), "HIT")
+		}
+		_, err := c.fetch(w, r, key, e, upstream)
+		return err
+	}
+	f := &proxyFlight{done: make(chan struct{})}
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	var err error
+	f.entry, err = c.fetch(w, r, key, e, upstream)
+	return err
+}
+
+// fetch answers r with the response of an upstream request, revalidating
+// stale when it has validators, and returns the response stored in the
+// cache, if any.
+func (c *ProxyCache) fetch(w http.ResponseWriter, r *http.Request, key string, stale *proxyEntry, upstream HandlerFunc) (*proxyEntry, error) {
+	out := r.Clone(r.Context())
+	for _, h := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		out.Header.Del(h)
+	}
+	if stale != nil {
+		if etag := stale.header.Get("ETag"); etag != "" {
+			out.Header.Set("If-None-Match", etag)
+		}
+		if lm := stale.header.Get("Last-Modified"); lm != "" {
+			out.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	rec := &record.Recorder{}
+	if err := upstream(rec, out); err != nil {
+		return nil, err
+	}
+	if rec.Status == 0 {
+		rec.Status = http.StatusOK
+	}
+	now := time.Now()
+
+	if rec.Status == http.StatusNotModified && stale != nil {
+		header := stale.header.Clone()
+		for _, h := range []string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified", "Vary"} {
+			if v, ok := rec.Header()[h]; ok {
+				header[h] = v
+			}
+		}
+		if e := c.newEntry(key, r, stale.status, header, stale.body, now); e != nil {
+			c.store(e)
+			return e, e.write(w, r, now, "REVALIDATED")
+		}
+		c.evict(key)
+		e := *stale
+		e.header = header
+		e.stored, e.age = now, 0
+		return nil, e.write(w, r, now, "REVALIDATED")
+	}
+
+	body := rec.Body.Bytes()
+	if e := c.newEntry(key, r, rec.Status, rec.Header(), body, now); e != nil {
+		c.store(e)
+		return e, e.write(w, r, now, "MISS")
+	}
+	if stale != nil {
+		c.evict(key)
+	}
+	h := w.Header()
+	for k, v := range rec.Header() {
+		h[k] = v
+	}
+	h.Set("X-Cache", "MISS")
+	w.WriteHeader(rec.Status)
+	_, err := w.Write(body)
+	return nil, err
+}
+
+// cacheableStatus holds the statuses of responses a ProxyCache stores,
+// those cacheable by default (RFC 9110, section 15.1).
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// newEntry returns the entry storing the response to r, or nil when the
+// response can't be stored.
+func (c *ProxyCache) newEntry(key string, r *http.Request, status int, header http.Header, body []byte, now time.Time) *proxyEntry {
+	if !cacheableStatus[status] || int64(len(key)+len(body)) > c.maxBytes || len(header.Values("Set-Cookie")) > 0 {
+		return nil
+	}
+	cc := parseCacheControl(strings.Join(header.Values("Cache-Control"), ","))
+	if _, ok := cc["no-store"]; ok {
+		return nil
+	}
+	if _, ok := cc["private"]; ok {
+		return nil
+	}
+	if r.Header.Get("Authorization") != "" {
+		_, public := cc["public"]
+		_, sMaxAge := cc["s-maxage"]
+		_, mustRevalidate := cc["must-revalidate"]
+		if !public && !sMaxAge && !mustRevalidate {
+			return nil
+		}
+	}
+
+	e := &proxyEntry{
+		key:    key,
+		status: status,
+		header: header,
+		body:   body,
+		stored: now,
+	}
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return nil
+			}
+			if name != "" {
+				e.vary = append(e.vary, name)
+				e.varyValues = append(e.varyValues, strings.Join(r.Header.Values(name), ","))
+			}
+		}
+	}
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		e.age = time.Duration(age) * time.Second
+	}
+	_, e.noCache = cc["no-cache"]
+
+	if v, ok := cc["s-maxage"]; ok {
+		e.fresh = seconds(v)
+	} else if v, ok := cc["max-age"]; ok {
+		e.fresh = seconds(v)
+	} else if exp := header.Get("Expires"); exp != "" {
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = now
+		}
+		if t, err := http.ParseTime(exp); err == nil {
+			e.fresh = t.Sub(date)
+		}
+	}
+	if e.fresh <= 0 && header.Get("ETag") == "" && header.Get("Last-Modified") == "" {
+		return nil
+	}
+	return e
+}
+
+// seconds parses the delta-seconds of a Cache-Control directive.
+func seconds(v string) time.Duration {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// lookup returns the entry of key matching r, or nil.
+func (c *ProxyCache) lookup(key string, r *http.Request) *proxyEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*proxyEntry)
+	if !e.matches(r) {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+// store adds e to the cache, replacing the entry of its key and
+// evicting the least recently used entries beyond the size of the
+// cache.
+func (c *ProxyCache) store(e *proxyEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += e.size()
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// evict removes the entry of key.
+func (c *ProxyCache) evict(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *ProxyCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*proxyEntry)
+	delete(c.entries, e.key)
+	c.size -= e.size()
+}
+
+func (e *proxyEntry) size() int64 {
+	return int64(len(e.key) + len(e.body))
+}
+
+// matches reports whether r has the values of the request headers the
+// response of e varies by.
+func (e *proxyEntry) matches(r *http.Request) bool {
+	for i, name := range e.vary {
+		if strings.Join(r.Header.Values(name), ",") != e.varyValues[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// currentAge returns the age of the response of e at now.
+func (e *proxyEntry) currentAge(now time.Time) time.Duration {
+	return e.age + now.Sub(e.stored)
+}
+
+// isFresh reports whether e can be used without revalidation at now.
+func (e *proxyEntry) isFresh(now time.Time) bool {
+	return !e.noCache && e.currentAge(now) < e.fresh
+}
+
+// write answers r with the response of e, or with a 304 Not Modified
+// when the If-None-Match header of r matches its ETag.
+func (e *proxyEntry) write(w http.ResponseWriter, r *http.Request, now time.Time, xcache string) error {
+	h := w.Header()
+	for k, v := range e.header {
+		h[k] = append([]string(nil), v...)
+	}
+	h.Set("X-Cache", xcache)
+	if xcache == "HIT" {
+		h.Set("Age", strconv.Itoa(int(e.currentAge(now)/time.Second)))
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" && e.status == http.StatusOK &&
+		matchETag(inm, e.header.Get("ETag"), true, false) {
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	w.WriteHeader(e.status)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err := w.Write(e.body)
+	return err
+}
+
+// parseCacheControl returns the directives of a Cache-Control header,
+// with their lowercased names, and their unquoted values.
+func parseCacheControl(header string) map[string]string {
+	cc := map[string]string{}
+	for _, d := range strings.Split(header, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(d, "=")
+		cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return cc
+}
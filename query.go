@@ -0,0 +1,117 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryString returns the first value of the query parameter key, or
+// def when the parameter is absent or empty.
+func QueryString(r *http.Request, key, def string) string {
+	if v := r.URL.Query().Get(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// QueryInt returns the query parameter key parsed as an int, or def
+// when the parameter is absent or empty. A malformed value is reported
+// as a 400 Bad Request StatusError.
+func QueryInt(r *http.Request, key string, def int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def, Errorf(http.StatusBadRequest, "query parameter %q must be an integer", key)
+	}
+	return n, nil
+}
+
+// QueryBool returns the query parameter key parsed as a bool, or def
+// when the parameter is absent or empty. A malformed value is reported
+// as a 400 Bad Request StatusError.
+func QueryBool(r *http.Request, key string, def bool) (bool, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def, Errorf(http.StatusBadRequest, "query parameter %q must be a boolean", key)
+	}
+	return b, nil
+}
+
+// QueryTime returns the query parameter key parsed as an RFC 3339 time
+// or a date, or def when the parameter is absent or empty. A malformed
+// value is reported as a 400 Bad Request StatusError.
+func QueryTime(r *http.Request, key string, def time.Time) (time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	t, err := parseFormTime(v)
+	if err != nil {
+		return def, Errorf(http.StatusBadRequest, "query parameter %q must be an RFC 3339 time or a date", key)
+	}
+	return t, nil
+}
+
+// QueryStrings returns the values of the query parameter key. Repeated
+// parameters and comma separated values are both accepted, so
+// "?tag=a&tag=b" and "?tag=a,b" return the same values. Empty values
+// are dropped.
+func QueryStrings(r *http.Request, key string) []string {
+	var vals []string
+	for _, v := range r.URL.Query()[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				vals = append(vals, s)
+			}
+		}
+	}
+	return vals
+}
+
+// BindQuery decodes the query parameters of the request into v, which
+// must be a pointer to a struct, following the rules of DecodeForm. A
+// field tagged with `default:"..."` is set to the tag value when its
+// parameter is absent. Malformed values are reported as FieldErrors.
+func BindQuery(r *http.Request, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("httpx: BindQuery requires a non-nil pointer to a struct")
+	}
+
+	query := r.URL.Query()
+	values := make(url.Values, len(query))
+	for key, vals := range query {
+		values[key] = vals
+	}
+	t := rv.Elem().Type()
+	for name, idx := range formFields(t) {
+		def, ok := t.FieldByIndex(idx).Tag.Lookup("default")
+		if ok && !hasPrefixKey(values, name) {
+			values[name] = []string{def}
+		}
+	}
+	return DecodeForm(values, v)
+}
+
+// hasPrefixKey reports whether values has the key name, or a key
+// addressing a value nested within name.
+func hasPrefixKey(values url.Values, name string) bool {
+	for key := range values {
+		if key == name || strings.HasPrefix(key, name+".") || strings.HasPrefix(key, name+"[") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// RealIP is a middleware that sets r.RemoteAddr to the address of the
+// client that originated the request. The X-Forwarded-For, X-Real-IP
+// and Forwarded headers are only consulted when the peer is one of the
+// trusted proxies, and X-Forwarded-For and Forwarded chains are walked
+// from the right, skipping trusted proxies, so a client cannot spoof
+// its address by sending the headers itself.
+//
+// The resolved address is available to handlers with ClientIP.
+func RealIP(trustedProxies ...netip.Prefix) Middleware {
+	trusted := func(addr netip.Addr) bool {
+		return containsAddr(trustedProxies, addr)
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			peer, ok := remoteAddr(r.RemoteAddr)
+			if !ok {
+				return next.ServeHTTP(w, r)
+			}
+
+			ip := peer
+			if trusted(peer) {
+				ip = forwardedFor(r.Header, trusted, peer)
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip))
+			r.RemoteAddr = ip.String()
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address of the client that originated the
+// request, as resolved by RealIP, or the peer address of the request
+// when RealIP is not in use. The result is the zero Addr when the
+// address can't be parsed.
+func ClientIP(r *http.Request) netip.Addr {
+	if ip, ok := r.Context().Value(clientIPKey{}).(netip.Addr); ok {
+		return ip
+	}
+	ip, _ := remoteAddr(r.RemoteAddr)
+	return ip
+}
+
+// remoteAddr parses an address in either "host:port" or "host" form,
+// IPv6 hosts being in brackets, as in "[2001:db8::1]:4711" or
+// "[2001:db8::1]", or bare.
+func remoteAddr(addr string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	} else if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		addr = addr[1 : len(addr)-1]
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+// forwardedFor returns the right-most untrusted address declared by
+// the forwarding headers, or peer when the headers declare none.
+func forwardedFor(h http.Header, trusted func(netip.Addr) bool, peer netip.Addr) netip.Addr {
+	var hops []string
+	if fwd := h.Values("Forwarded"); len(fwd) > 0 {
+		hops = parseForwarded(fwd)
+	} else if xff := h.Values("X-Forwarded-For"); len(xff) > 0 {
+		for _, v := range xff {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+	} else if xrip := h.Get("X-Real-IP"); xrip != "" {
+		hops = []string{xrip}
+	}
+
+	ip := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := remoteAddr(strings.Trim(strings.TrimSpace(hops[i]), `"`))
+		if !ok {
+			break
+		}
+		ip = hop
+		if !trusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// parseForwarded returns the for= parameters of RFC 7239 Forwarded
+// header values, in order.
+func parseForwarded(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, val)
+				}
+			}
+		}
+	}
+	return hops
+}
@@ -0,0 +1,39 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+// TestRealIPForwardedPorts checks that forwarded addresses are parsed
+// with or without a port.
+func TestRealIPForwardedPorts(t *testing.T) {
+	proxies := netip.MustParsePrefix("10.0.0.0/8")
+	tests := []struct {
+		header, value, want string
+	}{
+		{"X-Forwarded-For", "203.0.113.7", "203.0.113.7"},
+		{"X-Forwarded-For", "203.0.113.7:4711, 10.0.0.2", "203.0.113.7"},
+		{"X-Forwarded-For", "[2001:db8::1]:4711", "2001:db8::1"},
+		{"X-Forwarded-For", "2001:db8::1", "2001:db8::1"},
+		{"Forwarded", `for="[2001:db8::1]:4711"`, "2001:db8::1"},
+		{"Forwarded", `for="[2001:db8::1]"`, "2001:db8::1"},
+		{"Forwarded", "for=203.0.113.7:80;proto=https", "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		var got netip.Addr
+		h := RealIP(proxies)(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			got = ClientIP(r)
+			return nil
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set(tt.header, tt.value)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if got.String() != tt.want {
+			t.Errorf("%s: %s: got %s, want %s", tt.header, tt.value, got, tt.want)
+		}
+	}
+}
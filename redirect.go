@@ -0,0 +1,56 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Redirect replies to the request with a redirect to target, which may
+// be a path relative to the request path. Code must be a 3xx status.
+func Redirect(w http.ResponseWriter, r *http.Request, code int, target string) error {
+	if code < 300 || code > 399 {
+		return fmt.Errorf("httpx: invalid redirect status %d", code)
+	}
+	http.Redirect(w, r, target, code)
+	return nil
+}
+
+// RedirectWithQuery is like Redirect, but carries the query parameters
+// of the request over to target. Parameters set by target take
+// precedence.
+func RedirectWithQuery(w http.ResponseWriter, r *http.Request, code int, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	for key, vals := range u.Query() {
+		q[key] = vals
+	}
+	u.RawQuery = q.Encode()
+	return Redirect(w, r, code, u.String())
+}
+
+// RedirectToRoute redirects the request with a 303 See Other to the
+// named route of the Mux that routed the request, built with params as
+// by Mux.URL.
+func RedirectToRoute(w http.ResponseWriter, r *http.Request, name string, params ...string) error {
+	target, err := URLFor(r, name, params...)
+	if err != nil {
+		return err
+	}
+	return Redirect(w, r, http.StatusSeeOther, target)
+}
+
+// SeeOther redirects the request to target with a 303 See Other, the
+// status for redirecting after a form post.
+func SeeOther(w http.ResponseWriter, r *http.Request, target string) error {
+	return Redirect(w, r, http.StatusSeeOther, target)
+}
+
+// PermanentRedirect redirects the request to target with a 308
+// Permanent Redirect, which preserves the request method and body.
+func PermanentRedirect(w http.ResponseWriter, r *http.Request, target string) error {
+	return Redirect(w, r, http.StatusPermanentRedirect, target)
+}
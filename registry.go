@@ -0,0 +1,64 @@
+package httpx
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A MiddlewareFactory builds a middleware from an options map, such as
+// the options of a middleware in a MuxConfig.
+type MiddlewareFactory func(options map[string]interface{}) (Middleware, error)
+
+// middlewareFactories is the registry of named middlewares.
+var middlewareFactories = struct {
+	sync.RWMutex
+	byName map[string]MiddlewareFactory
+}{byName: map[string]MiddlewareFactory{}}
+
+// RegisterMiddleware registers the factory of the middleware name,
+// replacing any factory previously registered for it. Registered
+// middlewares can be referenced by name in a MuxConfig, and carry their
+// name in RouteInfo.Middlewares:
+//
+//	httpx.RegisterMiddleware("timeout", func(opts map[string]interface{}) (httpx.Middleware, error) {
+//		d, err := time.ParseDuration(fmt.Sprint(opts["duration"]))
+//		if err != nil {
+//			return nil, err
+//		}
+//		return httpx.Timeout(d), nil
+//	})
+func RegisterMiddleware(name string, factory MiddlewareFactory) {
+	middlewareFactories.Lock()
+	defer middlewareFactories.Unlock()
+	middlewareFactories.byName[name] = factory
+}
+
+// LookupMiddleware returns the middleware registered as name, built
+// with options, as a Chain carrying the name.
+func LookupMiddleware(name string, options map[string]interface{}) (Chain, error) {
+	middlewareFactories.RLock()
+	factory, ok := middlewareFactories.byName[name]
+	middlewareFactories.RUnlock()
+	if !ok {
+		return Chain{}, fmt.Errorf("httpx: unknown middleware %q", name)
+	}
+	mw, err := factory(options)
+	if err != nil {
+		return Chain{}, fmt.Errorf("httpx: middleware %q: %w", name, err)
+	}
+	return NewNamed(name, mw), nil
+}
+
+// RegisteredMiddlewares returns the names of the registered
+// middlewares, sorted.
+func RegisteredMiddlewares() []string {
+	middlewareFactories.RLock()
+	defer middlewareFactories.RUnlock()
+	names := make([]string, 0, len(middlewareFactories.byName))
+	for name := range middlewareFactories.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,101 @@
+package httpx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// A Codec encodes and decodes values in a media type.
+type Codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// codecs is the registry of media types handled by Render and Bind.
+var codecs = struct {
+	sync.RWMutex
+	types  []string
+	byType map[string]Codec
+}{
+	types: []string{"application/json", "application/xml", "text/xml"},
+	byType: map[string]Codec{
+		"application/json": jsonCodec{},
+		"application/xml":  xmlCodec{},
+		"text/xml":         xmlCodec{},
+	},
+}
+
+// RegisterCodec registers the codec used for a media type, replacing
+// any codec previously registered for it. Newly registered media types
+// are offered after the existing ones, so JSON remains the response
+// type for clients that accept any type.
+func RegisterCodec(mediaType string, c Codec) {
+	mediaType = strings.ToLower(mediaType)
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.byType[mediaType]; !ok {
+		codecs.types = append(codecs.types, mediaType)
+	}
+	codecs.byType[mediaType] = c
+}
+
+// MediaTypes returns the media types of the registered codecs, in order
+// of preference.
+func MediaTypes() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return append([]string(nil), codecs.types...)
+}
+
+// lookupCodec returns the codec registered for a media type.
+func lookupCodec(mediaType string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.byType[strings.ToLower(mediaType)]
+	return c, ok
+}
+
+// Render writes v with a 200 OK status, encoded with the registered
+// codec best matching the Accept header of the request. Render returns
+// a 406 Not Acceptable StatusError when no codec is acceptable.
+func Render(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return RenderStatus(w, r, http.StatusOK, v)
+}
+
+// RenderStatus is like Render, but writes the response with status.
+func RenderStatus(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	mediaType := Negotiate(r, MediaTypes()...)
+	if mediaType == "" {
+		return Error(http.StatusNotAcceptable, http.StatusText(http.StatusNotAcceptable))
+	}
+	c, _ := lookupCodec(mediaType)
+
+	addVary(w.Header(), "Accept")
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	return c.Encode(w, v)
+}
@@ -0,0 +1,170 @@
+// Package render renders html/template pages composed of layouts and
+// partials loaded from an fs.FS. An Engine is an httpx.HTMLRenderer:
+//
+//	engine, err := render.New(render.Options{FS: templates})
+//	mux.Use(httpx.UseHTMLRenderer(engine))
+//	mux.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+//		return httpx.HTML(w, http.StatusOK, "home", data)
+//	})
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Options configures an Engine.
+type Options struct {
+	// FS holds the template files.
+	FS fs.FS
+
+	// Layouts, Partials and Pages are fs.Glob patterns of the layout,
+	// partial and page templates. The defaults are "layouts/*.html",
+	// "partials/*.html" and "pages/*.html".
+	Layouts, Partials, Pages string
+
+	// Layout is the name of the template executed to render a page, when
+	// it is defined by the layouts or the page. Pages fill the layout by
+	// defining the blocks it declares. The default is "layout".
+	Layout string
+
+	// Funcs are added to the function map of all templates.
+	Funcs template.FuncMap
+
+	// Values returns per-request values made available to templates,
+	// such as a CSRF token or flash messages.
+	Values func(r *http.Request) map[string]interface{}
+
+	// Reload parses the templates again on every render, so changes
+	// show up without a restart. It is meant for development.
+	Reload bool
+}
+
+// View is the data templates are executed with.
+type View struct {
+	// Data is the data passed to httpx.HTML.
+	Data interface{}
+
+	// Request is the request being responded to.
+	Request *http.Request
+
+	// Values holds the per-request values returned by Options.Values.
+	Values map[string]interface{}
+}
+
+// Engine renders pages. It is safe for concurrent use.
+type Engine struct {
+	opts Options
+
+	mu    sync.RWMutex
+	pages map[string]*page
+}
+
+// page is the template set of a page and the template executed to
+// render it.
+type page struct {
+	t     *template.Template
+	entry string
+}
+
+// New returns an Engine that has parsed the templates described by opts.
+func New(opts Options) (*Engine, error) {
+	if opts.Layouts == "" {
+		opts.Layouts = "layouts/*.html"
+	}
+	if opts.Partials == "" {
+		opts.Partials = "partials/*.html"
+	}
+	if opts.Pages == "" {
+		opts.Pages = "pages/*.html"
+	}
+	if opts.Layout == "" {
+		opts.Layout = "layout"
+	}
+
+	e := &Engine{opts: opts}
+	pages, err := e.parse()
+	if err != nil {
+		return nil, err
+	}
+	e.pages = pages
+	return e, nil
+}
+
+// parse parses a template set for each page, holding the layouts, the
+// partials and the page. Pages are named by their file name without
+// extension.
+func (e *Engine) parse() (map[string]*page, error) {
+	var shared []string
+	for _, pattern := range []string{e.opts.Layouts, e.opts.Partials} {
+		files, err := fs.Glob(e.opts.FS, pattern)
+		if err != nil {
+			return nil, err
+		}
+		shared = append(shared, files...)
+	}
+	pageFiles, err := fs.Glob(e.opts.FS, e.opts.Pages)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]*page, len(pageFiles))
+	for _, file := range pageFiles {
+		name := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		t := template.New(name).Funcs(e.opts.Funcs)
+		if len(shared) > 0 {
+			if t, err = t.ParseFS(e.opts.FS, shared...); err != nil {
+				return nil, err
+			}
+		}
+		if t, err = t.ParseFS(e.opts.FS, file); err != nil {
+			return nil, err
+		}
+		entry := path.Base(file)
+		if t.Lookup(e.opts.Layout) != nil {
+			entry = e.opts.Layout
+		}
+		pages[name] = &page{t: t, entry: entry}
+	}
+	return pages, nil
+}
+
+// RenderHTML renders the named page with data to w.
+func (e *Engine) RenderHTML(w io.Writer, r *http.Request, name string, data interface{}) error {
+	p, err := e.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	view := &View{Data: data, Request: r}
+	if e.opts.Values != nil && r != nil {
+		view.Values = e.opts.Values(r)
+	}
+	return p.t.ExecuteTemplate(w, p.entry, view)
+}
+
+func (e *Engine) lookup(name string) (*page, error) {
+	if e.opts.Reload {
+		pages, err := e.parse()
+		if err != nil {
+			return nil, err
+		}
+		e.mu.Lock()
+		e.pages = pages
+		e.mu.Unlock()
+	}
+
+	e.mu.RLock()
+	p, ok := e.pages[name]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("render: no page %q", name)
+	}
+	return p, nil
+}
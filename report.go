@@ -0,0 +1,66 @@
+package httpx
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+)
+
+// An ErrorReporter receives the server errors returned by handlers, to
+// send them to an error tracking or alerting service.
+type ErrorReporter interface {
+	Report(ctx context.Context, err error, r *http.Request)
+}
+
+// The ErrorReporterFunc type is an adapter to allow the use of ordinary
+// functions as ErrorReporters.
+type ErrorReporterFunc func(ctx context.Context, err error, r *http.Request)
+
+// Report calls fn(ctx, err, r).
+func (fn ErrorReporterFunc) Report(ctx context.Context, err error, r *http.Request) {
+	fn(ctx, err, r)
+}
+
+// ReportErrors sets the ErrorReporter of the Mux, and of any Mux derived
+// from it. Errors returned by the handlers of its routes that result in
+// a 5xx response, that is StatusErrors with a 5xx status and errors
+// that are not StatusErrors, are passed to rep before the error
+// response is written. ReportErrors must be called before the Mux
+// serves requests.
+func (m *Mux) ReportErrors(rep ErrorReporter) {
+	m.routes.reporter = rep
+}
+
+// report passes err to the reporter of the table, when it is a server
+// error.
+func (t *routeTable) report(r *http.Request, err error) {
+	if t == nil || t.reporter == nil {
+		return
+	}
+	if sErr, ok := err.(StatusError); ok && sErr.Status() < 500 {
+		return
+	}
+	t.reporter.Report(r.Context(), err, r)
+}
+
+// SampleErrors returns an ErrorReporter that passes a random fraction
+// rate, between 0 and 1, of the errors to rep, to bound the volume of
+// reports sent during an incident.
+func SampleErrors(rep ErrorReporter, rate float64) ErrorReporter {
+	return ErrorReporterFunc(func(ctx context.Context, err error, r *http.Request) {
+		if rand.Float64() < rate {
+			rep.Report(ctx, err, r)
+		}
+	})
+}
+
+// CaptureReporter returns an ErrorReporter that passes errors to
+// capture, the CaptureException method of a Sentry-style client or
+// hub:
+//
+//	mux.ReportErrors(httpx.CaptureReporter(sentry.CurrentHub().CaptureException))
+func CaptureReporter[T any](capture func(err error) T) ErrorReporter {
+	return ErrorReporterFunc(func(ctx context.Context, err error, r *http.Request) {
+		capture(err)
+	})
+}
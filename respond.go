@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NoContent writes a 204 No Content response.
+func NoContent(w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// Created writes a 201 Created response with a Location header of
+// location, when it is not empty, and body encoded as JSON, when it is
+// not nil. The location of a named route can be built with URLFor.
+func Created(w http.ResponseWriter, location string, body interface{}) error {
+	return respondJSON(w, http.StatusCreated, "Location", location, body)
+}
+
+// Accepted writes a 202 Accepted response for work that will complete
+// asynchronously, with a Location header of statusURL, when it is not
+// empty, where the client can poll for the outcome of the work.
+func Accepted(w http.ResponseWriter, statusURL string) error {
+	return respondJSON(w, http.StatusAccepted, "Location", statusURL, nil)
+}
+
+// JSON writes v encoded as JSON with status.
+func JSON(w http.ResponseWriter, status int, v interface{}) error {
+	return respondJSON(w, status, "", "", v)
+}
+
+func respondJSON(w http.ResponseWriter, status int, key, value string, body interface{}) error {
+	if key != "" && value != "" {
+		w.Header().Set(key, value)
+	}
+	if body == nil {
+		w.WriteHeader(status)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(body)
+}
+
+// CreatedAt is like Created, with the Location set to the named route
+// of the Mux that routed the request, built with params as by Mux.URL.
+func CreatedAt(w http.ResponseWriter, r *http.Request, body interface{}, name string, params ...string) error {
+	location, err := URLFor(r, name, params...)
+	if err != nil {
+		return err
+	}
+	return Created(w, location, body)
+}
@@ -0,0 +1,169 @@
+package httpx
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures the Retry client middleware. The zero value
+// is a usable policy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt. The default is 3.
+	MaxAttempts int
+
+	// MinBackoff is the base delay of the exponential backoff between
+	// attempts. The default is 100ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. A response whose
+	// Retry-After asks for a longer delay is not retried. The default
+	// is 10s.
+	MaxBackoff time.Duration
+
+	// ShouldRetry reports whether an attempt should be retried. The
+	// default retries transport errors and 429, 502, 503 and 504
+	// responses.
+	ShouldRetry func(resp *http.Response, err error) bool
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.MinBackoff <= 0 {
+		p.MinBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	if p.ShouldRetry == nil {
+		p.ShouldRetry = shouldRetry
+	}
+	return p
+}
+
+// backoff returns the delay before the given retry, using exponential
+// backoff with full jitter.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MaxBackoff
+	if retry < 32 {
+		if exp := p.MinBackoff << uint(retry); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Retry is a ClientMiddleware that resends failed requests according
+// to policy, with exponential backoff and jitter between attempts. A
+// Retry-After header on the failed response takes precedence over the
+// backoff.
+//
+// Only requests with an idempotent method, or carrying an
+// Idempotency-Key header, are retried. A request with a body is only
+// retried when its GetBody func is set, as it is for requests built
+// by Client.NewRequest.
+func Retry(policy RetryPolicy) ClientMiddleware {
+	policy = policy.withDefaults()
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if !retryable(r) {
+				return next.RoundTrip(r)
+			}
+
+			for attempt := 1; ; attempt++ {
+				req := r
+				if attempt > 1 && r.Body != nil && r.Body != http.NoBody {
+					body, err := r.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					req = r.Clone(r.Context())
+					req.Body = body
+				}
+
+				resp, err := next.RoundTrip(req)
+				if attempt >= policy.MaxAttempts || !policy.ShouldRetry(resp, err) {
+					return resp, err
+				}
+				if r.Context().Err() != nil {
+					return resp, err
+				}
+
+				delay := policy.backoff(attempt - 1)
+				if resp != nil {
+					if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+						if after > policy.MaxBackoff {
+							return resp, err
+						}
+						delay = after
+					}
+					io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+					resp.Body.Close()
+				}
+
+				t := time.NewTimer(delay)
+				select {
+				case <-r.Context().Done():
+					t.Stop()
+					return nil, r.Context().Err()
+				case <-t.C:
+				}
+			}
+		})
+	}
+}
+
+// retryable reports whether the request may safely be sent again.
+func retryable(r *http.Request) bool {
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+	if r.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,101 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// RewriteRule is a transformation of requests applied by Rewrite.
+type RewriteRule struct {
+	// Path, when set, is a regular expression the request path must
+	// match for the rule to apply. Rules without a Path apply to every
+	// request.
+	Path string
+
+	// To, when set, replaces the request path matched by Path, with
+	// the submatches of Path expanded as by regexp.Regexp.Expand, such
+	// as "/v2/users/$1" or "/v2/users/${id}".
+	To string
+
+	// RenameHeader renames request headers, from the key to the value.
+	RenameHeader map[string]string
+
+	// Header holds headers set on the request.
+	Header http.Header
+
+	// Query holds query params set on the request.
+	Query url.Values
+
+	// Last stops the rewriting of requests the rule applies to, so that
+	// the rules after it are skipped.
+	Last bool
+}
+
+// Rewrite returns a middleware transforming requests with rules, in
+// order, during API migrations or for legacy clients. It wraps a Mux,
+// so that the requests are rewritten before routing:
+//
+//	handler := httpx.Rewrite(
+//		httpx.RewriteRule{Path: `^/api/v1/users/(\d+)$`, To: "/api/v2/users/$1", Query: url.Values{"compat": {"v1"}}},
+//		httpx.RewriteRule{RenameHeader: map[string]string{"X-Auth-Token": "Authorization"}},
+//	)(mux)
+//
+// Within a Mux, where requests are already routed, HTTPMiddleware
+// adapts it to rewrite the headers and query of the requests of some
+// routes. Rewrite panics when the Path of a rule is not a valid regular
+// expression.
+func Rewrite(rules ...RewriteRule) func(http.Handler) http.Handler {
+	paths := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		if rule.Path != "" {
+			paths[i] = regexp.MustCompile(rule.Path)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cloned := false
+			for i, rule := range rules {
+				var match []int
+				if paths[i] != nil {
+					if match = paths[i].FindStringSubmatchIndex(r.URL.Path); match == nil {
+						continue
+					}
+				}
+				if !cloned {
+					r = r.Clone(r.Context())
+					cloned = true
+				}
+
+				if rule.To != "" && match != nil {
+					r.URL.Path = string(paths[i].ExpandString(nil, rule.To, r.URL.Path, match))
+					r.URL.RawPath = ""
+				}
+				for from, to := range rule.RenameHeader {
+					if values := r.Header.Values(from); len(values) > 0 {
+						r.Header.Del(from)
+						r.Header[http.CanonicalHeaderKey(to)] = values
+					}
+				}
+				for key, values := range rule.Header {
+					r.Header[http.CanonicalHeaderKey(key)] = values
+				}
+				if len(rule.Query) > 0 {
+					q := r.URL.Query()
+					for key, values := range rule.Query {
+						q[key] = values
+					}
+					r.URL.RawQuery = q.Encode()
+				}
+				if rule.Last {
+					break
+				}
+			}
+			if cloned {
+				r.RequestURI = r.URL.RequestURI()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
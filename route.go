@@ -0,0 +1,309 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// RouteInfo describes a route registered on a Mux.
+type RouteInfo struct {
+	// Method is the http method the route matches, or "*" for any method.
+	Method string
+
+	// Pattern is the full routing pattern, including any Route prefixes.
+	Pattern string
+
+	// Middlewares lists the names of the middlewares applied to the
+	// route, in the order a request passes through them.
+	Middlewares []string
+
+	// Metadata holds the values declared with the Meta route option.
+	Metadata map[string]interface{}
+
+	// Name is the name declared with the Name route option, if any.
+	Name string
+
+	// Precedence is the precedence declared with the Precedence route
+	// option.
+	Precedence int
+
+	// Handler identifies the route handler by the name of its function,
+	// or its type for handlers that are not functions.
+	Handler string
+
+	// Params holds the URL params of the path resolved by Mux.Match.
+	// It is nil for the routes of Mux.Routes and CurrentRoute, whose
+	// params are read with URLParam.
+	Params map[string]string
+
+	table *routeTable
+}
+
+// Meta returns the metadata value declared for the route under key.
+func (ri RouteInfo) Meta(key string) (interface{}, bool) {
+	v, ok := ri.Metadata[key]
+	return v, ok
+}
+
+// A RouteOption configures a single route as it is registered on a Mux.
+// Middlewares added by route options run after the Mux middleware stack,
+// immediately before the route handler.
+type RouteOption func(*routeOptions)
+
+type routeOptions struct {
+	chain      Chain
+	meta       map[string]interface{}
+	name       string
+	precedence int
+}
+
+// WithMiddleware returns a RouteOption that adds the middlewares of
+// chain to a single route.
+func WithMiddleware(chain Chain) RouteOption {
+	return func(ro *routeOptions) {
+		ro.chain = ro.chain.Extend(chain)
+	}
+}
+
+// Meta returns a RouteOption that declares a metadata value for a
+// route. Metadata is reported by Mux.Routes and is available to
+// middlewares and handlers through CurrentRoute, so that middlewares
+// such as Audit can be configured per route.
+func Meta(key string, value interface{}) RouteOption {
+	return func(ro *routeOptions) {
+		if ro.meta == nil {
+			ro.meta = map[string]interface{}{}
+		}
+		ro.meta[key] = value
+	}
+}
+
+// Name returns a RouteOption that names a route, so that URLs of the
+// route can be built with Mux.URL and URLFor, and requests redirected
+// to it with RedirectToRoute.
+func Name(name string) RouteOption {
+	return func(ro *routeOptions) {
+		ro.name = name
+	}
+}
+
+// Precedence returns a RouteOption that sets the precedence of a route
+// over other routes matching the same paths. Of the routes matching a
+// request, the one with the highest precedence wins; routes of equal
+// precedence are preferred as usual, static segments over params over
+// wildcards. The default precedence is 0.
+//
+//	mux.Get("/files/{name}", file)
+//	mux.Get("/files/*", tree, httpx.Precedence(1)) // wins over /files/{name}
+//
+// Mux.Candidates explains which route a path resolves to.
+func Precedence(n int) RouteOption {
+	return func(ro *routeOptions) {
+		ro.precedence = n
+	}
+}
+
+type routeKey struct{}
+
+// CurrentRoute returns the route that matched the request. The ok
+// result is false outside of a handler registered on a Mux.
+func CurrentRoute(r *http.Request) (ri *RouteInfo, ok bool) {
+	if rc := callOf(r); rc != nil && rc.ri != nil {
+		return rc.ri, true
+	}
+	return nil, false
+}
+
+// routeTable records the routes registered on a Mux and all of its
+// inline-Muxes.
+type routeTable struct {
+	mu     sync.Mutex
+	routes []RouteInfo
+	names  map[string]string
+
+	// autoHead is set by Mux.AutoHead.
+	autoHead bool
+
+	// reporter is set by Mux.ReportErrors.
+	reporter ErrorReporter
+
+	// noPool is set by Mux.DisablePooling.
+	noPool bool
+
+	// signingKeys are set by Mux.URLSigningKeys.
+	signingKeys [][]byte
+
+	// securitySink is set by Mux.SecurityEvents.
+	securitySink SecurityEventSink
+
+	// sanitize is set by Mux.Sanitize.
+	sanitize *SanitizeOptions
+}
+
+// add records a route. It panics when the route name is already used
+// by a route with another pattern.
+func (t *routeTable) add(ri RouteInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ri.Name != "" {
+		if pattern, ok := t.names[ri.Name]; ok && pattern != ri.Pattern {
+			panic("httpx: route name " + ri.Name + " is already used by " + pattern)
+		}
+		if t.names == nil {
+			t.names = map[string]string{}
+		}
+		t.names[ri.Name] = ri.Pattern
+	}
+	t.routes = append(t.routes, ri)
+}
+
+// pattern returns the pattern of the named route.
+func (t *routeTable) pattern(name string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pattern, ok := t.names[name]
+	return pattern, ok
+}
+
+// routeHandler records a route in the Mux route table and returns its
+// endpoint.
+func (m *Mux) routeHandler(method, pattern string, h Handler, opts []RouteOption) *endpoint {
+	ep := m.newEndpoint(method, pattern, h, opts)
+	m.routes.add(*ep.ri)
+	return ep
+}
+
+// newEndpoint returns the endpoint of a route: h wrapped by the Mux
+// middleware stack and the middlewares added by the route options.
+func (m *Mux) newEndpoint(method, pattern string, h Handler, opts []RouteOption) *endpoint {
+	ro := &routeOptions{chain: NewChain()}
+	for _, opt := range opts {
+		opt(ro)
+	}
+	chain := m.chain.Extend(ro.chain)
+
+	ri := &RouteInfo{
+		Method:      method,
+		Pattern:     m.prefix + pattern,
+		Middlewares: chain.Middlewares(),
+		Metadata:    ro.meta,
+		Name:        ro.name,
+		Precedence:  ro.precedence,
+		Handler:     handlerName(h),
+		table:       m.routes,
+	}
+
+	// The handler is composed once, here. Per request, only the route
+	// context and the copy of the request made by WithContext are
+	// allocated; the writer tracking the response is pooled.
+	return &endpoint{ri: ri, h: chain.Then(h), errs: m.errs}
+}
+
+// routeCall is the context of a routed request, carrying its RouteInfo
+// and URL params. It is not pooled, as handlers may keep the request
+// context past the response, for example with Detach.
+type routeCall struct {
+	context.Context
+	ri     *RouteInfo
+	params params
+	after  afterHooks
+}
+
+func (c *routeCall) Value(key interface{}) interface{} {
+	if key == (routeKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// callOf returns the routeCall of a request, or nil.
+func callOf(r *http.Request) *routeCall {
+	rc, _ := r.Context().Value(routeKey{}).(*routeCall)
+	return rc
+}
+
+// Routes returns the routes registered on the Mux, and on any Mux
+// derived from it with With, Group or Route, in registration order.
+func (m *Mux) Routes() []RouteInfo {
+	m.routes.mu.Lock()
+	defer m.routes.mu.Unlock()
+	routes := make([]RouteInfo, len(m.routes.routes))
+	copy(routes, m.routes.routes)
+	return routes
+}
+
+// Walk calls fn for each route registered on the Mux, in registration
+// order. Walk stops and returns the first error returned by fn.
+//
+// Walk is useful for auditing that a middleware is applied everywhere:
+//
+//	err := mux.Walk(func(ri httpx.RouteInfo) error {
+//		for _, name := range ri.Middlewares {
+//			if name == "auth" {
+//				return nil
+//			}
+//		}
+//		return fmt.Errorf("%s %s is not authenticated", ri.Method, ri.Pattern)
+//	})
+func (m *Mux) Walk(fn func(RouteInfo) error) error {
+	for _, ri := range m.Routes() {
+		if err := fn(ri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match returns the route a request with method and path is routed to,
+// with the URL params of path, for tests and custom dispatch, such as
+// batch endpoints routing their sub-requests:
+//
+//	ri, ok := mux.Match("GET", "/users/42")
+//	// ri.Pattern == "/users/{id}", ri.Params["id"] == "42"
+//
+// The ok result is false when no route matches, including when only a
+// Fallback or routes of other methods do.
+func (m *Mux) Match(method, path string) (RouteInfo, bool) {
+	var ps params
+	ep, _ := m.router.find(strings.ToUpper(method), path, &ps)
+	if ep == nil {
+		return RouteInfo{}, false
+	}
+	ri := *ep.ri
+	ri.Params = make(map[string]string, len(ps.keys))
+	for i, key := range ps.keys {
+		ri.Params[key] = ps.values[i]
+	}
+	return ri, true
+}
+
+// Candidates returns the routes matching a request with method and
+// path, in order of preference: the first is the route the request is
+// routed to, and the others explain which overlapping routes it won
+// over, such as /users/{id} for a request to /users/new routed to
+// /users/new. Candidates returns nil when no route matches.
+func (m *Mux) Candidates(method, path string) []RouteInfo {
+	if path == "" || path[0] != '/' {
+		return nil
+	}
+	var routes []RouteInfo
+	for _, c := range m.router.candidates(strings.ToUpper(method), path) {
+		routes = append(routes, *c.ep.ri)
+	}
+	return routes
+}
+
+// handlerName returns the name of the function of h, or the type of h.
+func handlerName(h Handler) string {
+	if fn, ok := h.(HandlerFunc); ok && fn != nil {
+		if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+			return f.Name()
+		}
+	}
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,478 @@
+package httpx
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// router is the routing tree shared by a Mux and the Muxes derived from
+// it. It matches a request path against the registered patterns one
+// segment at a time, preferring static segments over segments with a
+// regexp param, over segments with a plain param, over a trailing
+// wildcard, and backtracking when a preferred branch doesn't lead to a
+// route.
+//
+// Patterns use the syntax of chi: a {name} param matches a non-empty
+// part of a segment, a {name:regexp} param also has to match the
+// regexp, and a trailing * matches the rest of the path, including
+// slashes, as the "*" param.
+type router struct {
+	root             node
+	notFound         Handler
+	methodNotAllowed Handler
+	fallbacks        []fallback
+
+	// ordered is set when routes have a precedence, or firstMatch is set
+	// by Mux.FirstMatch, so that all the routes matching a path are
+	// collected and ranked instead of taking the first found.
+	ordered    bool
+	firstMatch bool
+	seq        int
+}
+
+// fallback is the endpoint of the paths beginning with prefix that match
+// no route, added by Mux.Fallback.
+type fallback struct {
+	prefix string
+	ep     *endpoint
+}
+
+// fallback returns the fallback with the longest prefix of path.
+func (rt *router) fallback(path string) (fb fallback, ok bool) {
+	for _, f := range rt.fallbacks {
+		if strings.HasPrefix(path, f.prefix) && len(f.prefix) >= len(fb.prefix) {
+			fb, ok = f, true
+		}
+	}
+	return fb, ok
+}
+
+// node matches a single path segment, and holds the routes of the
+// patterns ending with that segment.
+type node struct {
+	static    map[string]*node
+	dynamic   []*node
+	wildcard  *node
+	segment   string
+	tokens    []token
+	regexp    bool
+	endpoints map[string]*endpoint
+}
+
+// token is a literal or a param of a dynamic segment.
+type token struct {
+	literal string
+	param   string
+	re      *regexp.Regexp
+}
+
+// endpoint is the handler of a route, composed with its middlewares.
+type endpoint struct {
+	ri   *RouteInfo
+	h    Handler
+	errs *errorScope
+
+	// auto is set for the HEAD routes added by AutoHead, which give way
+	// to explicitly registered HEAD routes.
+	auto bool
+
+	// seq is the registration order of the endpoint.
+	seq int
+}
+
+// params holds the URL params of a request.
+type params struct {
+	keys, values []string
+}
+
+func (ps *params) get(key string) string {
+	for i := len(ps.keys) - 1; i >= 0; i-- {
+		if ps.keys[i] == key {
+			return ps.values[i]
+		}
+	}
+	return ""
+}
+
+func (ps *params) clone() params {
+	return params{
+		keys:   append([]string(nil), ps.keys...),
+		values: append([]string(nil), ps.values...),
+	}
+}
+
+func (ps *params) truncate(n int) {
+	ps.keys, ps.values = ps.keys[:n], ps.values[:n]
+}
+
+// add registers the endpoint of method, or "*" for any method, for
+// pattern. It replaces the endpoint previously registered for the same
+// pattern and method. add panics on a malformed pattern.
+func (rt *router) add(method, pattern string, ep *endpoint) {
+	if pattern == "" || pattern[0] != '/' {
+		panic("httpx: routing pattern must begin with '/' in '" + pattern + "'")
+	}
+	segments := splitPattern(pattern[1:])
+	n := &rt.root
+	for i, seg := range segments {
+		if strings.HasSuffix(seg, "*") {
+			if i != len(segments)-1 {
+				panic("httpx: wildcard '*' must be the last value in a route in '" + pattern + "'")
+			}
+			n = n.wildcardChild(seg, pattern)
+			break
+		}
+		n = n.child(seg, pattern)
+	}
+
+	if n.endpoints == nil {
+		n.endpoints = map[string]*endpoint{}
+	}
+	if prev := n.endpoints[method]; prev != nil && !prev.auto && ep.auto {
+		return
+	}
+	rt.seq++
+	ep.seq = rt.seq
+	if ep.ri.Precedence != 0 {
+		rt.ordered = true
+	}
+	n.endpoints[method] = ep
+}
+
+// splitPattern splits a pattern into segments at the slashes that are
+// not part of a param regexp.
+func splitPattern(pattern string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '/':
+			if depth == 0 {
+				segments = append(segments, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, pattern[start:])
+}
+
+// child returns the child of n for the pattern segment seg, creating it
+// when needed.
+func (n *node) child(seg, pattern string) *node {
+	if !strings.Contains(seg, "{") {
+		if c, ok := n.static[seg]; ok {
+			return c
+		}
+		if n.static == nil {
+			n.static = map[string]*node{}
+		}
+		c := &node{segment: seg}
+		n.static[seg] = c
+		return c
+	}
+
+	for _, c := range n.dynamic {
+		if c.segment == seg {
+			return c
+		}
+	}
+	c := &node{segment: seg, tokens: parseSegment(seg, pattern)}
+	for _, t := range c.tokens {
+		if t.re != nil {
+			c.regexp = true
+		}
+	}
+	// Keep the segments with a regexp param ahead of the others.
+	i := len(n.dynamic)
+	if c.regexp {
+		i = sort.Search(len(n.dynamic), func(i int) bool { return !n.dynamic[i].regexp })
+	}
+	n.dynamic = append(n.dynamic, nil)
+	copy(n.dynamic[i+1:], n.dynamic[i:])
+	n.dynamic[i] = c
+	return c
+}
+
+// wildcardChild returns the wildcard child of n for the pattern segment
+// seg, a literal followed by '*', creating it when needed.
+func (n *node) wildcardChild(seg, pattern string) *node {
+	if strings.ContainsAny(seg[:len(seg)-1], "{*") {
+		panic("httpx: wildcard '*' must follow a literal in '" + pattern + "'")
+	}
+	if n.wildcard != nil {
+		if n.wildcard.segment != seg {
+			panic("httpx: wildcard '" + seg + "' conflicts with '" + n.wildcard.segment + "' in '" + pattern + "'")
+		}
+		return n.wildcard
+	}
+	n.wildcard = &node{segment: seg}
+	return n.wildcard
+}
+
+// parseSegment splits a pattern segment into literals and params.
+func parseSegment(seg, pattern string) []token {
+	var tokens []token
+	for len(seg) > 0 {
+		start := strings.IndexByte(seg, '{')
+		if start < 0 {
+			tokens = append(tokens, token{literal: seg})
+			break
+		}
+		if start > 0 {
+			tokens = append(tokens, token{literal: seg[:start]})
+		}
+		end := closingBrace(seg, start)
+		if end < 0 {
+			panic("httpx: route param closing delimiter '}' is missing in '" + pattern + "'")
+		}
+		if len(tokens) > 0 && tokens[len(tokens)-1].param != "" {
+			panic("httpx: route params must be separated by a literal in '" + pattern + "'")
+		}
+		key, expr, hasRE := strings.Cut(seg[start+1:end], ":")
+		if key == "" {
+			panic("httpx: route param name is missing in '" + pattern + "'")
+		}
+		t := token{param: key}
+		if hasRE {
+			expr = strings.TrimSuffix(strings.TrimPrefix(expr, "^"), "$")
+			t.re = regexp.MustCompile("^(?:" + expr + ")$")
+		}
+		tokens = append(tokens, t)
+		seg = seg[end+1:]
+	}
+	return tokens
+}
+
+// find returns the endpoint matching method and path, storing the URL
+// params in ps. When no endpoint matches but the path matches routes of
+// other methods, find returns the node of those routes as miss.
+func (rt *router) find(method, path string, ps *params) (ep *endpoint, miss *node) {
+	if path == "" || path[0] != '/' {
+		return nil, nil
+	}
+	if rt.ordered {
+		if cs := rt.candidates(method, path); len(cs) > 0 {
+			*ps = cs[0].params
+			return cs[0].ep, nil
+		}
+	}
+	ep = rt.root.match(method, path, 1, ps, &miss)
+	return ep, miss
+}
+
+// candidate is an endpoint matching a path, with its URL params.
+type candidate struct {
+	ep     *endpoint
+	params params
+}
+
+// candidates returns the endpoints matching method and path, the
+// preferred first: by precedence, then in registration order when
+// firstMatch is set, then by the order of the routing tree.
+func (rt *router) candidates(method, path string) []candidate {
+	var cs []candidate
+	rt.root.collect(method, path, 1, &params{}, &cs)
+	sort.SliceStable(cs, func(i, j int) bool {
+		a, b := cs[i].ep, cs[j].ep
+		if a.ri.Precedence != b.ri.Precedence {
+			return a.ri.Precedence > b.ri.Precedence
+		}
+		return rt.firstMatch && a.seq < b.seq
+	})
+	return cs
+}
+
+// collect is like match, but adds all the endpoints matching the path
+// to cs, in the order match prefers them.
+func (n *node) collect(method, path string, start int, ps *params, cs *[]candidate) {
+	end := strings.IndexByte(path[start:], '/')
+	last := end < 0
+	if last {
+		end = len(path)
+	} else {
+		end += start
+	}
+	seg := path[start:end]
+
+	next := func(c *node) {
+		if !last {
+			c.collect(method, path, end+1, ps, cs)
+			return
+		}
+		var none *node
+		if ep := c.endpoint(method, &none); ep != nil {
+			*cs = append(*cs, candidate{ep, ps.clone()})
+		}
+	}
+
+	if c, ok := n.static[seg]; ok {
+		next(c)
+	}
+	for _, c := range n.dynamic {
+		mark := len(ps.keys)
+		if c.matchTokens(seg, ps) {
+			next(c)
+		}
+		ps.truncate(mark)
+	}
+	if c := n.wildcard; c != nil {
+		prefix := c.segment[:len(c.segment)-1]
+		if rest := path[start:]; strings.HasPrefix(rest, prefix) {
+			var none *node
+			if ep := c.endpoint(method, &none); ep != nil {
+				wps := ps.clone()
+				wps.keys = append(wps.keys, "*")
+				wps.values = append(wps.values, rest[len(prefix):])
+				*cs = append(*cs, candidate{ep, wps})
+			}
+		}
+	}
+}
+
+// match matches the children of n against the path from start, the
+// beginning of a segment.
+func (n *node) match(method, path string, start int, ps *params, miss **node) *endpoint {
+	end := strings.IndexByte(path[start:], '/')
+	last := end < 0
+	if last {
+		end = len(path)
+	} else {
+		end += start
+	}
+	seg := path[start:end]
+
+	next := func(c *node) *endpoint {
+		if last {
+			return c.endpoint(method, miss)
+		}
+		return c.match(method, path, end+1, ps, miss)
+	}
+
+	if c, ok := n.static[seg]; ok {
+		if ep := next(c); ep != nil {
+			return ep
+		}
+	}
+	for _, c := range n.dynamic {
+		mark := len(ps.keys)
+		if c.matchTokens(seg, ps) {
+			if ep := next(c); ep != nil {
+				return ep
+			}
+		}
+		ps.truncate(mark)
+	}
+	if c := n.wildcard; c != nil {
+		prefix := c.segment[:len(c.segment)-1]
+		if rest := path[start:]; strings.HasPrefix(rest, prefix) {
+			if ep := c.endpoint(method, miss); ep != nil {
+				ps.keys = append(ps.keys, "*")
+				ps.values = append(ps.values, rest[len(prefix):])
+				return ep
+			}
+		}
+	}
+	return nil
+}
+
+// matchTokens matches a path segment against the tokens of n, adding
+// its params to ps.
+func (n *node) matchTokens(seg string, ps *params) bool {
+	for i, t := range n.tokens {
+		if t.param == "" {
+			if !strings.HasPrefix(seg, t.literal) {
+				return false
+			}
+			seg = seg[len(t.literal):]
+			continue
+		}
+		v := seg
+		if i+1 < len(n.tokens) {
+			end := strings.Index(seg, n.tokens[i+1].literal)
+			if end < 0 {
+				return false
+			}
+			v = seg[:end]
+		}
+		if v == "" || (t.re != nil && !t.re.MatchString(v)) {
+			return false
+		}
+		ps.keys = append(ps.keys, t.param)
+		ps.values = append(ps.values, v)
+		seg = seg[len(v):]
+	}
+	return seg == ""
+}
+
+// endpoint returns the endpoint of n for method, recording n as miss
+// when it has endpoints for other methods only.
+func (n *node) endpoint(method string, miss **node) *endpoint {
+	if ep := n.endpoints[method]; ep != nil {
+		return ep
+	}
+	if ep := n.endpoints["*"]; ep != nil {
+		return ep
+	}
+	if len(n.endpoints) > 0 && *miss == nil {
+		*miss = n
+	}
+	return nil
+}
+
+// allow returns the methods of the endpoints of n, for an Allow header.
+func (n *node) allow() string {
+	methods := make([]string, 0, len(n.endpoints))
+	for m := range n.endpoints {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// serve routes a request.
+func (rt *router) serve(w http.ResponseWriter, r *http.Request, t *routeTable, errs *errorScope) {
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	rc := &routeCall{Context: r.Context()}
+	ep, miss := rt.find(r.Method, path, &rc.params)
+	var h Handler
+	switch {
+	case ep != nil:
+		rc.ri = ep.ri
+		h = ep.h
+		errs = ep.errs
+	case miss != nil:
+		w.Header().Set("Allow", miss.allow())
+		h = rt.methodNotAllowed
+		if h == nil {
+			h = ErrorHandler(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		}
+	default:
+		if fb, ok := rt.fallback(path); ok {
+			rc.ri = fb.ep.ri
+			rc.params.keys = append(rc.params.keys, "*")
+			rc.params.values = append(rc.params.values, path[len(fb.prefix):])
+			h = fb.ep.h
+			errs = fb.ep.errs
+			break
+		}
+		h = rt.notFound
+		if h == nil {
+			h = ErrorHandler(http.StatusNotFound, "404 page not found")
+		}
+	}
+
+	rw := t.getWriter(w)
+	serve(h, rw, r.WithContext(rc), t, errs)
+	t.putWriter(rw)
+	rc.after.run()
+}
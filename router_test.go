@@ -0,0 +1,223 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRouterMatch checks the route and params a path resolves to.
+func TestRouterMatch(t *testing.T) {
+	m := NewMux()
+	h := func(w http.ResponseWriter, r *http.Request) error { return nil }
+	for _, p := range []string{
+		"/",
+		"/users",
+		"/users/new",
+		"/users/{id}",
+		"/users/{id}/x",
+		"/files/{name}.{ext}",
+		`/articles/{id:\d+}`,
+		"/articles/{slug:[a-z-]+}",
+		"/articles/{any}",
+		`/api/v{version:\d+}/ping`,
+		"/static/*",
+		"/docs/v1-*",
+	} {
+		m.Get(p, h)
+	}
+
+	tests := []struct {
+		path, pattern string
+		params        map[string]string
+	}{
+		{"/", "/", nil},
+		{"/users", "/users", nil},
+		{"/users/new", "/users/new", nil},
+		{"/users/42", "/users/{id}", map[string]string{"id": "42"}},
+		{"/users/new/x", "/users/{id}/x", map[string]string{"id": "new"}},
+		{"/users/42/x", "/users/{id}/x", map[string]string{"id": "42"}},
+		{"/files/report.pdf", "/files/{name}.{ext}", map[string]string{"name": "report", "ext": "pdf"}},
+		{"/articles/12", `/articles/{id:\d+}`, map[string]string{"id": "12"}},
+		{"/articles/hello-world", "/articles/{slug:[a-z-]+}", map[string]string{"slug": "hello-world"}},
+		{"/articles/Hello_1", "/articles/{any}", map[string]string{"any": "Hello_1"}},
+		{"/api/v2/ping", `/api/v{version:\d+}/ping`, map[string]string{"version": "2"}},
+		{"/static/css/site.css", "/static/*", map[string]string{"*": "css/site.css"}},
+		{"/static/", "/static/*", map[string]string{"*": ""}},
+		{"/docs/v1-intro/setup", "/docs/v1-*", map[string]string{"*": "intro/setup"}},
+		{"/users/", "", nil},
+		{"/users/42/y", "", nil},
+		{"/api/vx/ping", "", nil},
+		{"/files/report", "", nil},
+		{"/nope", "", nil},
+	}
+	for _, tt := range tests {
+		ri, ok := m.Match(http.MethodGet, tt.path)
+		if tt.pattern == "" {
+			if ok {
+				t.Errorf("%s matched %s, want no route", tt.path, ri.Pattern)
+			}
+			continue
+		}
+		if !ok || ri.Pattern != tt.pattern {
+			t.Errorf("%s matched %q, want %s", tt.path, ri.Pattern, tt.pattern)
+			continue
+		}
+		if len(ri.Params) == 0 {
+			ri.Params = nil
+		}
+		if !reflect.DeepEqual(ri.Params, tt.params) {
+			t.Errorf("%s params %v, want %v", tt.path, ri.Params, tt.params)
+		}
+	}
+}
+
+// TestRouterServe checks the responses of routes, of paths matching
+// routes of other methods only, and of unknown paths.
+func TestRouterServe(t *testing.T) {
+	m := NewMux()
+	m.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte("get " + URLParam(r, "id")))
+		return err
+	})
+	m.Post("/items/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte("post " + URLParam(r, "id")))
+		return err
+	})
+	m.Handle("/any", HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte("any " + r.Method))
+		return err
+	}))
+
+	tests := []struct {
+		method, path string
+		status       int
+		body, allow  string
+	}{
+		{http.MethodGet, "/items/7", 200, "get 7", ""},
+		{http.MethodPost, "/items/7", 200, "post 7", ""},
+		{http.MethodDelete, "/items/7", 405, "", "GET, POST"},
+		{http.MethodPatch, "/any", 200, "any PATCH", ""},
+		{http.MethodGet, "/items", 404, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status || rec.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s: status %d, Allow %q", tt.method, tt.path, rec.Code, rec.Header().Get("Allow"))
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s %s: body %q, want %q", tt.method, tt.path, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+// TestRouterPrecedence checks that Precedence and FirstMatch override
+// the preference of specific routes, and that Candidates lists the
+// matching routes in order.
+func TestRouterPrecedence(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) error { return nil }
+	patterns := func(ris []RouteInfo) []string {
+		var ps []string
+		for _, ri := range ris {
+			ps = append(ps, ri.Pattern)
+		}
+		return ps
+	}
+
+	m := NewMux()
+	m.Get("/files/*", h)
+	m.Get("/files/{name}", h)
+	m.Get("/files/readme", h)
+	if got, want := patterns(m.Candidates("GET", "/files/readme")), []string{"/files/readme", "/files/{name}", "/files/*"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates %v, want %v", got, want)
+	}
+	if m.Candidates("GET", "/other") != nil {
+		t.Error("candidates for a path matching no route")
+	}
+
+	m = NewMux()
+	m.Get("/files/{name}", h)
+	m.Get("/files/*", h, Precedence(1))
+	if ri, _ := m.Match("GET", "/files/readme"); ri.Pattern != "/files/*" {
+		t.Errorf("matched %s, want the route of higher precedence", ri.Pattern)
+	}
+
+	m = NewMux()
+	m.FirstMatch()
+	m.Get("/files/*", h)
+	m.Get("/files/{name}", h)
+	if ri, _ := m.Match("GET", "/files/readme"); ri.Pattern != "/files/*" {
+		t.Errorf("matched %s with FirstMatch, want the first registered", ri.Pattern)
+	}
+}
+
+// TestRouterFallback checks that the Fallback of the longest prefix
+// serves the paths matching no route.
+func TestRouterFallback(t *testing.T) {
+	m := NewMux()
+	m.Get("/api/users", func(w http.ResponseWriter, r *http.Request) error { return nil })
+	m.Fallback(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte("spa " + URLParam(r, "*")))
+		return err
+	}))
+	m.Route("/api", func(m *Mux) {
+		m.Fallback(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return Error(http.StatusNotFound, "api "+URLParam(r, "*"))
+		}))
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/api/users", 200, ""},
+		{"/settings/profile", 200, "spa settings/profile"},
+		{"/api/orders", 404, "api orders\n"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status || rec.Body.String() != tt.body {
+			t.Errorf("GET %s: %d %q, want %d %q", tt.path, rec.Code, rec.Body.String(), tt.status, tt.body)
+		}
+	}
+	if _, ok := m.Match("GET", "/settings"); ok {
+		t.Error("Match reported a route for a path served by a Fallback")
+	}
+}
+
+// TestRouterBadPatterns checks that invalid patterns panic when they
+// are registered.
+func TestRouterBadPatterns(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		panic    string
+	}{
+		{[]string{"users"}, "must begin with '/'"},
+		{[]string{"/files/*/x"}, "must be the last value"},
+		{[]string{"/files/{name}*"}, "must follow a literal"},
+		{[]string{"/a/x*", "/a/y*"}, "conflicts with"},
+		{[]string{"/users/{id"}, "closing delimiter"},
+		{[]string{"/users/{a}{b}"}, "separated by a literal"},
+		{[]string{"/users/{}"}, "param name is missing"},
+	}
+	h := func(w http.ResponseWriter, r *http.Request) error { return nil }
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				v := recover()
+				if s, _ := v.(string); !strings.Contains(s, tt.panic) {
+					t.Errorf("%v: panic %v, want %q", tt.patterns, v, tt.panic)
+				}
+			}()
+			m := NewMux()
+			for _, p := range tt.patterns {
+				m.Get(p, h)
+			}
+		}()
+	}
+}
@@ -0,0 +1,115 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SanitizeOptions configures the request checks of Mux.Sanitize.
+type SanitizeOptions struct {
+	// Methods lists the request methods allowed. The default allows GET,
+	// HEAD, POST, PUT, PATCH, DELETE and OPTIONS.
+	Methods []string
+
+	// MaxHeaderBytes caps the size of a single request header, its name
+	// and value. The default is 8 KiB; a negative value disables the
+	// check. The total size of the headers is capped by the server, see
+	// Limits.
+	MaxHeaderBytes int
+
+	// AllowNullBytes disables the rejection of requests with null bytes
+	// in their path, query or headers.
+	AllowNullBytes bool
+
+	// AllowMalformedEncoding disables the rejection of requests whose
+	// path or query holds a % not followed by two hex digits.
+	AllowMalformedEncoding bool
+
+	// Check, when set, is called after the other checks, and rejects
+	// the request when it returns an error. An error that is not a
+	// StatusError is sent with a 400 Bad Request status.
+	Check func(r *http.Request) error
+}
+
+// Sanitize makes the Mux reject malformed requests with a 400 Bad
+// Request StatusError before routing them, so that neither the router
+// nor any handler sees them: requests with disallowed methods, null
+// bytes, oversized headers or malformed percent-encoding. Rejected
+// requests are reported to the SecurityEventSink of the Mux.
+//
+//	mux.Sanitize(httpx.SanitizeOptions{Methods: []string{"GET", "POST"}})
+//
+// Sanitize must be called before the Mux serves requests.
+func (m *Mux) Sanitize(opts SanitizeOptions) {
+	if opts.Methods == nil {
+		opts.Methods = []string{
+			http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodOptions,
+		}
+	}
+	if opts.MaxHeaderBytes == 0 {
+		opts.MaxHeaderBytes = 8 << 10
+	}
+	m.routes.sanitize = &opts
+}
+
+// check returns an error rejecting r, or nil when r passes the checks.
+func (opts *SanitizeOptions) check(r *http.Request) error {
+	if !contains(opts.Methods, r.Method) {
+		return Errorf(http.StatusBadRequest, "method %s not allowed", r.Method)
+	}
+	if !opts.AllowNullBytes {
+		if strings.Contains(r.URL.Path, "\x00") {
+			return Error(http.StatusBadRequest, "null byte in path")
+		}
+		if strings.Contains(r.URL.RawQuery, "\x00") || strings.Contains(r.URL.RawQuery, "%00") {
+			return Error(http.StatusBadRequest, "null byte in query")
+		}
+	}
+	if !opts.AllowMalformedEncoding {
+		if !validPercentEncoding(r.URL.RawPath) {
+			return Error(http.StatusBadRequest, "malformed path encoding")
+		}
+		if !validPercentEncoding(r.URL.RawQuery) {
+			return Error(http.StatusBadRequest, "malformed query encoding")
+		}
+	}
+	for name, values := range r.Header {
+		for _, v := range values {
+			if opts.MaxHeaderBytes > 0 && len(name)+len(v) > opts.MaxHeaderBytes {
+				return Errorf(http.StatusBadRequest, "header %s too large", name)
+			}
+			if !opts.AllowNullBytes && strings.Contains(v, "\x00") {
+				return Errorf(http.StatusBadRequest, "null byte in header %s", name)
+			}
+		}
+	}
+	if opts.Check != nil {
+		if err := opts.Check(r); err != nil {
+			if _, ok := err.(StatusError); ok {
+				return err
+			}
+			return Error(http.StatusBadRequest, err.Error())
+		}
+	}
+	return nil
+}
+
+// validPercentEncoding reports whether every % in s starts an escape of
+// two hex digits.
+func validPercentEncoding(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			return false
+		}
+		i += 2
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,147 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidPercentEncoding(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"", true},
+		{"/plain/path", true},
+		{"/a%20b", true},
+		{"/%2F%2f", true},
+		{"q=%E2%82%AC&x=1", true},
+		{"%", false},
+		{"/a%", false},
+		{"/a%2", false},
+		{"/a%zz", false},
+		{"/a%g0", false},
+		{"/a%0g/b", false},
+		{"%%41", false},
+		{"q=100%", false},
+	}
+	for _, tt := range tests {
+		if got := validPercentEncoding(tt.s); got != tt.want {
+			t.Errorf("validPercentEncoding(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestSanitize checks that malformed requests are rejected with a 400
+// before routing, and reported to the SecurityEventSink of the Mux.
+func TestSanitize(t *testing.T) {
+	var events []SecurityEvent
+	routed := false
+	m := NewMux()
+	m.SecurityEvents(SecurityEventSinkFunc(func(ctx context.Context, event SecurityEvent) {
+		events = append(events, event)
+	}))
+	m.Sanitize(SanitizeOptions{MaxHeaderBytes: 64})
+	m.Handle("/*", HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		routed = true
+		return nil
+	}))
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{"valid", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/files/a%20b?q=%E2%82%AC", nil)
+		}, http.StatusOK},
+		{"disallowed method", func() *http.Request {
+			return httptest.NewRequest("TRACE", "/", nil)
+		}, http.StatusBadRequest},
+		{"null byte in path", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/files/a%00.txt", nil)
+		}, http.StatusBadRequest},
+		{"escaped null byte in query", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/?name=a%00b", nil)
+		}, http.StatusBadRequest},
+		{"null byte in header", func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Name", "a\x00b")
+			return r
+		}, http.StatusBadRequest},
+		{"malformed query escape", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/?q=%zz", nil)
+		}, http.StatusBadRequest},
+		{"truncated query escape", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/?q=50%", nil)
+		}, http.StatusBadRequest},
+		{"malformed path escape", func() *http.Request {
+			// The server doesn't parse such a path into a URL, but a
+			// proxy or a middleware may build one.
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.URL.Path, r.URL.RawPath = "/a%zz", "/a%zz"
+			return r
+		}, http.StatusBadRequest},
+		{"oversized header", func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Large", strings.Repeat("x", 64))
+			return r
+		}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		routed = false
+		events = nil
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, tt.req())
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if rejected := tt.want != http.StatusOK; routed == rejected {
+			t.Errorf("%s: routed %v", tt.name, routed)
+		}
+		if tt.want != http.StatusOK && (len(events) != 1 || events[0].Kind != SecurityMalformedRequest) {
+			t.Errorf("%s: events %+v", tt.name, events)
+		}
+	}
+}
+
+// TestSanitizeAllow checks the options that disable the checks, and a
+// custom Check.
+func TestSanitizeAllow(t *testing.T) {
+	m := NewMux()
+	m.Sanitize(SanitizeOptions{
+		Methods:                []string{http.MethodGet},
+		MaxHeaderBytes:         -1,
+		AllowNullBytes:         true,
+		AllowMalformedEncoding: true,
+		Check: func(r *http.Request) error {
+			if r.Header.Get("X-Deny") != "" {
+				return errors.New("denied")
+			}
+			return nil
+		},
+	})
+	m.Get("/*", func(w http.ResponseWriter, r *http.Request) error { return nil })
+	status := func(r *http.Request) int {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/a%00?q=%zz", nil)
+	r.Header.Set("X-Large", strings.Repeat("x", 16<<10))
+	if got := status(r); got != http.StatusOK {
+		t.Errorf("allowed request: status %d", got)
+	}
+	if got := status(httptest.NewRequest(http.MethodPost, "/", nil)); got != http.StatusBadRequest {
+		t.Errorf("POST: status %d, want 400", got)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Deny", "1")
+	if got := status(r); got != http.StatusBadRequest {
+		t.Errorf("denied by Check: status %d, want 400", got)
+	}
+}
@@ -0,0 +1,118 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Security event kinds, reported by the middlewares of httpx, and by
+// middlewares outside of it with ReportSecurityEvent.
+const (
+	// SecurityAuthFailure is a request that failed to authenticate.
+	SecurityAuthFailure = "auth.failure"
+
+	// SecurityAccessDenied is an authenticated request that was denied.
+	SecurityAccessDenied = "access.denied"
+
+	// SecurityIPBlocked is a request rejected by an IP filter.
+	SecurityIPBlocked = "ip.blocked"
+
+	// SecurityThrottled is a request rejected for exceeding a limit,
+	// such as a rate limit or failed logins.
+	SecurityThrottled = "throttled"
+
+	// SecurityCaptchaFailure is a request without a valid captcha.
+	SecurityCaptchaFailure = "captcha.failure"
+
+	// SecurityInvalidSignature is a request with an invalid signature,
+	// such as a tampered signed URL or webhook.
+	SecurityInvalidSignature = "signature.invalid"
+
+	// SecurityCSRF is a request rejected by a CSRF protection.
+	SecurityCSRF = "csrf"
+
+	// SecurityMalformedRequest is a request rejected by Mux.Sanitize.
+	SecurityMalformedRequest = "request.malformed"
+)
+
+// SecurityEvent records a request denied for security reasons.
+type SecurityEvent struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Reason    string    `json:"reason"`
+	Principal string    `json:"principal,omitempty"`
+	ClientIP  string    `json:"client_ip"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Route     string    `json:"route,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// A SecurityEventSink receives security events, for example to forward
+// them to a SIEM. Sinks must be safe for concurrent use, and should not
+// block.
+type SecurityEventSink interface {
+	Record(ctx context.Context, event SecurityEvent)
+}
+
+// The SecurityEventSinkFunc type is an adapter to allow the use of
+// ordinary functions as security event sinks.
+type SecurityEventSinkFunc func(ctx context.Context, event SecurityEvent)
+
+// Record calls fn(ctx, event).
+func (fn SecurityEventSinkFunc) Record(ctx context.Context, event SecurityEvent) {
+	fn(ctx, event)
+}
+
+// SecurityEvents sets the SecurityEventSink of the Mux, and of any Mux
+// derived from it. The auth, authorization, IP filter, login lockout,
+// captcha and signature middlewares, and Mux.Sanitize, report the
+// requests they deny to sink, wherever they are in the middleware
+// stacks of the routes. Rate limit and CSRF middlewares, which httpx
+// leaves to other packages, report theirs with ReportSecurityEvent, as
+// SecurityThrottled and SecurityCSRF events. SecurityEvents must be
+// called before the Mux serves requests.
+func (m *Mux) SecurityEvents(sink SecurityEventSink) {
+	m.routes.securitySink = sink
+}
+
+// ReportSecurityEvent reports a request denied for reason to the
+// SecurityEventSink of the Mux that routed it, if any. It is used by
+// security middlewares, including those outside of httpx.
+func ReportSecurityEvent(r *http.Request, kind, reason string) {
+	if ri, ok := CurrentRoute(r); ok {
+		ri.table.securityEvent(r, ri.Pattern, kind, reason)
+	}
+}
+
+// securityEvent reports a security event for r, matching route, to the
+// sink of the table, if any.
+func (t *routeTable) securityEvent(r *http.Request, route, kind, reason string) {
+	if t.securitySink == nil {
+		return
+	}
+	event := SecurityEvent{
+		Time:      time.Now(),
+		Kind:      kind,
+		Reason:    reason,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Route:     route,
+		RequestID: RequestID(r),
+	}
+	if ip := ClientIP(r); ip.IsValid() {
+		event.ClientIP = ip.String()
+	}
+	if p, ok := PrincipalFrom(r); ok {
+		event.Principal = p.ID
+	}
+	t.securitySink.Record(r.Context(), event)
+}
+
+// securityError reports a security event for r and returns a
+// StatusError for it.
+func securityError(r *http.Request, kind string, status int, message string) error {
+	ReportSecurityEvent(r, kind, message)
+	return Error(status, message)
+}
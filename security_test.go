@@ -0,0 +1,36 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+// TestSecurityEvents checks that the requests denied by the middlewares
+// of httpx and by other middlewares reach the sink of the Mux.
+func TestSecurityEvents(t *testing.T) {
+	var events []SecurityEvent
+	m := NewMux()
+	m.SecurityEvents(SecurityEventSinkFunc(func(ctx context.Context, event SecurityEvent) {
+		events = append(events, event)
+	}))
+	h := func(w http.ResponseWriter, r *http.Request) error { return nil }
+	m.With(IPFilter(nil, []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})).Get("/admin", h)
+	m.With(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			ReportSecurityEvent(r, SecurityCSRF, "cross-origin request")
+			return Error(http.StatusForbidden, "cross-origin request")
+		})
+	}).Post("/form", h)
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin", nil))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/form", nil))
+	if len(events) != 2 || events[0].Kind != SecurityIPBlocked || events[1].Kind != SecurityCSRF {
+		t.Fatalf("events %+v", events)
+	}
+	if e := events[1]; e.Route != "/form" || e.Method != http.MethodPost || e.ClientIP != "192.0.2.1" {
+		t.Errorf("event %+v", e)
+	}
+}
@@ -0,0 +1,185 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Server is an HTTP server that drains in-flight requests on shutdown.
+// Once Shutdown is called, requests arriving on open connections are
+// rejected with a 503 Service Unavailable, and Shutdown blocks until
+// the outstanding handlers have finished.
+type Server struct {
+	hs       *http.Server
+	handler  http.Handler
+	drainer  *Drainer
+	acme     *autocert.Manager
+	redirect *http.Server
+	maxConns int
+	extra    []*extraListener
+}
+
+// extraListener is a listener added to a Server with Listen.
+type extraListener struct {
+	hs       *http.Server
+	maxConns int
+}
+
+// NewServer returns a newly initialized Server that serves handler,
+// usually a Mux, on addr, with the default Limits.
+func NewServer(addr string, handler http.Handler) *Server {
+	s := &Server{handler: handler, drainer: NewDrainer()}
+	s.hs = &http.Server{
+		Addr:    addr,
+		Handler: s.track(handler),
+	}
+	Limits{}.apply(s.hs)
+	return s
+}
+
+// track wraps handler to count in-flight requests with the Server
+// Drainer.
+func (s *Server) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.drainer.enter() {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.drainer.exit()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Listen makes the Server also serve handler on addr, within limits,
+// for example an admin Mux with metrics, profiling and health checks on
+// a private port next to the public API:
+//
+//	srv := httpx.NewServer(":443", api)
+//	srv.Listen("127.0.0.1:9090", admin, httpx.Limits{MaxConns: 16})
+//	err := srv.ListenAndServeTLS(certFile, keyFile)
+//
+// The additional addresses are bound along with the Server's own, and
+// the Server fails to start, without serving any request, when one of
+// them can't be bound. Once serving, an error from any listener stops
+// all of them. Shutdown drains the requests of all listeners and stops
+// them together.
+func (s *Server) Listen(addr string, handler http.Handler, limits Limits) {
+	hs := &http.Server{
+		Addr:    addr,
+		Handler: s.track(handler),
+	}
+	limits.apply(hs)
+	s.extra = append(s.extra, &extraListener{hs: hs, maxConns: limits.MaxConns})
+}
+
+// ListenAndServe listens on the Server address and serves requests
+// until the Server is shut down. Like http.Server, it always returns a
+// non-nil error; after Shutdown the error is http.ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	if len(s.extra) == 0 && s.maxConns <= 0 {
+		return s.hs.ListenAndServe()
+	}
+	addr := s.hs.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.serveAll(l, s.hs.Serve, nil)
+}
+
+// Serve accepts connections on l and serves requests until the Server
+// is shut down.
+func (s *Server) Serve(l net.Listener) error {
+	if len(s.extra) == 0 {
+		return s.hs.Serve(limitListener(l, s.maxConns))
+	}
+	return s.serveAll(l, s.hs.Serve, nil)
+}
+
+// serveAll serves the Server on l with serve, along with the additional
+// listeners and the servers of others. It binds the addresses of all
+// servers before serving any, and stops all of them on the first error.
+func (s *Server) serveAll(l net.Listener, serve func(net.Listener) error, others []*http.Server) error {
+	l = limitListener(l, s.maxConns)
+	servers := others
+	for _, el := range s.extra {
+		servers = append(servers, el.hs)
+	}
+	listeners := make([]net.Listener, 0, len(servers))
+	for i, hs := range servers {
+		addr := hs.Addr
+		if addr == "" {
+			addr = ":http"
+		}
+		el, err := net.Listen("tcp", addr)
+		if err != nil {
+			l.Close()
+			for _, el := range listeners {
+				el.Close()
+			}
+			return err
+		}
+		if j := i - len(others); j >= 0 {
+			el = limitListener(el, s.extra[j].maxConns)
+		}
+		listeners = append(listeners, el)
+	}
+
+	errc := make(chan error, len(servers)+1)
+	for i, hs := range servers {
+		go func(hs *http.Server, l net.Listener) { errc <- hs.Serve(l) }(hs, listeners[i])
+	}
+	go func() { errc <- serve(l) }()
+	err := <-errc
+	if err != http.ErrServerClosed {
+		s.hs.Close()
+		for _, hs := range servers {
+			hs.Close()
+		}
+	}
+	return err
+}
+
+// InFlight returns the number of requests being handled.
+func (s *Server) InFlight() int {
+	return s.drainer.InFlight()
+}
+
+// Shutdown gracefully shuts down the Server. It rejects new requests,
+// waits for in-flight requests to finish and then closes the Server
+// listeners and connections. If ctx is done first, the remaining
+// connections are closed and the context's error is returned.
+//
+// The listeners added with Listen are drained and closed along with the
+// Server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.redirect != nil {
+		defer s.redirect.Shutdown(ctx)
+	}
+	servers := []*http.Server{s.hs}
+	for _, el := range s.extra {
+		servers = append(servers, el.hs)
+	}
+	for _, hs := range servers {
+		hs.SetKeepAlivesEnabled(false)
+	}
+	if err := s.drainer.Drain(ctx); err != nil {
+		for _, hs := range servers {
+			hs.Close()
+		}
+		return err
+	}
+	errs := make([]error, len(servers))
+	for i, hs := range servers {
+		errs[i] = hs.Shutdown(ctx)
+	}
+	return errors.Join(errs...)
+}
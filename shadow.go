@@ -0,0 +1,122 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/eriklott/httpx/internal/record"
+)
+
+// ShadowOptions configures the Shadow middleware.
+type ShadowOptions struct {
+	// Rate is the fraction of requests mirrored, between 0 and 1.
+	Rate float64
+
+	// MaxBodyBytes is the size of the largest request body mirrored.
+	// Requests with larger bodies are not mirrored. The default is
+	// 1 MiB.
+	MaxBodyBytes int64
+
+	// Timeout bounds the handling of a mirrored request. The default is
+	// 10 seconds.
+	Timeout time.Duration
+
+	// MaxInFlight is the number of mirrored requests handled at once.
+	// Requests sampled beyond it are not mirrored, so that a slow
+	// shadow doesn't pile up goroutines. The default is 100.
+	MaxInFlight int
+}
+
+// Shadow is a middleware that mirrors a sampled fraction opts.Rate of
+// the requests to shadow, such as a new implementation of a handler or
+// a ReverseProxy to a canary deployment, to validate it against
+// production traffic:
+//
+//	mux.With(httpx.Shadow(httpx.ReverseProxy(canary, httpx.ProxyOptions{}), httpx.ShadowOptions{Rate: 0.05})).Get("/search", search)
+//
+// A mirrored request is a copy of the request, with its body and an
+// X-Shadow-Request header, handled in a new goroutine with a context
+// detached from the request, so that it doesn't delay the response.
+// The response of shadow is discarded; the errors it returns and its
+// 5xx responses are logged with the logger of the request.
+func Shadow(shadow Handler, opts ShadowOptions) Middleware {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 100
+	}
+	inFlight := make(chan struct{}, opts.MaxInFlight)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			if opts.Rate <= 0 || rand.Float64() >= opts.Rate {
+				return next.ServeHTTP(w, r)
+			}
+
+			var body []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				b, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBodyBytes+1))
+				if err != nil {
+					return err
+				}
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+				if int64(len(b)) > opts.MaxBodyBytes {
+					return next.ServeHTTP(w, r)
+				}
+				body = b
+			}
+
+			select {
+			case inFlight <- struct{}{}:
+			default:
+				return next.ServeHTTP(w, r)
+			}
+			ctx, cancel := context.WithTimeout(Detach(r.Context()), opts.Timeout)
+			sr := r.Clone(ctx)
+			sr.Body = io.NopCloser(bytes.NewReader(body))
+			sr.ContentLength = int64(len(body))
+			sr.Header.Set("X-Shadow-Request", "1")
+			logger := LoggerFrom(r)
+			go func() {
+				defer func() {
+					cancel()
+					<-inFlight
+					if v := recover(); v != nil {
+						logger.ErrorContext(ctx, "shadow request panicked",
+							"panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+					}
+				}()
+				sw := &record.Recorder{Discard: true}
+				err := shadow.ServeHTTP(sw, sr)
+				switch {
+				case err != nil:
+					logger.WarnContext(ctx, "shadow request failed", "error", err.Error())
+				case sw.Status >= 500:
+					logger.WarnContext(ctx, "shadow request failed", "status", sw.Status)
+				}
+			}()
+
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// readCloser is a request body read from r and closed with c.
+type readCloser struct {
+	io.Reader
+	c io.Closer
+}
+
+func (rc readCloser) Close() error {
+	return rc.c.Close()
+}
@@ -0,0 +1,167 @@
+package httpx
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shedPriorityKey is the route metadata key declared by ShedPriority.
+const shedPriorityKey = "shed.priority"
+
+// Priority is the class of a route for load shedding.
+type Priority int
+
+// Load shedding priorities. Low priority routes are shed at twice the
+// rate of normal routes, and critical routes, such as health checks and
+// payments, are never shed.
+const (
+	PriorityLow      Priority = -1
+	PriorityNormal   Priority = 0
+	PriorityCritical Priority = 1
+)
+
+// ShedPriority returns a RouteOption that declares the load shedding
+// priority of a route. Routes without a priority are PriorityNormal.
+func ShedPriority(p Priority) RouteOption {
+	return Meta(shedPriorityKey, p)
+}
+
+// ShedOptions configures the LoadShed middleware.
+type ShedOptions struct {
+	// Target is the p99 latency objective of the service. Once the p99
+	// latency of recent requests exceeds it, a fraction of requests is
+	// rejected so that the remaining ones are served within the target.
+	Target time.Duration
+
+	// Window is the number of recent requests the p99 latency is
+	// measured over. The default is 1000.
+	Window int
+
+	// Signal, when set, replaces the latency measurement: it returns
+	// the fraction of requests to reject, between 0 and 1, from a signal
+	// of the service's choice, such as CPU usage or queue depth.
+	Signal func() float64
+
+	// MaxShed caps the fraction of normal priority requests that is
+	// rejected. The default is 0.9.
+	MaxShed float64
+
+	// RetryAfter is the Retry-After of rejected requests. The default
+	// is one second.
+	RetryAfter time.Duration
+}
+
+// LoadShed is a middleware that rejects a fraction of requests with a
+// 503 Service Unavailable StatusError and a Retry-After header when the
+// service is overloaded, so the requests it accepts stay within the
+// latency objective. The fraction grows with the ratio of the measured
+// p99 latency to the target, or follows opts.Signal, and is scaled by
+// the ShedPriority of the route.
+func LoadShed(opts ShedOptions) Middleware {
+	if opts.Window <= 0 {
+		opts.Window = 1000
+	}
+	if opts.MaxShed <= 0 {
+		opts.MaxShed = 0.9
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = time.Second
+	}
+	retryAfter := strconv.Itoa(int(math.Ceil(opts.RetryAfter.Seconds())))
+
+	var lat *latencyWindow
+	if opts.Signal == nil {
+		lat = newLatencyWindow(opts.Window)
+	}
+	fraction := func() float64 {
+		if opts.Signal != nil {
+			return opts.Signal()
+		}
+		p99 := lat.p99()
+		if opts.Target <= 0 || p99 <= opts.Target {
+			return 0
+		}
+		return 1 - float64(opts.Target)/float64(p99)
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			priority := PriorityNormal
+			if ri, ok := CurrentRoute(r); ok {
+				if p, ok := ri.Metadata[shedPriorityKey].(Priority); ok {
+					priority = p
+				}
+			}
+
+			if priority < PriorityCritical {
+				f := math.Min(fraction(), opts.MaxShed)
+				if priority == PriorityLow {
+					f *= 2
+				}
+				if f > 0 && rand.Float64() < f {
+					w.Header().Set("Retry-After", retryAfter)
+					return Error(http.StatusServiceUnavailable, "service overloaded")
+				}
+			}
+
+			if lat == nil {
+				return next.ServeHTTP(w, r)
+			}
+			start := time.Now()
+			err := next.ServeHTTP(w, r)
+			lat.add(time.Since(start))
+			return err
+		})
+	}
+}
+
+// latencyWindow records the latencies of the most recent requests. The
+// p99 latency is recomputed every tenth of the window, so that reading
+// it stays cheap.
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+	pending int
+	cached  atomic.Int64
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+func (l *latencyWindow) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples[l.next] = d
+	l.next++
+	if l.next == len(l.samples) {
+		l.next = 0
+		l.full = true
+	}
+	l.pending++
+	if l.pending*10 < len(l.samples) {
+		return
+	}
+	l.pending = 0
+
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, l.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	l.cached.Store(int64(sorted[(n-1)*99/100]))
+}
+
+func (l *latencyWindow) p99() time.Duration {
+	return time.Duration(l.cached.Load())
+}
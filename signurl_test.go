@@ -0,0 +1,124 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignedURL checks that a signed URL is accepted until it expires,
+// and that a URL whose path, query or signature was changed is not.
+func TestSignedURL(t *testing.T) {
+	m := NewMux()
+	m.URLSigningKeys([]byte("0123456789abcdef0123456789abcdef"))
+	m.With(RequireSignedURL).Get("/invoices/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}, Name("invoice"))
+	status := func(target string) int {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Code
+	}
+
+	link, err := m.SignURL("invoice", time.Hour, "id", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := status(link); got != http.StatusOK {
+		t.Errorf("signed URL %s: status %d", link, got)
+	}
+
+	u, _ := url.Parse(link)
+	if u.Query().Get(expiresParam) == "" {
+		t.Errorf("signed URL %s has no %s param", link, expiresParam)
+	}
+	tests := []struct {
+		name   string
+		target func() string
+	}{
+		{"unsigned", func() string { return "/invoices/42" }},
+		{"other path", func() string { return "/invoices/43?" + u.RawQuery }},
+		{"extended expiry", func() string {
+			q := u.Query()
+			q.Set(expiresParam, "99999999999")
+			return u.Path + "?" + q.Encode()
+		}},
+		{"added param", func() string { return link + "&admin=1" }},
+		{"changed signature", func() string {
+			q := u.Query()
+			q.Set(signatureParam, strings.ToUpper(q.Get(signatureParam)))
+			return u.Path + "?" + q.Encode()
+		}},
+		{"empty signature", func() string {
+			q := u.Query()
+			q.Set(signatureParam, "")
+			return u.Path + "?" + q.Encode()
+		}},
+	}
+	for _, tt := range tests {
+		if got := status(tt.target()); got != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", tt.name, got)
+		}
+	}
+
+	expired, err := m.SignURL("invoice", -time.Second, "id", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, expired, nil))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "link expired") {
+		t.Errorf("expired URL: status %d, body %q", rec.Code, rec.Body.String())
+	}
+}
+
+// TestSignedURLRotation checks that URLs signed with a previous key
+// still verify once the key is rotated, and that URLs signed with a
+// removed key don't.
+func TestSignedURLRotation(t *testing.T) {
+	oldKey := []byte("old key, 32 bytes long, at least")
+	newKey := []byte("new key, 32 bytes long, at least")
+	m := NewMux()
+	m.With(RequireSignedURL).Get("/download", func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}, Name("download"))
+	status := func(target string) int {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Code
+	}
+
+	if _, err := m.SignURL("download", time.Hour); err == nil {
+		t.Error("SignURL without keys succeeded")
+	}
+	m.URLSigningKeys(oldKey)
+	signedOld, err := m.SignURL("download", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.URLSigningKeys(newKey, oldKey)
+	signedNew, err := m.SignURL("download", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signedNew == signedOld {
+		t.Error("URL signed with the same key after rotation")
+	}
+	for _, link := range []string{signedOld, signedNew} {
+		if got := status(link); got != http.StatusOK {
+			t.Errorf("%s: status %d after rotation", link, got)
+		}
+	}
+
+	m.URLSigningKeys(newKey)
+	if got := status(signedOld); got != http.StatusForbidden {
+		t.Errorf("URL signed with a removed key: status %d, want 403", got)
+	}
+	if got := status(signedNew); got != http.StatusOK {
+		t.Errorf("URL signed with the current key: status %d", got)
+	}
+}
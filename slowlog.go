@@ -0,0 +1,152 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// SlowRequest records a request that took longer than the threshold of
+// the Slowlog middleware.
+type SlowRequest struct {
+	Time     time.Time         `json:"time"`
+	Method   string            `json:"method"`
+	Path     string            `json:"path"`
+	Route    string            `json:"route,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+	Status   int               `json:"status"`
+	Duration time.Duration     `json:"duration"`
+	Error    string            `json:"error,omitempty"`
+
+	// Stack is the stack of the goroutine running the handler, sampled
+	// when the request crossed the threshold. It is only recorded by
+	// SlowlogStacks.
+	Stack string `json:"stack,omitempty"`
+}
+
+// A SlowSink receives the slow requests recorded by Slowlog. Sinks must
+// be safe for concurrent use.
+type SlowSink interface {
+	Slow(ctx context.Context, req SlowRequest)
+}
+
+// The SlowSinkFunc type is an adapter to allow the use of ordinary
+// functions as slow request sinks.
+type SlowSinkFunc func(ctx context.Context, req SlowRequest)
+
+// Slow calls fn(ctx, req).
+func (fn SlowSinkFunc) Slow(ctx context.Context, req SlowRequest) {
+	fn(ctx, req)
+}
+
+// Slowlog is a middleware that passes each request taking longer than
+// threshold to sink once the handler has returned, with its route and
+// URL params, to help diagnose tail latencies:
+//
+//	mux.Use(httpx.Slowlog(time.Second, httpx.SlowSinkFunc(func(ctx context.Context, req httpx.SlowRequest) {
+//		slog.WarnContext(ctx, "slow request", "route", req.Route, "duration", req.Duration)
+//	})))
+func Slowlog(threshold time.Duration, sink SlowSink) Middleware {
+	return slowlog(threshold, sink, false)
+}
+
+// SlowlogStacks is like Slowlog, and also samples the stack of the
+// goroutine running the handler as the request crosses threshold,
+// showing where the handler was stuck. Sampling a stack briefly stops
+// the program, as runtime.Stack does, so threshold should only be
+// crossed by a small fraction of the requests.
+func SlowlogStacks(threshold time.Duration, sink SlowSink) Middleware {
+	return slowlog(threshold, sink, true)
+}
+
+func slowlog(threshold time.Duration, sink SlowSink, stacks bool) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			start := time.Now()
+
+			var stack []byte
+			var timer *time.Timer
+			sampled := make(chan struct{})
+			if stacks {
+				id := goroutineID()
+				timer = time.AfterFunc(threshold, func() {
+					stack = goroutineStack(id)
+					close(sampled)
+				})
+			}
+
+			rw := wrapWriter(w)
+			err := next.ServeHTTP(rw, r)
+			d := time.Since(start)
+			if timer != nil && !timer.Stop() {
+				<-sampled
+			}
+			if d < threshold {
+				return err
+			}
+
+			req := SlowRequest{
+				Time:     start,
+				Method:   r.Method,
+				Path:     r.URL.Path,
+				Params:   URLParams(r),
+				Status:   statusOf(r, rw, err),
+				Duration: d,
+				Stack:    string(stack),
+			}
+			if ri, ok := CurrentRoute(r); ok {
+				req.Route = ri.Pattern
+			}
+			if err != nil {
+				req.Error = err.Error()
+			}
+			sink.Slow(r.Context(), req)
+			return err
+		})
+	}
+}
+
+// goroutineID returns the id of the calling goroutine, as printed in
+// its stack trace.
+func goroutineID() string {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		return string(b[:i])
+	}
+	return ""
+}
+
+// goroutineStack returns the stack trace of the goroutine with id, or
+// nil when it has exited.
+func goroutineStack(id string) []byte {
+	if id == "" {
+		return nil
+	}
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 16<<20 {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	header := []byte("goroutine " + id + " [")
+	for len(buf) > 0 {
+		trace := buf
+		if i := bytes.Index(buf, []byte("\n\n")); i >= 0 {
+			trace, buf = buf[:i+1], buf[i+2:]
+		} else {
+			buf = nil
+		}
+		if bytes.HasPrefix(trace, header) {
+			return append([]byte(nil), trace...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ServeContent replies to the request using the content in the
+// provided ReadSeeker, like http.ServeContent: it handles Range
+// requests with 206 Partial Content responses, and the If-Match,
+// If-Unmodified-Since, If-None-Match, If-Modified-Since and If-Range
+// headers.
+//
+// Unlike http.ServeContent, error responses are not written but
+// returned as StatusErrors, such as a 416 Range Not Satisfiable for an
+// unsatisfiable range, so they go through the handler error path. The
+// Content-Range header of a 416 response is still set.
+func ServeContent(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, content io.ReadSeeker) error {
+	ec := &errorCapture{ResponseWriter: w}
+	http.ServeContent(ec, r, name, modtime, content)
+	return ec.err()
+}
+
+// errorCapture intercepts an error response written by a standard
+// library handler, so that it can be returned as a StatusError.
+type errorCapture struct {
+	http.ResponseWriter
+	status int
+	msg    strings.Builder
+}
+
+func (c *errorCapture) WriteHeader(status int) {
+	if status >= 400 {
+		c.status = status
+		h := c.Header()
+		h.Del("Content-Type")
+		h.Del("X-Content-Type-Options")
+		return
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *errorCapture) Write(b []byte) (int, error) {
+	if c.status != 0 {
+		if c.msg.Len() < 512 {
+			c.msg.Write(b)
+		}
+		return len(b), nil
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *errorCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// err returns the intercepted error response as a StatusError, or nil.
+func (c *errorCapture) err() error {
+	if c.status == 0 {
+		return nil
+	}
+	msg := strings.TrimSpace(c.msg.String())
+	if msg == "" {
+		msg = http.StatusText(c.status)
+	}
+	return Error(c.status, msg)
+}
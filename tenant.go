@@ -0,0 +1,170 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// TenantInfo is the tenant of a request to a multi-tenant service.
+// ResolveTenant sets it on the request context, where middlewares and
+// handlers get it with Tenant.
+type TenantInfo struct {
+	// ID identifies the tenant, such as "acme".
+	ID string
+
+	// Data holds the attributes of the tenant returned by the Lookup
+	// function of TenantOptions, such as its plan or database.
+	Data interface{}
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a shallow copy of r with t set as its tenant.
+func WithTenant(r *http.Request, t *TenantInfo) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tenantKey{}, t))
+}
+
+// Tenant returns the tenant of a request. The ok result is false for
+// requests without a tenant.
+func Tenant(r *http.Request) (t *TenantInfo, ok bool) {
+	t, ok = r.Context().Value(tenantKey{}).(*TenantInfo)
+	return t, ok && t != nil
+}
+
+// A TenantResolver extracts the tenant ID of a request, returning ""
+// when the request names no tenant.
+type TenantResolver func(r *http.Request) string
+
+// SubdomainTenant returns a TenantResolver reading the ID from the
+// subdomain of domain that the request is sent to, such as "acme" for
+// acme.example.com with domain example.com.
+func SubdomainTenant(domain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(r *http.Request) string {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		sub, ok := strings.CutSuffix(host, suffix)
+		if !ok || strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// HeaderTenant returns a TenantResolver reading the ID from the header
+// name, such as X-Tenant-ID.
+func HeaderTenant(name string) TenantResolver {
+	return func(r *http.Request) string {
+		return strings.TrimSpace(r.Header.Get(name))
+	}
+}
+
+// PathTenant returns a TenantResolver reading the ID from the first
+// segment of the request path, such as "acme" for /acme/projects. The
+// routes of such a service are registered under a param, as in
+// "/{tenant}/projects", as PathTenant doesn't strip the segment.
+func PathTenant() TenantResolver {
+	return func(r *http.Request) string {
+		seg, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		return seg
+	}
+}
+
+// ErrUnknownTenant is returned by the Lookup function of TenantOptions
+// for an unknown tenant.
+var ErrUnknownTenant = errors.New("httpx: unknown tenant")
+
+// TenantOptions configures the ResolveTenant middleware.
+type TenantOptions struct {
+	// Resolvers extract the tenant ID of a request. They are tried in
+	// order, and the first ID found is used.
+	Resolvers []TenantResolver
+
+	// Lookup, when set, returns the attributes of the tenant id, stored
+	// as the Data of the TenantInfo, or ErrUnknownTenant for an unknown
+	// tenant.
+	Lookup func(ctx context.Context, id string) (interface{}, error)
+
+	// Optional passes requests that name no tenant on to the next
+	// handler, without a tenant. By default they are rejected.
+	Optional bool
+
+	// Chain, when set, returns the middlewares run for the requests of
+	// a tenant, such as a rate limit or feature flags of its plan. With
+	// a Lookup, it is called once per known tenant, on its first
+	// request, so that the middlewares of each tenant keep their own
+	// state; t is then the tenant as looked up at that time, not the
+	// TenantInfo of later requests, which middlewares get with Tenant.
+	// Without a Lookup, any ID can be named by clients, and Chain is
+	// called for every request rather than kept per tenant.
+	Chain func(t *TenantInfo) Chain
+}
+
+// ResolveTenant is a middleware resolving the tenant of requests with
+// opts.Resolvers, and setting it on the request, where it is returned
+// by Tenant:
+//
+//	mux.Use(httpx.ResolveTenant(httpx.TenantOptions{
+//		Resolvers: []httpx.TenantResolver{httpx.SubdomainTenant("example.com"), httpx.HeaderTenant("X-Tenant-ID")},
+//		Lookup:    tenants.Get,
+//		Chain: func(t *httpx.TenantInfo) httpx.Chain {
+//			return httpx.NewChain(quotas[t.Data.(*Account).Plan]...)
+//		},
+//	}))
+//
+// Requests naming no tenant are rejected with a 400 Bad Request
+// StatusError, unless opts.Optional is set, and requests naming a
+// tenant for which Lookup returns ErrUnknownTenant with a 404 Not Found
+// StatusError.
+func ResolveTenant(opts TenantOptions) Middleware {
+	return func(next Handler) Handler {
+		var chains sync.Map // tenant ID to Handler
+
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			var id string
+			for _, resolve := range opts.Resolvers {
+				if id = resolve(r); id != "" {
+					break
+				}
+			}
+			if id == "" {
+				if opts.Optional {
+					return next.ServeHTTP(w, r)
+				}
+				return Error(http.StatusBadRequest, "missing tenant")
+			}
+
+			t := &TenantInfo{ID: id}
+			if opts.Lookup != nil {
+				data, err := opts.Lookup(r.Context(), id)
+				if errors.Is(err, ErrUnknownTenant) {
+					return Errorf(http.StatusNotFound, "unknown tenant %q", id)
+				}
+				if err != nil {
+					return err
+				}
+				t.Data = data
+			}
+			r = WithTenant(r, t)
+
+			if opts.Chain == nil {
+				return next.ServeHTTP(w, r)
+			}
+			if opts.Lookup == nil {
+				return opts.Chain(&TenantInfo{ID: id}).Then(next).ServeHTTP(w, r)
+			}
+			h, ok := chains.Load(id)
+			if !ok {
+				h, _ = chains.LoadOrStore(id, opts.Chain(&TenantInfo{ID: id, Data: t.Data}).Then(next))
+			}
+			return h.(Handler).ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestResolveTenantChain checks that tenant chains are kept only for
+// tenants validated by Lookup, and aren't given the TenantInfo of the
+// request that built them.
+func TestResolveTenantChain(t *testing.T) {
+	var built []string
+	chain := func(ti *TenantInfo) Chain {
+		built = append(built, ti.ID)
+		ti.Data = "changed by chain"
+		return NewChain()
+	}
+	h := func(w http.ResponseWriter, r *http.Request) error {
+		ti, _ := Tenant(r)
+		if ti.Data == "changed by chain" {
+			t.Errorf("request tenant %s shared with its chain", ti.ID)
+		}
+		return nil
+	}
+	serve := func(m *Mux, id string) int {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Tenant", id)
+		m.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	m := NewMux()
+	m.Use(ResolveTenant(TenantOptions{
+		Resolvers: []TenantResolver{HeaderTenant("X-Tenant")},
+		Lookup: func(ctx context.Context, id string) (interface{}, error) {
+			if id != "acme" {
+				return nil, ErrUnknownTenant
+			}
+			return "plan", nil
+		},
+		Chain: chain,
+	}))
+	m.Get("/", h)
+	for _, id := range []string{"acme", "acme", "nope", "nope"} {
+		serve(m, id)
+	}
+	if len(built) != 1 || built[0] != "acme" {
+		t.Errorf("chains built for %v, want [acme]", built)
+	}
+
+	built = nil
+	m = NewMux()
+	m.Use(ResolveTenant(TenantOptions{Resolvers: []TenantResolver{HeaderTenant("X-Tenant")}, Chain: chain}))
+	m.Get("/", h)
+	serve(m, "a")
+	serve(m, "a")
+	if len(built) != 2 {
+		t.Errorf("chains built for %v without Lookup, want one per request", built)
+	}
+}
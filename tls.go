@@ -0,0 +1,181 @@
+package httpx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// AutoTLS configures certificates obtained automatically from an ACME
+// certificate authority such as Let's Encrypt.
+type AutoTLS struct {
+	// Hosts lists the host names certificates may be obtained for.
+	// Requests for any other host fail the TLS handshake.
+	Hosts []string
+
+	// CacheDir is the directory certificates are cached in. Without a
+	// cache, certificates are requested again on every restart and
+	// quickly run into the authority's rate limits.
+	CacheDir string
+
+	// Email is an optional contact address for the ACME account.
+	Email string
+
+	// DirectoryURL is the ACME directory of the certificate authority.
+	// The default is the Let's Encrypt production directory.
+	DirectoryURL string
+}
+
+// TLSConfig returns a tls.Config with modern defaults: TLS 1.2 or newer
+// with forward-secret AEAD cipher suites only.
+func TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+	}
+}
+
+// AutoTLS configures the Server to obtain and renew certificates with
+// ACME and returns the certificate manager. Start the Server with
+// ListenAndServeTLS("", "").
+//
+// When the Server handler is a Mux, the route answering HTTP-01
+// challenges is registered on it, so that serving the same Mux on port
+// 80 completes the challenges. TLS-ALPN-01 challenges are answered by
+// the TLS listener itself.
+func (s *Server) AutoTLS(cfg AutoTLS) *autocert.Manager {
+	mgr := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Hosts...),
+		Email:      cfg.Email,
+	}
+	if cfg.CacheDir != "" {
+		mgr.Cache = autocert.DirCache(cfg.CacheDir)
+	}
+	if cfg.DirectoryURL != "" {
+		mgr.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+
+	tc := TLSConfig()
+	tc.GetCertificate = mgr.GetCertificate
+	tc.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	s.hs.TLSConfig = tc
+	s.acme = mgr
+
+	if mux, ok := s.handler.(*Mux); ok {
+		mux.Get("/.well-known/acme-challenge/*", ACMEChallenge(mgr))
+	}
+	return mgr
+}
+
+// ACMEChallenge returns a handler that answers ACME HTTP-01 challenges
+// for mgr. It must be routed at /.well-known/acme-challenge/*.
+func ACMEChallenge(mgr *autocert.Manager) HandlerFunc {
+	h := mgr.HTTPHandler(nil)
+	return func(w http.ResponseWriter, r *http.Request) error {
+		h.ServeHTTP(w, r)
+		return nil
+	}
+}
+
+// SelfSignedTLS configures the Server with a self-signed certificate
+// for hosts, for use in development. The default hosts are localhost
+// and the loopback addresses. Start the Server with
+// ListenAndServeTLS("", "").
+func (s *Server) SelfSignedTLS(hosts ...string) error {
+	cert, err := selfSigned(hosts)
+	if err != nil {
+		return err
+	}
+	tc := TLSConfig()
+	tc.Certificates = []tls.Certificate{cert}
+	s.hs.TLSConfig = tc
+	return nil
+}
+
+// ListenAndServeTLS listens on the Server address and serves requests
+// over TLS until the Server is shut down. The certificate files may be
+// empty when the Server was configured with AutoTLS or SelfSignedTLS.
+//
+// When RedirectHTTP has been called, the plain HTTP listener is served
+// as well, along with the listeners added with Listen, and an error
+// from any listener stops all of them.
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	if s.hs.TLSConfig == nil {
+		s.hs.TLSConfig = TLSConfig()
+	}
+	if s.redirect == nil && len(s.extra) == 0 && s.maxConns <= 0 {
+		return s.hs.ListenAndServeTLS(certFile, keyFile)
+	}
+
+	addr := s.hs.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	var others []*http.Server
+	if s.redirect != nil {
+		others = append(others, s.redirect)
+	}
+	return s.serveAll(l, func(l net.Listener) error {
+		return s.hs.ServeTLS(l, certFile, keyFile)
+	}, others)
+}
+
+func selfSigned(hosts []string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"httpx development"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
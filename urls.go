@@ -0,0 +1,101 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// URL returns the path of the named route, with its URL params set from
+// params, given as alternating keys and values. Params that don't
+// appear in the route pattern are added as query parameters, and the
+// "*" param fills a trailing wildcard:
+//
+//	mux.Get("/users/{id}", showUser, httpx.Name("user"))
+//	mux.URL("user", "id", "42", "tab", "posts") // "/users/42?tab=posts"
+func (m *Mux) URL(name string, params ...string) (string, error) {
+	return buildURL(m.routes, name, params)
+}
+
+// URLFor is like Mux.URL, for the Mux that routed the request.
+func URLFor(r *http.Request, name string, params ...string) (string, error) {
+	ri, ok := CurrentRoute(r)
+	if !ok {
+		return "", fmt.Errorf("httpx: no route for request, cannot build URL for %q", name)
+	}
+	return buildURL(ri.table, name, params)
+}
+
+func buildURL(t *routeTable, name string, params []string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("httpx: odd number of params for route %q", name)
+	}
+	pattern, ok := t.pattern(name)
+	if !ok {
+		return "", fmt.Errorf("httpx: no route named %q", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	var keys []string
+	for i := 0; i < len(params); i += 2 {
+		if _, dup := values[params[i]]; !dup {
+			keys = append(keys, params[i])
+		}
+		values[params[i]] = params[i+1]
+	}
+
+	var b strings.Builder
+	used := map[string]bool{}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '{':
+			end := closingBrace(pattern, i)
+			if end < 0 {
+				return "", fmt.Errorf("httpx: malformed pattern %q", pattern)
+			}
+			key, _, _ := strings.Cut(pattern[i+1:end], ":")
+			v, ok := values[key]
+			if !ok {
+				return "", fmt.Errorf("httpx: missing param %q for route %q", key, name)
+			}
+			b.WriteString(url.PathEscape(v))
+			used[key] = true
+			i = end
+		case c == '*' && i == len(pattern)-1:
+			b.WriteString(values["*"])
+			used["*"] = true
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	query := url.Values{}
+	for _, key := range keys {
+		if !used[key] && key != "*" {
+			query.Set(key, values[key])
+		}
+	}
+	if len(query) > 0 {
+		b.WriteString("?" + query.Encode())
+	}
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the brace closing the param that
+// starts at i, allowing for braces in a param regexp.
+func closingBrace(pattern string, i int) int {
+	depth := 0
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,53 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type varyKey struct{}
+
+// varySet collects the request headers a response was negotiated on.
+type varySet struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// AutoVary is a middleware that adds to the Vary header of each
+// response the request headers the response was negotiated on, as
+// recorded with VaryBy. Negotiate records Accept, so a handler
+// rendering with Render or picking a representation with Negotiate
+// gets a correct Vary header without setting it, as do the other
+// negotiating middlewares of httpx and those using VaryBy.
+//
+// AutoVary should come first in the middleware stack, so that it sees
+// the negotiations of all the middlewares after it.
+func AutoVary() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			set := &varySet{}
+			dw := &deferWriter{ResponseWriter: w, fn: func(h http.Header) {
+				set.mu.Lock()
+				defer set.mu.Unlock()
+				addVary(h, set.names...)
+			}}
+			err := next.ServeHTTP(dw, r.WithContext(context.WithValue(r.Context(), varyKey{}, set)))
+			dw.apply()
+			return err
+		})
+	}
+}
+
+// VaryBy records that the response to r depends on the named request
+// headers, for AutoVary to list them in its Vary header. It does
+// nothing for requests not passed through AutoVary.
+func VaryBy(r *http.Request, names ...string) {
+	set, ok := r.Context().Value(varyKey{}).(*varySet)
+	if !ok {
+		return
+	}
+	set.mu.Lock()
+	set.names = append(set.names, names...)
+	set.mu.Unlock()
+}
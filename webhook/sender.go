@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/eriklott/httpx"
+)
+
+// Delivery is a webhook event sent to an endpoint.
+type Delivery struct {
+	// ID identifies the delivery. It is sent in the Webhook-Id header,
+	// and as the Idempotency-Key of the request, so receivers can
+	// discard duplicates.
+	ID string
+
+	// URL is the endpoint the event is sent to.
+	URL string
+
+	// Event is the type of the event, sent in the Webhook-Event header.
+	Event string
+
+	// Payload is the JSON body of the request.
+	Payload []byte
+}
+
+// SenderOptions configures a Sender.
+type SenderOptions struct {
+	// Client sends the requests. Its middlewares run for each attempt,
+	// inside the retry middleware of the Sender. The default is a Client
+	// of http.DefaultClient.
+	Client *httpx.Client
+
+	// Scheme signs the requests. It must be a Signer, as the schemes of
+	// this package are. The default is Standard.
+	Scheme Scheme
+
+	// Secret is the signing secret shared with the receivers.
+	Secret []byte
+
+	// Retry is the retry policy of a delivery. The defaults are 5
+	// attempts, with a backoff between 1 second and 1 minute, retrying
+	// transport errors, 429 and 5xx responses.
+	Retry httpx.RetryPolicy
+
+	// DeadLetter, when set, is called with a delivery that failed after
+	// all its attempts, and the error of the last attempt, for example
+	// to store it for manual replay.
+	DeadLetter func(d Delivery, err error)
+}
+
+// Sender delivers signed webhook events. A Sender must be created with
+// NewSender.
+type Sender struct {
+	client     *httpx.Client
+	signer     Signer
+	secret     []byte
+	deadLetter func(d Delivery, err error)
+}
+
+// NewSender returns a newly initialized Sender.
+func NewSender(opts SenderOptions) *Sender {
+	if opts.Client == nil {
+		opts.Client = httpx.NewClient("", nil)
+	}
+	if opts.Scheme == nil {
+		opts.Scheme = Standard
+	}
+	signer, ok := opts.Scheme.(Signer)
+	if !ok {
+		panic("webhook: sender scheme is not a Signer")
+	}
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry.MaxAttempts = 5
+	}
+	if opts.Retry.MinBackoff <= 0 {
+		opts.Retry.MinBackoff = time.Second
+	}
+	if opts.Retry.MaxBackoff <= 0 {
+		opts.Retry.MaxBackoff = time.Minute
+	}
+	if opts.Retry.ShouldRetry == nil {
+		opts.Retry.ShouldRetry = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		}
+	}
+	return &Sender{
+		client:     opts.Client.With(httpx.Retry(opts.Retry)),
+		signer:     signer,
+		secret:     opts.Secret,
+		deadLetter: opts.DeadLetter,
+	}
+}
+
+// Send delivers an event with payload encoded as JSON to url, and
+// returns once the receiver has accepted it or the delivery has failed.
+func (s *Sender) Send(ctx context.Context, url, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	return s.Deliver(ctx, Delivery{ID: hex.EncodeToString(id[:]), URL: url, Event: event, Payload: body})
+}
+
+// Deliver sends d, retrying failed attempts, and returns once the
+// receiver has responded with a 2xx status or the delivery has failed.
+// A failed delivery is passed to the dead letter callback.
+func (s *Sender) Deliver(ctx context.Context, d Delivery) error {
+	err := s.deliver(ctx, d)
+	if err != nil && s.deadLetter != nil {
+		s.deadLetter(d, err)
+	}
+	return err
+}
+
+func (s *Sender) deliver(ctx context.Context, d Delivery) error {
+	req, err := s.client.NewRequest(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Webhook-Id", d.ID)
+	req.Header.Set("Idempotency-Key", d.ID)
+	if d.Event != "" {
+		req.Header.Set("Webhook-Event", d.Event)
+	}
+	s.signer.Sign(req.Header, d.Payload, s.secret, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
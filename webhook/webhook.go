@@ -0,0 +1,270 @@
+// Package webhook verifies the signatures of webhook requests sent by
+// third-party services. The Verify middleware buffers the request
+// body, checks its HMAC signature with a Scheme, and re-exposes the
+// body to the handler:
+//
+//	mux.Post("/hooks/github", handleGitHub, httpx.WithMiddleware(httpx.NewChain(
+//		webhook.Verify(webhook.Options{Scheme: webhook.GitHub, Secrets: [][]byte{secret}}))))
+//
+// A request without a well-formed signature is rejected with a 400 Bad
+// Request StatusError, and a request with a wrong signature or a stale
+// timestamp with a 401 Unauthorized StatusError.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eriklott/httpx"
+)
+
+// A Scheme verifies the signature of a webhook request.
+type Scheme interface {
+	// Verify checks the signature of a request with body against each
+	// of secrets, and that the request timestamp, if the scheme signs
+	// one, is within tolerance of the current time.
+	Verify(r *http.Request, body []byte, secrets [][]byte, tolerance time.Duration) error
+}
+
+// A Signer signs outbound webhook requests. The schemes of this package
+// are Signers.
+type Signer interface {
+	// Sign sets the signature headers of a request with body, signed
+	// with secret at time now.
+	Sign(h http.Header, body, secret []byte, now time.Time)
+}
+
+// Options configures the Verify middleware.
+type Options struct {
+	// Scheme is the signature scheme of the sender.
+	Scheme Scheme
+
+	// Secrets are the signing secrets shared with the sender. A
+	// signature made with any of them is accepted, so that secrets can
+	// be rotated.
+	Secrets [][]byte
+
+	// Tolerance is the largest accepted difference between the signed
+	// timestamp of a request and the current time, which limits replay
+	// attacks. The default is five minutes.
+	Tolerance time.Duration
+
+	// MaxBodySize is the size of the largest request body accepted. The
+	// default is 1 MiB.
+	MaxBodySize int64
+}
+
+// Verify is a middleware that rejects requests whose signature doesn't
+// verify with opts.Scheme. The body of verified requests can be read
+// again by the next handler.
+func Verify(opts Options) httpx.Middleware {
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 5 * time.Minute
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 1 << 20
+	}
+	return func(next httpx.Handler) httpx.Handler {
+		return httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBodySize+1))
+			if err != nil {
+				return err
+			}
+			if int64(len(body)) > opts.MaxBodySize {
+				return httpx.Error(http.StatusRequestEntityTooLarge, "request body too large")
+			}
+			if err := opts.Scheme.Verify(r, body, opts.Secrets, opts.Tolerance); err != nil {
+				if se, ok := err.(httpx.StatusError); ok && se.Status() == http.StatusUnauthorized {
+					httpx.ReportSecurityEvent(r, httpx.SecurityInvalidSignature, se.Error())
+				}
+				return err
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			return next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Handler returns h wrapped in the Verify middleware.
+func Handler(opts Options, h httpx.Handler) httpx.Handler {
+	return Verify(opts)(h)
+}
+
+// GitHub verifies the X-Hub-Signature-256 header of GitHub webhooks.
+// GitHub doesn't sign a timestamp.
+var GitHub Scheme = HMAC{Header: "X-Hub-Signature-256", Prefix: "sha256="}
+
+// Stripe verifies the Stripe-Signature header of Stripe webhooks, which
+// signs a timestamp and holds a signature per active secret.
+var Stripe Scheme = stripeScheme{}
+
+// Slack verifies the X-Slack-Signature and X-Slack-Request-Timestamp
+// headers of Slack requests.
+var Slack Scheme = HMAC{
+	Header:          "X-Slack-Signature",
+	Prefix:          "v0=",
+	TimestampHeader: "X-Slack-Request-Timestamp",
+	Format:          "v0:{timestamp}:{body}",
+}
+
+// Standard is the scheme of webhooks sent by a Sender: an HMAC-SHA256
+// of the Webhook-Timestamp header and the body in the Webhook-Signature
+// header.
+var Standard Scheme = HMAC{
+	Header:          "Webhook-Signature",
+	Prefix:          "sha256=",
+	TimestampHeader: "Webhook-Timestamp",
+}
+
+// HMAC is a generic Scheme for senders that put a hex encoded HMAC of
+// the body in a request header.
+type HMAC struct {
+	// Header holds the signature.
+	Header string
+
+	// Prefix precedes the hex signature in the header, as in "sha256=".
+	Prefix string
+
+	// Hash is the hash function of the HMAC. The default is SHA-256.
+	Hash func() hash.Hash
+
+	// TimestampHeader, when set, holds the Unix time at which the
+	// request was signed.
+	TimestampHeader string
+
+	// Format is the signed message, where {timestamp} and {body} are
+	// replaced by the timestamp and the body. The default is the body
+	// alone, or "{timestamp}.{body}" when TimestampHeader is set.
+	Format string
+}
+
+// Verify implements Scheme.
+func (s HMAC) Verify(r *http.Request, body []byte, secrets [][]byte, tolerance time.Duration) error {
+	sig, ok := strings.CutPrefix(r.Header.Get(s.Header), s.Prefix)
+	if !ok || sig == "" {
+		return httpx.Errorf(http.StatusBadRequest, "missing or malformed %s header", s.Header)
+	}
+	mac, err := hex.DecodeString(sig)
+	if err != nil {
+		return httpx.Errorf(http.StatusBadRequest, "malformed %s header", s.Header)
+	}
+
+	var ts string
+	if s.TimestampHeader != "" {
+		ts = r.Header.Get(s.TimestampHeader)
+		if err := checkTimestamp(ts, s.TimestampHeader, tolerance); err != nil {
+			return err
+		}
+	}
+	if !validMAC(s.hash(), secrets, s.message(ts, body), [][]byte{mac}) {
+		return httpx.Error(http.StatusUnauthorized, "invalid webhook signature")
+	}
+	return nil
+}
+
+// Sign implements Signer.
+func (s HMAC) Sign(h http.Header, body, secret []byte, now time.Time) {
+	var ts string
+	if s.TimestampHeader != "" {
+		ts = strconv.FormatInt(now.Unix(), 10)
+		h.Set(s.TimestampHeader, ts)
+	}
+	m := hmac.New(s.hash(), secret)
+	m.Write(s.message(ts, body))
+	h.Set(s.Header, s.Prefix+hex.EncodeToString(m.Sum(nil)))
+}
+
+func (s HMAC) hash() func() hash.Hash {
+	if s.Hash == nil {
+		return sha256.New
+	}
+	return s.Hash
+}
+
+// message returns the signed message of a request with timestamp ts.
+func (s HMAC) message(ts string, body []byte) []byte {
+	format := s.Format
+	if format == "" && s.TimestampHeader != "" {
+		format = "{timestamp}.{body}"
+	}
+	if format == "" || format == "{body}" {
+		return body
+	}
+	return []byte(strings.NewReplacer("{timestamp}", ts, "{body}", string(body)).Replace(format))
+}
+
+type stripeScheme struct{}
+
+func (stripeScheme) Verify(r *http.Request, body []byte, secrets [][]byte, tolerance time.Duration) error {
+	header := r.Header.Get("Stripe-Signature")
+	var ts string
+	var macs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = val
+		case "v1":
+			if mac, err := hex.DecodeString(val); err == nil {
+				macs = append(macs, mac)
+			}
+		}
+	}
+	if ts == "" || len(macs) == 0 {
+		return httpx.Error(http.StatusBadRequest, "missing or malformed Stripe-Signature header")
+	}
+	if err := checkTimestamp(ts, "Stripe-Signature", tolerance); err != nil {
+		return err
+	}
+	msg := append([]byte(ts+"."), body...)
+	if !validMAC(sha256.New, secrets, msg, macs) {
+		return httpx.Error(http.StatusUnauthorized, "invalid webhook signature")
+	}
+	return nil
+}
+
+func (stripeScheme) Sign(h http.Header, body, secret []byte, now time.Time) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(ts + "."))
+	m.Write(body)
+	h.Set("Stripe-Signature", "t="+ts+",v1="+hex.EncodeToString(m.Sum(nil)))
+}
+
+// checkTimestamp checks that ts, a Unix time from header, is within
+// tolerance of the current time.
+func checkTimestamp(ts, header string, tolerance time.Duration) error {
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return httpx.Errorf(http.StatusBadRequest, "missing or malformed %s timestamp", header)
+	}
+	if math.Abs(time.Since(time.Unix(secs, 0)).Seconds()) > tolerance.Seconds() {
+		return httpx.Error(http.StatusUnauthorized, "webhook timestamp outside of tolerance")
+	}
+	return nil
+}
+
+// validMAC reports whether any of macs is the HMAC of msg with any of
+// secrets.
+func validMAC(h func() hash.Hash, secrets [][]byte, msg []byte, macs [][]byte) bool {
+	for _, secret := range secrets {
+		m := hmac.New(h, secret)
+		m.Write(msg)
+		sum := m.Sum(nil)
+		for _, mac := range macs {
+			if hmac.Equal(sum, mac) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eriklott/httpx"
+)
+
+// verifyStatus runs a request with body and header through the Verify
+// middleware and returns the status of the error, or 200.
+func verifyStatus(t *testing.T, opts Options, h http.Header, body string) int {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+	for k, v := range h {
+		r.Header[k] = v
+	}
+	err := Verify(opts)(httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		got, err := io.ReadAll(r.Body)
+		if err != nil || string(got) != body {
+			t.Errorf("handler read %q, %v", got, err)
+		}
+		return nil
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if err == nil {
+		return http.StatusOK
+	}
+	se, ok := err.(httpx.StatusError)
+	if !ok {
+		t.Fatalf("error %v is not a StatusError", err)
+	}
+	return se.Status()
+}
+
+// TestVerifySchemes checks that a request signed by each scheme
+// verifies, and that a changed body, a missing header, a malformed
+// header and a stale timestamp are rejected.
+func TestVerifySchemes(t *testing.T) {
+	secret := []byte("s3cret")
+	body := `{"event":"ping"}`
+	schemes := []struct {
+		name   string
+		scheme Scheme
+		header string
+		stale  int // the status of a request signed ten minutes ago
+	}{
+		// GitHub doesn't sign a timestamp.
+		{"GitHub", GitHub, "X-Hub-Signature-256", http.StatusOK},
+		{"Stripe", Stripe, "Stripe-Signature", http.StatusUnauthorized},
+		{"Slack", Slack, "X-Slack-Signature", http.StatusUnauthorized},
+		{"Standard", Standard, "Webhook-Signature", http.StatusUnauthorized},
+	}
+	for _, s := range schemes {
+		t.Run(s.name, func(t *testing.T) {
+			opts := Options{Scheme: s.scheme, Secrets: [][]byte{secret}}
+			sign := func(now time.Time) http.Header {
+				h := http.Header{}
+				s.scheme.(Signer).Sign(h, []byte(body), secret, now)
+				return h
+			}
+
+			if got := verifyStatus(t, opts, sign(time.Now()), body); got != http.StatusOK {
+				t.Errorf("signed request: status %d", got)
+			}
+			if got := verifyStatus(t, opts, sign(time.Now()), body+" "); got != http.StatusUnauthorized {
+				t.Errorf("changed body: status %d", got)
+			}
+			if got := verifyStatus(t, opts, http.Header{}, body); got != http.StatusBadRequest {
+				t.Errorf("missing signature: status %d", got)
+			}
+			h := sign(time.Now())
+			h.Set(s.header, "zz")
+			if got := verifyStatus(t, opts, h, body); got != http.StatusBadRequest {
+				t.Errorf("malformed signature: status %d", got)
+			}
+
+			stale := sign(time.Now().Add(-10 * time.Minute))
+			if got := verifyStatus(t, opts, stale, body); got != s.stale {
+				t.Errorf("stale timestamp: status %d, want %d", got, s.stale)
+			}
+		})
+	}
+}
+
+// TestVerifyRotatedSecrets checks that a signature made with any of the
+// secrets verifies, and that a Stripe header with a signature per
+// secret verifies when one of them matches.
+func TestVerifyRotatedSecrets(t *testing.T) {
+	old, cur := []byte("old"), []byte("new")
+	body := "payload"
+	opts := Options{Scheme: Standard, Secrets: [][]byte{cur, old}}
+
+	for _, secret := range [][]byte{old, cur} {
+		h := http.Header{}
+		Standard.(Signer).Sign(h, []byte(body), secret, time.Now())
+		if got := verifyStatus(t, opts, h, body); got != http.StatusOK {
+			t.Errorf("secret %q: status %d", secret, got)
+		}
+	}
+	h := http.Header{}
+	Standard.(Signer).Sign(h, []byte(body), []byte("other"), time.Now())
+	if got := verifyStatus(t, opts, h, body); got != http.StatusUnauthorized {
+		t.Errorf("unknown secret: status %d", got)
+	}
+
+	signed, unknown := http.Header{}, http.Header{}
+	Stripe.(Signer).Sign(signed, []byte(body), old, time.Now())
+	Stripe.(Signer).Sign(unknown, []byte(body), []byte("other"), time.Now())
+	_, v1, _ := strings.Cut(unknown.Get("Stripe-Signature"), ",")
+	h = http.Header{}
+	h.Set("Stripe-Signature", signed.Get("Stripe-Signature")+","+v1)
+	if got := verifyStatus(t, Options{Scheme: Stripe, Secrets: [][]byte{cur, old}}, h, body); got != http.StatusOK {
+		t.Errorf("Stripe header with a signature per secret: status %d", got)
+	}
+}
+
+// TestVerifyTimestamp checks the 400 and 401 responses to malformed and
+// out of tolerance timestamps.
+func TestVerifyTimestamp(t *testing.T) {
+	secret := []byte("s3cret")
+	opts := Options{Scheme: Slack, Secrets: [][]byte{secret}, Tolerance: time.Minute}
+	tests := []struct {
+		name string
+		ts   string
+		want int
+	}{
+		{"current", strconv.FormatInt(time.Now().Unix(), 10), http.StatusOK},
+		{"within tolerance", strconv.FormatInt(time.Now().Add(-30*time.Second).Unix(), 10), http.StatusOK},
+		{"stale", strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10), http.StatusUnauthorized},
+		{"future", strconv.FormatInt(time.Now().Add(2*time.Minute).Unix(), 10), http.StatusUnauthorized},
+		{"malformed", "yesterday", http.StatusBadRequest},
+		{"missing", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sign at the timestamp under test, so that only the
+			// timestamp check can fail.
+			h := http.Header{}
+			ts, _ := strconv.ParseInt(tt.ts, 10, 64)
+			Slack.(Signer).Sign(h, []byte("body"), secret, time.Unix(ts, 0))
+			h.Set("X-Slack-Request-Timestamp", tt.ts)
+			if got := verifyStatus(t, opts, h, "body"); got != tt.want {
+				t.Errorf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestVerifyBodySize checks that a body over MaxBodySize is rejected
+// before its signature is checked.
+func TestVerifyBodySize(t *testing.T) {
+	opts := Options{Scheme: GitHub, Secrets: [][]byte{[]byte("s")}, MaxBodySize: 4}
+	if got := verifyStatus(t, opts, http.Header{}, "12345"); got != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d", got)
+	}
+}
+
+// TestSignVerify checks that the headers set by Sign verify with the
+// Scheme they were signed with, for a custom HMAC format.
+func TestSignVerify(t *testing.T) {
+	scheme := HMAC{Header: "X-Sig", TimestampHeader: "X-Ts", Format: "{timestamp}:{body}:end"}
+	h := http.Header{}
+	scheme.Sign(h, []byte("body"), []byte("k"), time.Now())
+	if h.Get("X-Ts") == "" || h.Get("X-Sig") == "" {
+		t.Fatalf("headers %v", h)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header = h
+	if err := scheme.Verify(r, []byte("body"), [][]byte{[]byte("k")}, time.Minute); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := scheme.Verify(r, []byte("body"), [][]byte{[]byte("x")}, time.Minute); err == nil {
+		t.Error("Verify with the wrong secret succeeded")
+	}
+}
@@ -0,0 +1,186 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+)
+
+// responseWriter wraps an http.ResponseWriter to record the status and
+// the number of body bytes written by a handler.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+// wrapWriter returns w wrapped in a responseWriter, or w itself when it
+// is already wrapped.
+func wrapWriter(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w}
+}
+
+// writerPool recycles the responseWriters of the requests served by a
+// Mux, to reduce garbage collection at high request rates.
+var writerPool = sync.Pool{
+	New: func() interface{} { return new(responseWriter) },
+}
+
+// getWriter returns a pooled responseWriter wrapping w, unless pooling
+// is disabled for the table.
+func (t *routeTable) getWriter(w http.ResponseWriter) *responseWriter {
+	if t.noPool {
+		return &responseWriter{ResponseWriter: w}
+	}
+	rw := writerPool.Get().(*responseWriter)
+	rw.ResponseWriter = w
+	return rw
+}
+
+// putWriter returns rw to the pool once its request has been served.
+func (t *routeTable) putWriter(rw *responseWriter) {
+	if t.noPool {
+		return
+	}
+	*rw = responseWriter{}
+	writerPool.Put(rw)
+}
+
+// DisablePooling stops the Mux, and any Mux derived from it, from
+// recycling the ResponseWriters passed to its handlers. With pooling,
+// a writer must not be used once the handler has returned, as the
+// http.ResponseWriter contract requires; DisablePooling is an opt-out
+// for code that doesn't honor it and can't be fixed. It must be called
+// before the Mux serves requests.
+func (m *Mux) DisablePooling() {
+	m.routes.noPool = true
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the wrapped writer supports it.
+func (w *responseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker when the wrapped writer supports it.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the wrapped writer, for use by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// tracker returns w. It is promoted by the writers embedding a
+// responseWriter, so findWriter can find it through them.
+func (w *responseWriter) tracker() *responseWriter {
+	return w
+}
+
+// findWriter returns the responseWriter wrapped by w, or nil.
+func findWriter(w http.ResponseWriter) *responseWriter {
+	for {
+		switch t := w.(type) {
+		case interface{ tracker() *responseWriter }:
+			return t.tracker()
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// Status returns the status of the response written to w, or 0 when
+// the response headers haven't been written yet. The writers passed to
+// the handlers and middlewares of a Mux track their response; Status
+// returns 0 for writers that don't.
+func Status(w http.ResponseWriter) int {
+	if rw := findWriter(w); rw != nil {
+		return rw.status
+	}
+	return 0
+}
+
+// BytesWritten returns the number of body bytes written to w, or 0 for
+// a writer that doesn't track its response.
+func BytesWritten(w http.ResponseWriter) int64 {
+	if rw := findWriter(w); rw != nil {
+		return rw.written
+	}
+	return 0
+}
+
+// HeaderWritten reports whether the response headers have been written
+// to w, after which the status and headers can't be changed anymore.
+func HeaderWritten(w http.ResponseWriter) bool {
+	return Status(w) != 0
+}
+
+// statusOf returns the status of a response to r written to w by a
+// handler that returned err. An error that was not written yet is
+// reported with the status the adaptor will respond with, and an error
+// from a client that went away with StatusClientClosedRequest.
+func statusOf(r *http.Request, w *responseWriter, err error) int {
+	if ClientGone(r, err) {
+		return StatusClientClosedRequest
+	}
+	if w.status != 0 {
+		return w.status
+	}
+	if err != nil {
+		if sErr, ok := mapError(err).(StatusError); ok {
+			return sErr.Status()
+		}
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// StatusClientClosedRequest is the non-standard status, introduced by
+// nginx, with which access logs and metrics report requests whose
+// client went away before the response was complete.
+const StatusClientClosedRequest = 499
+
+// ClientGone reports whether err, returned by the handler of r, results
+// from the client of r going away: err is a cancellation and the
+// context of r is done, or writing the response failed with a broken
+// pipe or a reset connection. The Mux neither reports nor logs such
+// errors as server errors, as no response can reach the client. A
+// cancellation while the client is still connected, such as of work
+// canceled by the server, is a server error.
+func ClientGone(r *http.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	return errors.Is(err, context.Canceled) && r.Context().Err() != nil
+}
@@ -0,0 +1,118 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWriterPoolReuse serves concurrent requests with pooled writers,
+// checking that no request sees the status or size of a previous one.
+// Run with -race.
+func TestWriterPoolReuse(t *testing.T) {
+	m := NewMux()
+	m.Get("/{n}", func(w http.ResponseWriter, r *http.Request) error {
+		if s, n := Status(w), BytesWritten(w); s != 0 || n != 0 {
+			t.Errorf("fresh writer has status %d and %d bytes written", s, n)
+		}
+		n, _ := strconv.Atoi(URLParam(r, "n"))
+		if n%3 == 0 {
+			return Error(http.StatusConflict, "conflict")
+		}
+		w.Header().Set("X-N", URLParam(r, "n"))
+		w.WriteHeader(200 + n%2)
+		_, err := w.Write([]byte(URLParam(r, "n")))
+		return err
+	})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				n := strconv.Itoa(g*1000 + i)
+				rec := httptest.NewRecorder()
+				m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+n, nil))
+				nn := g*1000 + i
+				switch {
+				case nn%3 == 0:
+					if rec.Code != http.StatusConflict {
+						t.Errorf("GET /%s: status %d, want 409", n, rec.Code)
+					}
+				case rec.Code != 200+nn%2 || rec.Body.String() != n || rec.Header().Get("X-N") != n:
+					t.Errorf("GET /%s: got %d %q %q", n, rec.Code, rec.Body.String(), rec.Header().Get("X-N"))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// TestWriterPoolRequestOutlivesHandler checks that requests kept past
+// the return of their handler, by Go and AfterResponse, keep their route
+// and params once their writer is back in the pool. Run with -race.
+func TestWriterPoolRequestOutlivesHandler(t *testing.T) {
+	const n = 200
+	type result struct{ want, param, route string }
+	results := make(chan result, 2*n)
+
+	m := NewMux()
+	m.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) error {
+		id := URLParam(r, "id")
+		Go(r, func(ctx context.Context) error {
+			ri, _ := CurrentRoute(r)
+			results <- result{want: id, param: URLParam(r, "id"), route: ri.Pattern}
+			return nil
+		})
+		AfterResponse(r, func(ctx context.Context) {
+			ri, _ := CurrentRoute(r)
+			results <- result{want: id, param: URLParam(r, "id"), route: ri.Pattern}
+		})
+		_, err := w.Write([]byte(id))
+		return err
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/"+strconv.Itoa(i), nil))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 2*n; i++ {
+		select {
+		case res := <-results:
+			if res.param != res.want || res.route != "/items/{id}" {
+				t.Errorf("request %s seen as %s on route %s", res.want, res.param, res.route)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d of %d background results", i, 2*n)
+		}
+	}
+}
+
+// TestDisablePooling checks that a writer kept past the return of its
+// handler isn't reused when pooling is disabled.
+func TestDisablePooling(t *testing.T) {
+	m := NewMux()
+	m.DisablePooling()
+	var kept http.ResponseWriter
+	m.Get("/", func(w http.ResponseWriter, r *http.Request) error {
+		kept = w
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	})
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if s := Status(kept); s != http.StatusAccepted {
+		t.Errorf("kept writer has status %d, want 202", s)
+	}
+}